@@ -0,0 +1,440 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/devopsext/events/common"
+	"github.com/devopsext/events/input"
+	"github.com/devopsext/events/output"
+	"github.com/devopsext/events/processor"
+	sreCommon "github.com/devopsext/sre/common"
+	sreProvider "github.com/devopsext/sre/provider"
+	"github.com/devopsext/utils"
+)
+
+type eventers struct {
+	newrelic *sreProvider.NewRelicEventer
+	grafana  *sreProvider.GrafanaEventer
+	datadog  *sreProvider.DataDogEventer
+}
+
+type pipeline struct {
+	inputs        common.Inputs
+	processors    *common.Processors
+	outputs       *common.Outputs
+	eventers      eventers
+	observability *common.Observability
+}
+
+// processorFactory makes processor of config item, options of item are decoded over env defaults
+type processorFactory struct {
+	typ string
+	new func(item *common.ConfigItem, outputs *common.Outputs, observability *common.Observability) (common.Processor, error)
+}
+
+var processorFactories = []processorFactory{
+	{processor.K8sProcessorType(), func(_ *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		return processor.NewK8sProcessor(o, obs), nil
+	}},
+	{processor.KubeProcessorType(), func(_ *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		return processor.NewKubeProcessor(o, obs), nil
+	}},
+	{processor.WinEventProcessorType(), func(_ *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		return processor.NewWinEventProcessor(o, obs), nil
+	}},
+	{processor.GitlabProcessorType(), func(_ *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		return processor.NewGitlabProcessor(o, obs), nil
+	}},
+	{processor.AlertmanagerProcessorType(), func(_ *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		return processor.NewAlertmanagerProcessor(o, obs), nil
+	}},
+	{processor.CustomJsonProcessorType(), func(item *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		opts := customJsonProcessorOptions
+		if err := item.Decode(&opts); err != nil {
+			return nil, err
+		}
+		return processor.NewCustomJsonProcessor(opts, o, obs), nil
+	}},
+	{processor.RancherProcessorType(), func(_ *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		return processor.NewRancherProcessor(o, obs), nil
+	}},
+	{processor.DataDogProcessorType(), func(_ *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		return processor.NewDataDogProcessor(o, obs), nil
+	}},
+	{processor.NewRelicProcessorType(), func(_ *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		return processor.NewNewRelicProcessor(o, obs), nil
+	}},
+	{processor.GrafanaProcessorType(), func(_ *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		return processor.NewGrafanaProcessor(o, obs), nil
+	}},
+	{processor.PagerDutyProcessorType(), func(item *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		opts := pagerDutyProcessorOptions
+		if err := item.Decode(&opts); err != nil {
+			return nil, err
+		}
+		if utils.IsEmpty(opts.Secret) && processorEnabled(httpInputOptions.PagerDutyURL) {
+			return nil, fmt.Errorf("%s processor %s requires secret", item.Type, item.Name)
+		}
+		return processor.NewPagerDutyProcessor(opts, o, obs), nil
+	}},
+	{processor.OpsgenieProcessorType(), func(item *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		opts := opsgenieProcessorOptions
+		if err := item.Decode(&opts); err != nil {
+			return nil, err
+		}
+		if utils.IsEmpty(opts.Secret) && processorEnabled(httpInputOptions.OpsgenieURL) {
+			return nil, fmt.Errorf("%s processor %s requires secret", item.Type, item.Name)
+		}
+		return processor.NewOpsgenieProcessor(opts, o, obs), nil
+	}},
+	{processor.Site24x7ProcessorType(), func(_ *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		return processor.NewSite24x7Processor(o, obs), nil
+	}},
+	{processor.CloudflareProcessorType(), func(_ *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		return processor.NewCloudflareProcessor(o, obs), nil
+	}},
+	{processor.GoogleProcessorType(), func(_ *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		return processor.NewGoogleProcessor(o, obs), nil
+	}},
+	{processor.AWSProcessorType(), func(_ *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		return processor.NewAWSProcessor(o, obs), nil
+	}},
+	{processor.ZabbixProcessorType(), func(_ *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		return processor.NewZabbixProcessor(o, obs), nil
+	}},
+	{processor.VCenterProcessorType(), func(_ *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		return processor.NewVCenterProcessor(o, obs), nil
+	}},
+	{processor.ObserviumEventProcessorType(), func(_ *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		return processor.NewObserviumEventProcessor(o, obs), nil
+	}},
+	{processor.TeamcityProcessorType(), func(_ *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		return processor.NewTeamcityProcessor(o, obs), nil
+	}},
+	{processor.NomadProcessorType(), func(_ *common.ConfigItem, o *common.Outputs, obs *common.Observability) (common.Processor, error) {
+		return processor.NewNomadProcessor(o, obs), nil
+	}},
+}
+
+var inputTypes = []string{"http", "pubsub", "vcenter", "nomad", "kafka"}
+var outputTypes = []string{"collector", "kafka", "telegram", "slack", "workchat", "newrelic", "datadog", "grafana", "pubsub", "gitlab"}
+
+// processorEnabled says whether processor gets requests, it's declared in pipeline config or has its http url
+func processorEnabled(url string) bool {
+	return !utils.IsEmpty(rootOptions.Config) || !utils.IsEmpty(url)
+}
+
+// defaultConfig makes config from env variables and flags, one instance of each type
+func defaultConfig() *common.Config {
+
+	c := &common.Config{}
+	for _, t := range inputTypes {
+		c.Inputs = append(c.Inputs, &common.ConfigItem{Name: t, Type: t})
+	}
+	for _, f := range processorFactories {
+		c.Processors = append(c.Processors, &common.ConfigItem{Name: f.typ, Type: f.typ})
+	}
+	for _, t := range outputTypes {
+		c.Outputs = append(c.Outputs, &common.ConfigOutput{ConfigItem: common.ConfigItem{Type: t}})
+	}
+	return c
+}
+
+func loadConfig() (*common.Config, error) {
+
+	if utils.IsEmpty(rootOptions.Config) {
+		return defaultConfig(), nil
+	}
+	return common.LoadConfig(rootOptions.Config)
+}
+
+func (p *pipeline) addInput(item *common.ConfigItem) error {
+
+	switch strings.ToLower(item.Type) {
+	case "http":
+		opts := httpInputOptions
+		if err := item.Decode(&opts); err != nil {
+			return err
+		}
+		if err := opts.LoadAuth(); err != nil {
+			return err
+		}
+		if err := opts.ValidateTLS(); err != nil {
+			return err
+		}
+		p.inputs.Add(input.NewHttpInput(opts, p.processors, p.observability))
+	case "pubsub":
+		opts := pubsubInputOptions
+		if err := item.Decode(&opts); err != nil {
+			return err
+		}
+		p.inputs.Add(input.NewPubSubInput(opts, p.processors, p.observability))
+	case "vcenter":
+		opts := vcInputOptions
+		if err := item.Decode(&opts); err != nil {
+			return err
+		}
+		p.inputs.Add(input.NewVCenterInput(opts, p.processors, p.observability))
+	case "nomad":
+		opts := nomadInputOptions
+		if err := item.Decode(&opts); err != nil {
+			return err
+		}
+		p.inputs.Add(input.NewNomadInput(opts, p.processors, p.observability))
+	case "kafka":
+		opts := kafkaInputOptions
+		if err := item.Decode(&opts); err != nil {
+			return err
+		}
+		if err := opts.ValidateProcessor(p.processors); err != nil {
+			return err
+		}
+		p.inputs.Add(input.NewKafkaInput(opts, p.processors, p.observability))
+	default:
+		return fmt.Errorf("input type %s is not supported", item.Type)
+	}
+	return nil
+}
+
+func (p *pipeline) addProcessor(item *common.ConfigItem) error {
+
+	for _, f := range processorFactories {
+		if strings.EqualFold(f.typ, item.Type) {
+			pr, err := f.new(item, p.outputs, p.observability)
+			if err != nil {
+				return err
+			}
+			p.processors.AddNamed(item.Name, pr)
+			return nil
+		}
+	}
+	return fmt.Errorf("processor type %s is not supported", item.Type)
+}
+
+func (p *pipeline) newOutput(item *common.ConfigOutput) (common.Output, error) {
+
+	wg := &outputsWG
+	obs := p.observability
+
+	switch strings.ToLower(item.Type) {
+	case "collector":
+		opts := collectorOutputOptions
+		if err := item.Decode(&opts); err != nil {
+			return nil, err
+		}
+		opts.Instance = item.Name
+		return output.NewCollectorOutput(wg, opts, textTemplateOptions, obs), nil
+	case "kafka":
+		opts := kafkaOutputOptions
+		if err := item.Decode(&opts); err != nil {
+			return nil, err
+		}
+		opts.Instance = item.Name
+		return output.NewKafkaOutput(wg, opts, textTemplateOptions, obs), nil
+	case "telegram":
+		opts := telegramOutputOptions
+		if err := item.Decode(&opts); err != nil {
+			return nil, err
+		}
+		opts.Instance = item.Name
+		return output.NewTelegramOutput(wg, opts, textTemplateOptions, grafanaRenderOptions, obs, p.outputs), nil
+	case "slack":
+		opts := slackOutputOptions
+		if err := item.Decode(&opts); err != nil {
+			return nil, err
+		}
+		opts.Instance = item.Name
+		return output.NewSlackOutput(wg, opts, textTemplateOptions, grafanaRenderOptions, obs, p.outputs), nil
+	case "workchat":
+		opts := workchatOutputOptions
+		if err := item.Decode(&opts); err != nil {
+			return nil, err
+		}
+		opts.Instance = item.Name
+		return output.NewWorkchatOutput(wg, opts, textTemplateOptions, grafanaRenderOptions, obs, p.outputs), nil
+	case "newrelic":
+		opts := newrelicOutputOptions
+		if err := item.Decode(&opts); err != nil {
+			return nil, err
+		}
+		opts.Instance = item.Name
+		return output.NewNewRelicOutput(wg, opts, textTemplateOptions, obs, p.eventers.newrelic), nil
+	case "datadog":
+		opts := datadogOutputOptions
+		if err := item.Decode(&opts); err != nil {
+			return nil, err
+		}
+		opts.Instance = item.Name
+		return output.NewDataDogOutput(wg, opts, textTemplateOptions, obs, p.eventers.datadog), nil
+	case "grafana":
+		opts := grafanaOutputOptions
+		if err := item.Decode(&opts); err != nil {
+			return nil, err
+		}
+		opts.Instance = item.Name
+		return output.NewGrafanaOutput(wg, opts, textTemplateOptions, obs, p.eventers.grafana), nil
+	case "pubsub":
+		opts := pubsubOutputOptions
+		if err := item.Decode(&opts); err != nil {
+			return nil, err
+		}
+		opts.Instance = item.Name
+		return output.NewPubSubOutput(wg, opts, textTemplateOptions, obs), nil
+	case "gitlab":
+		opts := gitlabOutputOptions
+		if err := item.Decode(&opts); err != nil {
+			return nil, err
+		}
+		opts.Instance = item.Name
+		return output.NewGitlabOutput(wg, opts, textTemplateOptions, obs), nil
+	}
+	return nil, fmt.Errorf("output type %s is not supported", item.Type)
+}
+
+func (p *pipeline) addOutput(item *common.ConfigOutput) error {
+
+	o, err := p.newOutput(item)
+	if err != nil {
+		return err
+	}
+
+	retry := outputRetryOptions(strings.ToUpper(item.Type))
+	if err := item.DecodeRetry(&retry); err != nil {
+		return err
+	}
+
+	pool := outputPoolOptions(strings.ToUpper(item.Type))
+	if err := item.DecodePool(&pool); err != nil {
+		return err
+	}
+	// persistent queue delivers events one by one in order, pool workers and overflow would bypass it
+	if !utils.IsEmpty(queueOptions.Dir) && pool.Workers > 0 {
+		return fmt.Errorf("%s %s pool can't be used with queue dir, pool workers should be 0", item.Type, item.Name)
+	}
+
+	deadLetters := common.NewDeadLetters(retry, p.outputs, p.observability)
+
+	o = common.NewDeliveryOutput(&outputsWG, o, p.outputs.Deliveries(), p.observability)
+	o = common.NewRetryOutput(&outputsWG, o, retry, deadLetters, p.observability)
+	o = common.NewPoolOutput(o, pool, p.observability)
+	p.outputs.Add(common.NewQueueOutput(o, queueOptions, deadLetters, p.observability))
+	return nil
+}
+
+func newPipeline(config *common.Config, eventers eventers, observability *common.Observability) (*pipeline, error) {
+
+	outputs := common.NewOutputs(observability)
+	p := &pipeline{
+		inputs:        common.NewInputs(),
+		processors:    common.NewProcessors(),
+		outputs:       &outputs,
+		eventers:      eventers,
+		observability: observability,
+	}
+	p.outputs.SetDeliveries(common.NewDeliveries(deliveryOptions))
+
+	for _, item := range config.Processors {
+		if err := p.addProcessor(item); err != nil {
+			return nil, err
+		}
+	}
+	for _, item := range config.Inputs {
+		if err := p.addInput(item); err != nil {
+			return nil, err
+		}
+	}
+	for _, item := range config.Outputs {
+		if err := p.addOutput(item); err != nil {
+			return nil, err
+		}
+	}
+	dedup := dedupOptions
+	if err := config.DecodeDedup(&dedup); err != nil {
+		return nil, err
+	}
+	d, err := common.NewDedup(dedup, textTemplateOptions, observability)
+	if err != nil {
+		return nil, err
+	}
+	p.outputs.SetDedup(d)
+
+	state, err := common.NewState(stateOptions, observability)
+	if err != nil {
+		return nil, err
+	}
+	p.outputs.SetState(state)
+
+	if err := p.outputs.SetRouting(config.Filters, config.Routes); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// configReloadable applies filters and routes from changed config, other sections require restart
+type configReloadable struct {
+	path    string
+	content []byte
+	config  *common.Config
+	outputs *common.Outputs
+	logger  sreCommon.Logger
+}
+
+func (c *configReloadable) Name() string {
+	return c.path
+}
+
+func (c *configReloadable) topology(config *common.Config) string {
+
+	b, err := json.Marshal(common.Config{
+		Inputs:     config.Inputs,
+		Processors: config.Processors,
+		Outputs:    config.Outputs,
+	})
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (c *configReloadable) Reload() (bool, error) {
+
+	b, err := os.ReadFile(c.path)
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(b, c.content) {
+		return false, nil
+	}
+
+	config, err := common.ParseConfig(b)
+	if err != nil {
+		return false, err
+	}
+	// old filters and routes are kept together if new ones are invalid
+	if err := c.outputs.SetRouting(config.Filters, config.Routes); err != nil {
+		return false, err
+	}
+
+	if c.topology(config) != c.topology(c.config) {
+		c.logger.Warn("%s inputs, processors or outputs are changed, restart is required to apply them", c.path)
+	}
+	c.content = b
+	c.config = config
+	return true, nil
+}
+
+func newConfigReloadable(path string, config *common.Config, outputs *common.Outputs, logger sreCommon.Logger) *configReloadable {
+
+	content, _ := os.ReadFile(path)
+	return &configReloadable{
+		path:    path,
+		content: content,
+		config:  config,
+		outputs: outputs,
+		logger:  logger,
+	}
+}
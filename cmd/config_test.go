@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/devopsext/events/common"
+	sre "github.com/devopsext/sre/common"
+)
+
+type countOutput struct {
+	name   string
+	mutex  sync.Mutex
+	events int
+}
+
+func (o *countOutput) Name() string                 { return o.name }
+func (o *countOutput) Send(event *common.Event)     { o.Deliver(event) }
+func (o *countOutput) Stop(_ context.Context) error { return nil }
+
+func (o *countOutput) Deliver(_ *common.Event) error {
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.events++
+	return nil
+}
+
+func (o *countOutput) count() int {
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.events
+}
+
+const reloadConfig = `
+filters:
+  - name: drop-test
+    type: TestEvent
+    expression: .data.drop == true
+routes:
+  - type: AlertEvent
+    outputs: [alerts]
+  - default: true
+    outputs: [other]
+`
+
+const reloadBadRoutes = `
+filters:
+  - name: drop-all
+    expression: .type != null
+routes:
+  - type: "AlertEvent("
+    outputs: [alerts]
+`
+
+func TestConfigReloadKeepsOldConfigOnBadRoutes(t *testing.T) {
+
+	observability := common.NewObservability(sre.NewLogs(), sre.NewTraces(), sre.NewMetrics(), sre.NewEvents())
+	path := filepath.Join(t.TempDir(), "pipeline.yaml")
+	if err := os.WriteFile(path, []byte(reloadConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := common.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs := common.NewOutputs(observability)
+	alerts := &countOutput{name: "alerts"}
+	other := &countOutput{name: "other"}
+	outputs.Add(alerts)
+	outputs.Add(other)
+	if err := outputs.SetRouting(config.Filters, config.Routes); err != nil {
+		t.Fatal(err)
+	}
+
+	reloadable := newConfigReloadable(path, config, &outputs, observability.Logs())
+	if err := os.WriteFile(path, []byte(reloadBadRoutes), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := reloadable.Reload(); err == nil || changed {
+		t.Fatalf("Reload() = %v, %v, want error", changed, err)
+	}
+
+	// new drop-all filter isn't applied and old routes still work
+	outputs.Send(&common.Event{Type: "AlertEvent"})
+	outputs.Send(&common.Event{Type: "TestEvent", Data: map[string]interface{}{"drop": true}})
+	outputs.Send(&common.Event{Type: "TestEvent"})
+	if alerts.count() != 1 || other.count() != 1 {
+		t.Fatalf("alerts %d, other %d, want 1 and 1", alerts.count(), other.count())
+	}
+}
@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
+	"regexp"
 	"strings"
 	"time"
 
@@ -33,19 +35,26 @@ var metrics = sreCommon.NewMetrics()
 var events = sreCommon.NewEvents()
 var stdout *sreProvider.Stdout
 var mainWG sync.WaitGroup
+var outputsWG sync.WaitGroup
+
+const outputsStopTimeout = 5 * time.Second
 
 type RootOptions struct {
-	Logs    []string
-	Metrics []string
-	Traces  []string
-	Events  []string
+	Logs            []string
+	Metrics         []string
+	Traces          []string
+	Events          []string
+	ShutdownTimeout int
+	Config          string
 }
 
 var rootOptions = RootOptions{
-	Logs:    strings.Split(envGet("LOGS", "stdout").(string), ","),
-	Metrics: strings.Split(envGet("METRICS", "prometheus").(string), ","),
-	Traces:  strings.Split(envGet("TRACES", "").(string), ","),
-	Events:  strings.Split(envGet("EVENTS", "").(string), ","),
+	Logs:            strings.Split(envGet("LOGS", "stdout").(string), ","),
+	Metrics:         strings.Split(envGet("METRICS", "prometheus").(string), ","),
+	Traces:          strings.Split(envGet("TRACES", "").(string), ","),
+	Events:          strings.Split(envGet("EVENTS", "").(string), ","),
+	ShutdownTimeout: envGet("SHUTDOWN_TIMEOUT", 20).(int),
+	Config:          envGet("CONFIG", "").(string),
 }
 
 var textTemplateOptions = toolsRender.TemplateOptions{
@@ -68,6 +77,7 @@ var prometheusOptions = sreProvider.PrometheusOptions{
 
 var httpInputOptions = input.HttpInputOptions{
 	HealthcheckURL:    envGet("HTTP_IN_HEALTHCHECK_URL", "/healthcheck").(string),
+	DeliveriesURL:     envGet("HTTP_IN_DELIVERIES_URL", "").(string),
 	K8sURL:            envGet("HTTP_IN_K8S_URL", "").(string),
 	KubeURL:           envGet("HTTP_IN_KUBE_URL", "").(string),
 	WinEventURL:       envGet("HTTP_IN_WINEVENT_URL", "").(string),
@@ -76,6 +86,10 @@ var httpInputOptions = input.HttpInputOptions{
 	AlertmanagerURL:   envGet("HTTP_IN_ALERTMANAGER_URL", "").(string),
 	GitlabURL:         envGet("HTTP_IN_GITLAB_URL", "").(string),
 	DataDogURL:        envGet("HTTP_IN_DATADOG_URL", "").(string),
+	NewRelicURL:       envGet("HTTP_IN_NEWRELIC_URL", "").(string),
+	GrafanaURL:        envGet("HTTP_IN_GRAFANA_URL", "").(string),
+	PagerDutyURL:      envGet("HTTP_IN_PAGERDUTY_URL", "").(string),
+	OpsgenieURL:       envGet("HTTP_IN_OPSGENIE_URL", "").(string),
 	CustomJsonURL:     envGet("HTTP_IN_CUSTOMJSON_URL", "").(string),
 	AWSURL:            envGet("HTTP_IN_AWS_URL", "").(string),
 	ZabbixURL:         envGet("HTTP_IN_ZABBIX_URL", "").(string),
@@ -83,6 +97,7 @@ var httpInputOptions = input.HttpInputOptions{
 	CloudflareURL:     envGet("HTTP_IN_CLOUDFLARE_URL", "").(string),
 	Site24x7URL:       envGet("HTTP_IN_SITE24X7_URL", "").(string),
 	TeamcityURL:       envGet("HTTP_IN_TEAMCITY_URL", "").(string),
+	AuthConfig:        envGet("HTTP_IN_AUTH", "").(string),
 
 	ServerName:    envGet("HTTP_IN_SERVER_NAME", "").(string),
 	Listen:        envGet("HTTP_IN_LISTEN", ":80").(string),
@@ -91,13 +106,27 @@ var httpInputOptions = input.HttpInputOptions{
 	Cert:          envGet("HTTP_IN_CERT", "").(string),
 	Key:           envGet("HTTP_IN_KEY", "").(string),
 	Chain:         envGet("HTTP_IN_CHAIN", "").(string),
+	ClientAuth:    envGet("HTTP_IN_CLIENT_AUTH", "").(string),
 	HeaderTraceID: envGet("HTTP_IN_HEADER_TRACE_ID", "X-Trace-ID").(string),
+	Accepted:      envGet("HTTP_IN_ACCEPTED", false).(bool),
+
+	MaxBodySize:       envGet("HTTP_IN_MAX_BODY_SIZE", int64(10<<20)).(int64),
+	ReadHeaderTimeout: envGet("HTTP_IN_READ_HEADER_TIMEOUT", 10).(int),
+	ReadTimeout:       envGet("HTTP_IN_READ_TIMEOUT", 30).(int),
+	WriteTimeout:      envGet("HTTP_IN_WRITE_TIMEOUT", 30).(int),
+	IdleTimeout:       envGet("HTTP_IN_IDLE_TIMEOUT", 120).(int),
+	PathRateLimit:     envGet("HTTP_IN_PATH_RATE_LIMIT", 0).(int),
+	PathRateBurst:     envGet("HTTP_IN_PATH_RATE_BURST", 0).(int),
+	ClientRateLimit:   envGet("HTTP_IN_CLIENT_RATE_LIMIT", 0).(int),
+	ClientRateBurst:   envGet("HTTP_IN_CLIENT_RATE_BURST", 0).(int),
 }
 
 var pubsubInputOptions = input.PubSubInputOptions{
 	Credentials:  envGet("PUBSUB_IN_CREDENTIALS", "").(string),
 	ProjectID:    envGet("PUBSUB_IN_PROJECT_ID", "").(string),
 	Subscription: envGet("PUBSUB_IN_SUBSCRIPTION", "").(string),
+	Processor:    envGet("PUBSUB_IN_PROCESSOR", "").(string),
+	Channel:      envGet("PUBSUB_IN_CHANNEL", "").(string),
 }
 
 var nomadInputOptions = input.NomadInputOptions{
@@ -106,6 +135,25 @@ var nomadInputOptions = input.NomadInputOptions{
 	Topics:  strings.Split(envGet("NOMAD_TOPICS", "Deployment,Evaluation,Job,Allocation,Service").(string), ","), //Deployment,Evaluation,Allocation,Job,Node"
 }
 
+var kafkaInputOptions = input.KafkaInputOptions{
+	ClientID:      envGet("KAFKA_IN_CLIENT_ID", "events").(string),
+	Brokers:       envGet("KAFKA_IN_BROKERS", "").(string),
+	Topics:        strings.Split(envGet("KAFKA_IN_TOPICS", "").(string), ","),
+	GroupID:       envGet("KAFKA_IN_GROUP_ID", "events").(string),
+	Offset:        envGet("KAFKA_IN_OFFSET", "newest").(string),
+	Processor:     envGet("KAFKA_IN_PROCESSOR", "").(string),
+	Channel:       envGet("KAFKA_IN_CHANNEL", "").(string),
+	RetryDelay:    envGet("KAFKA_IN_RETRY_DELAY", 5).(int),
+	SaslMechanism: envGet("KAFKA_IN_SASL_MECHANISM", "").(string),
+	SaslUser:      envGet("KAFKA_IN_SASL_USER", "").(string),
+	SaslPassword:  envGet("KAFKA_IN_SASL_PASSWORD", "").(string),
+	Tls:           envGet("KAFKA_IN_TLS", false).(bool),
+	Insecure:      envGet("KAFKA_IN_INSECURE", false).(bool),
+	Cert:          envGet("KAFKA_IN_CERT", "").(string),
+	Key:           envGet("KAFKA_IN_KEY", "").(string),
+	Chain:         envGet("KAFKA_IN_CHAIN", "").(string),
+}
+
 var vcInputOptions = input.VCenterInputOptions{
 	URL:           envGet("VCENTER_IN_URL", "").(string),
 	InsecureSSL:   envGet("VCENTER_IN_INSECURE", false).(bool),
@@ -118,6 +166,65 @@ var vcInputOptions = input.VCenterInputOptions{
 	DelayMS:       envGet("VCENTER_IN_DELAY_MS", 5000).(int),
 }
 
+var queueOptions = common.QueueOptions{
+	Dir:         envGet("QUEUE_DIR", "").(string),
+	SegmentSize: envGet("QUEUE_SEGMENT_SIZE", 16777216).(int),
+	RetryDelay:  envGet("QUEUE_RETRY_DELAY", 5).(int),
+	MaxAttempts: envGet("QUEUE_MAX_ATTEMPTS", 0).(int),
+}
+
+var retryOptions = common.RetryOptions{
+	MaxAttempts:      envGet("RETRY_MAX_ATTEMPTS", 3).(int),
+	Delay:            envGet("RETRY_DELAY", 1000).(int),
+	MaxDelay:         envGet("RETRY_MAX_DELAY", 30000).(int),
+	Factor:           envGet("RETRY_FACTOR", 2.0).(float64),
+	Jitter:           envGet("RETRY_JITTER", 0.2).(float64),
+	DeadLetterFile:   envGet("RETRY_DEAD_LETTER_FILE", "").(string),
+	DeadLetterOutput: envGet("RETRY_DEAD_LETTER_OUTPUT", "").(string),
+}
+
+var poolOptions = common.PoolOptions{
+	Workers:   envGet("POOL_WORKERS", 0).(int),
+	QueueSize: envGet("POOL_QUEUE_SIZE", 1000).(int),
+	Overflow:  envGet("POOL_OVERFLOW", common.PoolOverflowBlock).(string),
+}
+
+var customJsonProcessorOptions = processor.CustomJsonProcessorOptions{
+	TimePath:        envGet("CUSTOMJSON_TIME_PATH", "").(string),
+	TimeFormat:      envGet("CUSTOMJSON_TIME_FORMAT", "").(string),
+	ChannelPath:     envGet("CUSTOMJSON_CHANNEL_PATH", "").(string),
+	FingerprintPath: envGet("CUSTOMJSON_FINGERPRINT_PATH", "").(string),
+}
+
+var pagerDutyProcessorOptions = processor.PagerDutyProcessorOptions{
+	Secret: envGet("PAGERDUTY_SECRET", "").(string),
+}
+
+var opsgenieProcessorOptions = processor.OpsgenieProcessorOptions{
+	Header: envGet("OPSGENIE_HEADER", "X-Opsgenie-Secret").(string),
+	Secret: envGet("OPSGENIE_SECRET", "").(string),
+}
+
+var dedupOptions = common.DedupOptions{
+	TTL:      envGet("DEDUP_TTL", 0).(int),
+	Size:     envGet("DEDUP_SIZE", 10000).(int),
+	Template: envGet("DEDUP_TEMPLATE", "").(string),
+	File:     envGet("DEDUP_FILE", "").(string),
+}
+
+var stateOptions = common.StateOptions{
+	TTL:  envGet("STATE_TTL", 604800).(int),
+	File: envGet("STATE_FILE", "").(string),
+}
+
+var deliveryOptions = common.DeliveryOptions{
+	Size: envGet("DELIVERY_SIZE", 1000).(int),
+}
+
+var reloaderOptions = common.ReloaderOptions{
+	Interval: envGet("RELOAD_INTERVAL", 10).(int),
+}
+
 var collectorOutputOptions = output.CollectorOutputOptions{
 	Address: envGet("COLLECTOR_OUT_ADDRESS", "").(string),
 	Message: envGet("COLLECTOR_OUT_MESSAGE", "").(string),
@@ -128,12 +235,13 @@ var kafkaOutputOptions = output.KafkaOutputOptions{
 	Message:            envGet("KAFKA_OUT_MESSAGE", "").(string),
 	Brokers:            envGet("KAFKA_OUT_BROKERS", "").(string),
 	Topic:              envGet("KAFKA_OUT_TOPIC", appName).(string),
-	FlushFrequency:     envGet("KAFKA_OUT_FLUSH_FREQUENCY", 1).(int),
-	FlushMaxMessages:   envGet("KAFKA_OUT_FLUSH_MAX_MESSAGES", 100).(int),
+	FlushFrequency:     envGet("KAFKA_OUT_FLUSH_FREQUENCY", 0).(int),
+	FlushMaxMessages:   envGet("KAFKA_OUT_FLUSH_MAX_MESSAGES", 0).(int),
 	NetMaxOpenRequests: envGet("KAFKA_OUT_NET_MAX_OPEN_REQUESTS", 5).(int),
 	NetDialTimeout:     envGet("KAFKA_OUT_NET_DIAL_TIMEOUT", 30).(int),
 	NetReadTimeout:     envGet("KAFKA_OUT_NET_READ_TIMEOUT", 30).(int),
 	NetWriteTimeout:    envGet("KAFKA_OUT_NET_WRITE_TIMEOUT", 30).(int),
+	CloudEvents:        envGet("KAFKA_OUT_CLOUDEVENTS", "").(string),
 }
 
 var telegramOutputOptions = output.TelegramOutputOptions{
@@ -150,7 +258,8 @@ var telegramOutputOptions = output.TelegramOutputOptions{
 	Forward:         envGet("TELEGRAM_OUT_FORWARD", "").(string),
 	//Also note that your bot will not be able to send more than 20 messages per minute to the same group.
 	//https://core.telegram.org/bots/faq#my-bot-is-hitting-limits-how-do-i-avoid-this
-	RateLimit: envGet("TELEGRAM_RATELIMIT", 18).(int),
+	RateLimit:   envGet("TELEGRAM_RATELIMIT", 18).(int),
+	ResolveMode: envGet("TELEGRAM_OUT_RESOLVE_MODE", common.StateModeReply).(string),
 }
 
 var slackOutputOptions = output.SlackOutputOptions{
@@ -162,6 +271,7 @@ var slackOutputOptions = output.SlackOutputOptions{
 	Channel:         envGet("SLACK_OUT_CHANNEL", "").(string),
 	Forward:         envGet("SLACK_OUT_FORWARD", "").(string),
 	Insecure:        envGet("SLACK_OUT_INSECURE", false).(bool),
+	ResolveMode:     envGet("SLACK_OUT_RESOLVE_MODE", common.StateModeReply).(string),
 }
 
 var workchatOutputOptions = output.WorkchatOutputOptions{
@@ -171,6 +281,7 @@ var workchatOutputOptions = output.WorkchatOutputOptions{
 	Timeout:          envGet("WORKCHAT_OUT_TIMEOUT", 30).(int),
 	AlertExpression:  envGet("WORKCHAT_OUT_ALERT_EXPRESSION", "g0.expr").(string),
 	NotificationType: envGet("WORKCHAT_OUT_NOTIFICATION_TYPE", "REGULAR").(string),
+	ResolveMode:      envGet("WORKCHAT_OUT_RESOLVE_MODE", common.StateModeReply).(string),
 }
 
 var newrelicOutputOptions = output.NewRelicOutputOptions{
@@ -196,6 +307,7 @@ var pubsubOutputOptions = output.PubSubOutputOptions{
 	ProjectID:     envGet("PUBSUB_OUT_PROJECT_ID", "").(string),
 	Message:       envGet("PUBSUB_OUT_MESSAGE", "").(string),
 	TopicSelector: envGet("PUBSUB_OUT_TOPIC_SELECTOR", "").(string),
+	CloudEvents:   envGet("PUBSUB_OUT_CLOUDEVENTS", "").(string),
 }
 
 var gitlabOutputOptions = output.GitlabOutputOptions{
@@ -302,23 +414,120 @@ func envGet(s string, d interface{}) interface{} {
 	return utils.EnvGet(fmt.Sprintf("%s_%s", APPNAME, s), d)
 }
 
-func interceptSyscall() {
+// outputRetryOptions allows to override retry options per output, like EVENTS_SLACK_OUT_RETRY_MAX_ATTEMPTS
+func outputRetryOptions(prefix string) common.RetryOptions {
 
-	c := make(chan os.Signal)
+	return common.RetryOptions{
+		MaxAttempts:      envGet(prefix+"_OUT_RETRY_MAX_ATTEMPTS", retryOptions.MaxAttempts).(int),
+		Delay:            envGet(prefix+"_OUT_RETRY_DELAY", retryOptions.Delay).(int),
+		MaxDelay:         envGet(prefix+"_OUT_RETRY_MAX_DELAY", retryOptions.MaxDelay).(int),
+		Factor:           envGet(prefix+"_OUT_RETRY_FACTOR", retryOptions.Factor).(float64),
+		Jitter:           envGet(prefix+"_OUT_RETRY_JITTER", retryOptions.Jitter).(float64),
+		DeadLetterFile:   envGet(prefix+"_OUT_RETRY_DEAD_LETTER_FILE", retryOptions.DeadLetterFile).(string),
+		DeadLetterOutput: envGet(prefix+"_OUT_RETRY_DEAD_LETTER_OUTPUT", retryOptions.DeadLetterOutput).(string),
+	}
+}
+
+// outputPoolOptions allows to override pool options per output, like EVENTS_SLACK_OUT_POOL_WORKERS
+func outputPoolOptions(prefix string) common.PoolOptions {
+
+	return common.PoolOptions{
+		Workers:   envGet(prefix+"_OUT_POOL_WORKERS", poolOptions.Workers).(int),
+		QueueSize: envGet(prefix+"_OUT_POOL_QUEUE_SIZE", poolOptions.QueueSize).(int),
+		Overflow:  envGet(prefix+"_OUT_POOL_OVERFLOW", poolOptions.Overflow).(string),
+	}
+}
+
+func interceptSyscall(cancel context.CancelFunc) {
+
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
 	go func() {
 		<-c
 		logs.Info("Exiting...")
+		cancel()
+		<-c
+		logs.Info("Exiting immediately...")
 		os.Exit(1)
 	}()
 }
 
+func interceptReload(ctx context.Context, reloader *common.Reloader) {
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				signal.Stop(c)
+				return
+			case <-c:
+				logs.Info("Reloading...")
+				reloader.Reload()
+			}
+		}
+	}()
+}
+
+// waitGroup returns false if ctx is done before wg
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) bool {
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// shutdown drains in-flight sends within shutdown timeout and then flushes outputs
+func shutdown(inputsWG *sync.WaitGroup, outputs *common.Outputs) {
+
+	drain, cancelDrain := context.WithTimeout(context.Background(), time.Duration(rootOptions.ShutdownTimeout)*time.Second)
+	defer cancelDrain()
+
+	if !waitGroup(drain, inputsWG) {
+		logs.Warn("Inputs are not stopped in %d seconds", rootOptions.ShutdownTimeout)
+	}
+	if !waitGroup(drain, &outputsWG) {
+		logs.Warn("Output sends are not finished in %d seconds", rootOptions.ShutdownTimeout)
+	}
+
+	stop, cancelStop := context.WithTimeout(context.Background(), outputsStopTimeout)
+	defer cancelStop()
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- outputs.Stop(stop)
+	}()
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			logs.Error(err)
+		}
+	case <-stop.Done():
+		logs.Warn("Outputs are not stopped in %s", outputsStopTimeout)
+	}
+	logs.Info("Stopped")
+}
+
 func Execute() {
 
 	var newrelicEventer *sreProvider.NewRelicEventer
 	var grafanaEventer *sreProvider.GrafanaEventer
 	var datadogEventer *sreProvider.DataDogEventer
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	rootCmd := &cobra.Command{
 		Use:   "events",
 		Short: "Events",
@@ -453,45 +662,38 @@ func Execute() {
 		Run: func(cmd *cobra.Command, args []string) {
 
 			observability := common.NewObservability(logs, traces, metrics, events)
-			outputs := common.NewOutputs(logs)
-
-			processors := common.NewProcessors()
-			processors.Add(processor.NewK8sProcessor(&outputs, observability))
-			processors.Add(processor.NewKubeProcessor(&outputs, observability))
-			processors.Add(processor.NewWinEventProcessor(&outputs, observability))
-			processors.Add(processor.NewGitlabProcessor(&outputs, observability))
-			processors.Add(processor.NewAlertmanagerProcessor(&outputs, observability))
-			processors.Add(processor.NewCustomJsonProcessor(&outputs, observability))
-			processors.Add(processor.NewRancherProcessor(&outputs, observability))
-			processors.Add(processor.NewDataDogProcessor(&outputs, observability))
-			processors.Add(processor.NewSite24x7Processor(&outputs, observability))
-			processors.Add(processor.NewCloudflareProcessor(&outputs, observability))
-			processors.Add(processor.NewGoogleProcessor(&outputs, observability))
-			processors.Add(processor.NewAWSProcessor(&outputs, observability))
-			processors.Add(processor.NewZabbixProcessor(&outputs, observability))
-			processors.Add(processor.NewVCenterProcessor(&outputs, observability))
-			processors.Add(processor.NewObserviumEventProcessor(&outputs, observability))
-			processors.Add(processor.NewTeamcityProcessor(&outputs, observability))
-			processors.Add(processor.NewNomadProcessor(&outputs, observability))
-			inputs := common.NewInputs()
-			inputs.Add(input.NewHttpInput(httpInputOptions, processors, observability))
-			inputs.Add(input.NewPubSubInput(pubsubInputOptions, processors, observability))
-			inputs.Add(input.NewVCenterInput(vcInputOptions, processors, observability))
-			inputs.Add(input.NewNomadInput(nomadInputOptions, processors, observability))
-
-			outputs.Add(output.NewCollectorOutput(&mainWG, collectorOutputOptions, textTemplateOptions, observability))
-			outputs.Add(output.NewKafkaOutput(&mainWG, kafkaOutputOptions, textTemplateOptions, observability))
-			outputs.Add(output.NewTelegramOutput(&mainWG, telegramOutputOptions, textTemplateOptions, grafanaRenderOptions, observability, &outputs))
-			outputs.Add(output.NewSlackOutput(&mainWG, slackOutputOptions, textTemplateOptions, grafanaRenderOptions, observability, &outputs))
-			outputs.Add(output.NewWorkchatOutput(&mainWG, workchatOutputOptions, textTemplateOptions, grafanaRenderOptions, observability))
-			outputs.Add(output.NewNewRelicOutput(&mainWG, newrelicOutputOptions, textTemplateOptions, observability, newrelicEventer))
-			outputs.Add(output.NewDataDogOutput(&mainWG, datadogOutputOptions, textTemplateOptions, observability, datadogEventer))
-			outputs.Add(output.NewGrafanaOutput(&mainWG, grafanaOutputOptions, textTemplateOptions, observability, grafanaEventer))
-			outputs.Add(output.NewPubSubOutput(&mainWG, pubsubOutputOptions, textTemplateOptions, observability))
-			outputs.Add(output.NewGitlabOutput(&mainWG, gitlabOutputOptions, textTemplateOptions, observability))
-
-			inputs.Start(&mainWG, &outputs)
-			mainWG.Wait()
+
+			config, err := loadConfig()
+			if err != nil {
+				logs.Error(err)
+				os.Exit(1)
+			}
+
+			p, err := newPipeline(config, eventers{newrelicEventer, grafanaEventer, datadogEventer}, observability)
+			if err != nil {
+				logs.Error(err)
+				os.Exit(1)
+			}
+
+			reloader := common.NewReloader(reloaderOptions, observability)
+			if !utils.IsEmpty(rootOptions.Config) {
+				reloader.Add(newConfigReloadable(rootOptions.Config, config, p.outputs, logs))
+			}
+			reloader.Start(ctx, &mainWG)
+			interceptReload(ctx, reloader)
+
+			var inputsWG sync.WaitGroup
+			p.inputs.Start(ctx, &inputsWG, p.outputs)
+
+			// exit when nothing is left to wait for, as before
+			go func() {
+				inputsWG.Wait()
+				mainWG.Wait()
+				cancel()
+			}()
+
+			<-ctx.Done()
+			shutdown(&inputsWG, p.outputs)
 		},
 	}
 
@@ -501,6 +703,8 @@ func Execute() {
 	flags.StringSliceVar(&rootOptions.Metrics, "metrics", rootOptions.Metrics, "Metric providers: prometheus, datadog, opentelemetry, newrelic")
 	flags.StringSliceVar(&rootOptions.Traces, "traces", rootOptions.Traces, "Trace providers: jaeger, datadog, opentelemetry, newrelic")
 	flags.StringSliceVar(&rootOptions.Events, "events", rootOptions.Events, "Event providers: grafana, datadog, newrelic")
+	flags.StringVar(&rootOptions.Config, "config", rootOptions.Config, "Pipeline config file in YAML or JSON, env variables and flags are used as defaults")
+	flags.IntVar(&rootOptions.ShutdownTimeout, "shutdown-timeout", rootOptions.ShutdownTimeout, "Shutdown timeout in seconds to drain in-flight events")
 
 	flags.StringVar(&textTemplateOptions.TimeFormat, "template-time-format", textTemplateOptions.TimeFormat, "Template time format")
 
@@ -523,6 +727,10 @@ func Execute() {
 	flags.StringVar(&httpInputOptions.AlertmanagerURL, "http-in-alertmanager-url", httpInputOptions.AlertmanagerURL, "Http Alertmanager url")
 	flags.StringVar(&httpInputOptions.GitlabURL, "http-in-gitlab-url", httpInputOptions.GitlabURL, "Http Gitlab url")
 	flags.StringVar(&httpInputOptions.DataDogURL, "http-in-datadog-url", httpInputOptions.DataDogURL, "Http DataDog url")
+	flags.StringVar(&httpInputOptions.NewRelicURL, "http-in-newrelic-url", httpInputOptions.NewRelicURL, "Http NewRelic url")
+	flags.StringVar(&httpInputOptions.GrafanaURL, "http-in-grafana-url", httpInputOptions.GrafanaURL, "Http Grafana url")
+	flags.StringVar(&httpInputOptions.PagerDutyURL, "http-in-pagerduty-url", httpInputOptions.PagerDutyURL, "Http PagerDuty url")
+	flags.StringVar(&httpInputOptions.OpsgenieURL, "http-in-opsgenie-url", httpInputOptions.OpsgenieURL, "Http Opsgenie url")
 	flags.StringVar(&httpInputOptions.Site24x7URL, "http-in-site24x7-url", httpInputOptions.Site24x7URL, "Http Site24x7 url")
 	flags.StringVar(&httpInputOptions.CloudflareURL, "http-in-cloudflare-url", httpInputOptions.CloudflareURL, "Http Cloudflare url")
 	flags.StringVar(&httpInputOptions.GoogleURL, "http-in-google-url", httpInputOptions.GoogleURL, "Http Google url")
@@ -530,18 +738,33 @@ func Execute() {
 	flags.StringVar(&httpInputOptions.ZabbixURL, "http-in-zabbix-url", httpInputOptions.ZabbixURL, "Http Zabbix url")
 	flags.StringVar(&httpInputOptions.CustomJsonURL, "http-in-customjson-url", httpInputOptions.CustomJsonURL, "Http CustomJson url")
 	flags.StringVar(&httpInputOptions.TeamcityURL, "http-in-teamcity-url", httpInputOptions.TeamcityURL, "Http Teamcity url")
+	flags.StringVar(&httpInputOptions.AuthConfig, "http-in-auth", httpInputOptions.AuthConfig, "Http auth of urls, file or content in YAML or JSON")
+	flags.StringVar(&httpInputOptions.DeliveriesURL, "http-in-deliveries-url", httpInputOptions.DeliveriesURL, "Http debug url of delivery log")
 	flags.StringVar(&httpInputOptions.ServerName, "http-in-server-name", httpInputOptions.ServerName, "Http server name")
 	flags.StringVar(&httpInputOptions.Listen, "http-in-listen", httpInputOptions.Listen, "Http listen")
 	flags.BoolVar(&httpInputOptions.Tls, "http-in-tls", httpInputOptions.Tls, "Http TLS")
 	flags.BoolVar(&httpInputOptions.Insecure, "http-in-insecure", httpInputOptions.Insecure, "Http insecure skip verify")
 	flags.StringVar(&httpInputOptions.Cert, "http-in-cert", httpInputOptions.Cert, "Http cert file or content")
 	flags.StringVar(&httpInputOptions.Key, "http-in-key", httpInputOptions.Key, "Http key file or content")
-	flags.StringVar(&httpInputOptions.Chain, "http-in-chain", httpInputOptions.Chain, "Http CA chain file or content")
+	flags.StringVar(&httpInputOptions.Chain, "http-in-chain", httpInputOptions.Chain, "Http client CA chain file or content")
+	flags.StringVar(&httpInputOptions.ClientAuth, "http-in-client-auth", httpInputOptions.ClientAuth, "Http client certificate policy: none, request, require, verify-if-given, require-and-verify")
 	flags.StringVar(&httpInputOptions.HeaderTraceID, "http-in-header-trace-id", httpInputOptions.HeaderTraceID, "Http trace ID header")
+	flags.BoolVar(&httpInputOptions.Accepted, "http-in-accepted", httpInputOptions.Accepted, "Http responds 202 once events are accepted by outputs")
+	flags.Int64Var(&httpInputOptions.MaxBodySize, "http-in-max-body-size", httpInputOptions.MaxBodySize, "Http max body size in bytes, 0 is unlimited")
+	flags.IntVar(&httpInputOptions.ReadHeaderTimeout, "http-in-read-header-timeout", httpInputOptions.ReadHeaderTimeout, "Http read header timeout in seconds")
+	flags.IntVar(&httpInputOptions.ReadTimeout, "http-in-read-timeout", httpInputOptions.ReadTimeout, "Http read timeout in seconds")
+	flags.IntVar(&httpInputOptions.WriteTimeout, "http-in-write-timeout", httpInputOptions.WriteTimeout, "Http write timeout in seconds")
+	flags.IntVar(&httpInputOptions.IdleTimeout, "http-in-idle-timeout", httpInputOptions.IdleTimeout, "Http idle timeout in seconds")
+	flags.IntVar(&httpInputOptions.PathRateLimit, "http-in-path-rate-limit", httpInputOptions.PathRateLimit, "Http rate limit per url, requests per second, 0 is unlimited")
+	flags.IntVar(&httpInputOptions.PathRateBurst, "http-in-path-rate-burst", httpInputOptions.PathRateBurst, "Http rate burst per url")
+	flags.IntVar(&httpInputOptions.ClientRateLimit, "http-in-client-rate-limit", httpInputOptions.ClientRateLimit, "Http rate limit per client IP, requests per second, 0 is unlimited")
+	flags.IntVar(&httpInputOptions.ClientRateBurst, "http-in-client-rate-burst", httpInputOptions.ClientRateBurst, "Http rate burst per client IP")
 
 	flags.StringVar(&pubsubInputOptions.Credentials, "pubsub-in-credentials", pubsubInputOptions.Credentials, "PubSub input credentials")
 	flags.StringVar(&pubsubInputOptions.ProjectID, "pubsub-in-project-id", pubsubInputOptions.ProjectID, "PubSub input project ID")
 	flags.StringVar(&pubsubInputOptions.Subscription, "pubsub-in-subscription", pubsubInputOptions.Subscription, "PubSub input subscription")
+	flags.StringVar(&pubsubInputOptions.Processor, "pubsub-in-processor", pubsubInputOptions.Processor, "PubSub input processor name for raw messages, messages are events if it's empty")
+	flags.StringVar(&pubsubInputOptions.Channel, "pubsub-in-channel", pubsubInputOptions.Channel, "PubSub input channel of raw messages, subscription is used if it's empty")
 
 	flags.StringVar(&vcInputOptions.URL, "vcenter-in-url", vcInputOptions.URL, "VCenter SDK url")
 	flags.BoolVar(&vcInputOptions.InsecureSSL, "vcenter-in-insecure", vcInputOptions.InsecureSSL, "VCenter insecure access")
@@ -553,20 +776,78 @@ func Execute() {
 	flags.StringVar(&vcInputOptions.CheckpointDir, "vcenter-checkpoint-dir", vcInputOptions.CheckpointDir, "VCenter checkpoint dir")
 	flags.IntVar(&vcInputOptions.DelayMS, "vcenter-in-delay-ms", vcInputOptions.DelayMS, "VCenter poll delay ms")
 
+	flags.StringVar(&kafkaInputOptions.ClientID, "kafka-in-client-id", kafkaInputOptions.ClientID, "Kafka input client ID")
+	flags.StringVar(&kafkaInputOptions.Brokers, "kafka-in-brokers", kafkaInputOptions.Brokers, "Kafka input brokers")
+	flags.StringSliceVar(&kafkaInputOptions.Topics, "kafka-in-topics", kafkaInputOptions.Topics, "Kafka input topics")
+	flags.StringVar(&kafkaInputOptions.GroupID, "kafka-in-group-id", kafkaInputOptions.GroupID, "Kafka input consumer group ID")
+	flags.StringVar(&kafkaInputOptions.Offset, "kafka-in-offset", kafkaInputOptions.Offset, "Kafka input initial offset of new group: newest, oldest")
+	flags.StringVar(&kafkaInputOptions.Processor, "kafka-in-processor", kafkaInputOptions.Processor, "Kafka input processor name for raw payloads, messages are events if it's empty")
+	flags.StringVar(&kafkaInputOptions.Channel, "kafka-in-channel", kafkaInputOptions.Channel, "Kafka input channel of raw payloads, topic is used if it's empty")
+	flags.IntVar(&kafkaInputOptions.RetryDelay, "kafka-in-retry-delay", kafkaInputOptions.RetryDelay, "Kafka input delay in seconds before message is consumed again")
+	flags.StringVar(&kafkaInputOptions.SaslMechanism, "kafka-in-sasl-mechanism", kafkaInputOptions.SaslMechanism, "Kafka input SASL mechanism: PLAIN, SCRAM-SHA-256, SCRAM-SHA-512")
+	flags.StringVar(&kafkaInputOptions.SaslUser, "kafka-in-sasl-user", kafkaInputOptions.SaslUser, "Kafka input SASL user")
+	flags.StringVar(&kafkaInputOptions.SaslPassword, "kafka-in-sasl-password", kafkaInputOptions.SaslPassword, "Kafka input SASL password")
+	flags.BoolVar(&kafkaInputOptions.Tls, "kafka-in-tls", kafkaInputOptions.Tls, "Kafka input TLS")
+	flags.BoolVar(&kafkaInputOptions.Insecure, "kafka-in-insecure", kafkaInputOptions.Insecure, "Kafka input insecure skip verify")
+	flags.StringVar(&kafkaInputOptions.Cert, "kafka-in-cert", kafkaInputOptions.Cert, "Kafka input client cert file or content")
+	flags.StringVar(&kafkaInputOptions.Key, "kafka-in-key", kafkaInputOptions.Key, "Kafka input client key file or content")
+	flags.StringVar(&kafkaInputOptions.Chain, "kafka-in-chain", kafkaInputOptions.Chain, "Kafka input CA chain file or content")
+
 	flags.StringVar(&nomadInputOptions.Address, "nomad-url", nomadInputOptions.Address, "Nomad url")
 	flags.StringVar(&nomadInputOptions.Token, "nomad-token", nomadInputOptions.Token, "Nomad token")
 	flags.StringSliceVar(&nomadInputOptions.Topics, "nomad-topics", nomadInputOptions.Topics, "Nomad topics")
 
+	flags.StringVar(&queueOptions.Dir, "queue-dir", queueOptions.Dir, "Queue directory, enables persistent output queues")
+	flags.IntVar(&queueOptions.SegmentSize, "queue-segment-size", queueOptions.SegmentSize, "Queue segment size in bytes")
+	flags.IntVar(&queueOptions.RetryDelay, "queue-retry-delay", queueOptions.RetryDelay, "Queue retry delay in seconds")
+	flags.IntVar(&queueOptions.MaxAttempts, "queue-max-attempts", queueOptions.MaxAttempts, "Queue max delivery attempts of retryable errors, 0 retries until event is delivered")
+
+	flags.IntVar(&retryOptions.MaxAttempts, "retry-max-attempts", retryOptions.MaxAttempts, "Retry max attempts per output delivery")
+	flags.IntVar(&retryOptions.Delay, "retry-delay", retryOptions.Delay, "Retry initial delay in milliseconds")
+	flags.IntVar(&retryOptions.MaxDelay, "retry-max-delay", retryOptions.MaxDelay, "Retry max delay in milliseconds")
+	flags.Float64Var(&retryOptions.Factor, "retry-factor", retryOptions.Factor, "Retry backoff factor")
+	flags.Float64Var(&retryOptions.Jitter, "retry-jitter", retryOptions.Jitter, "Retry jitter, part of delay from 0 to 1")
+	flags.StringVar(&retryOptions.DeadLetterFile, "retry-dead-letter-file", retryOptions.DeadLetterFile, "Retry dead letter JSONL file")
+	flags.StringVar(&retryOptions.DeadLetterOutput, "retry-dead-letter-output", retryOptions.DeadLetterOutput, "Retry dead letter output regex pattern")
+
+	flags.IntVar(&poolOptions.Workers, "pool-workers", poolOptions.Workers, "Pool workers per output, 0 sends every event in its own goroutine, it must be 0 with queue dir")
+	flags.IntVar(&poolOptions.QueueSize, "pool-queue-size", poolOptions.QueueSize, "Pool queue size per output")
+	flags.StringVar(&poolOptions.Overflow, "pool-overflow", poolOptions.Overflow, "Pool overflow policy: block, drop-oldest, reject")
+
+	flags.StringVar(&customJsonProcessorOptions.TimePath, "customjson-time-path", customJsonProcessorOptions.TimePath, "CustomJson JSON path of event time")
+	flags.StringVar(&customJsonProcessorOptions.TimeFormat, "customjson-time-format", customJsonProcessorOptions.TimeFormat, "CustomJson time format: unix, unix_ms or Go layout, RFC3339 or unix time is guessed if empty")
+	flags.StringVar(&customJsonProcessorOptions.ChannelPath, "customjson-channel-path", customJsonProcessorOptions.ChannelPath, "CustomJson JSON path of event channel")
+	flags.StringVar(&customJsonProcessorOptions.FingerprintPath, "customjson-fingerprint-path", customJsonProcessorOptions.FingerprintPath, "CustomJson JSON paths of event fingerprint, comma separated")
+
+	flags.StringVar(&pagerDutyProcessorOptions.Secret, "pagerduty-secret", pagerDutyProcessorOptions.Secret, "PagerDuty webhook signing secret")
+	flags.StringVar(&opsgenieProcessorOptions.Header, "opsgenie-header", opsgenieProcessorOptions.Header, "Opsgenie webhook secret header")
+	flags.StringVar(&opsgenieProcessorOptions.Secret, "opsgenie-secret", opsgenieProcessorOptions.Secret, "Opsgenie webhook secret")
+
+	flags.IntVar(&dedupOptions.TTL, "dedup-ttl", dedupOptions.TTL, "Dedup TTL in seconds, 0 disables it")
+	flags.IntVar(&dedupOptions.Size, "dedup-size", dedupOptions.Size, "Dedup max fingerprints in memory")
+	flags.StringVar(&dedupOptions.Template, "dedup-template", dedupOptions.Template, "Dedup fingerprint template")
+	flags.StringVar(&dedupOptions.File, "dedup-file", dedupOptions.File, "Dedup fingerprints file")
+
+	flags.IntVar(&stateOptions.TTL, "state-ttl", stateOptions.TTL, "Alert state TTL in seconds since last update")
+	flags.StringVar(&stateOptions.File, "state-file", stateOptions.File, "Alert state file")
+
+	flags.IntVar(&deliveryOptions.Size, "delivery-size", deliveryOptions.Size, "Delivery log max events in memory, 0 disables it")
+
+	flags.IntVar(&reloaderOptions.Interval, "reload-interval", reloaderOptions.Interval, "Reload interval in seconds to check template and config files, 0 disables it")
+
 	flags.StringVar(&kafkaOutputOptions.Brokers, "kafka-out-brokers", kafkaOutputOptions.Brokers, "Kafka brokers")
 	flags.StringVar(&kafkaOutputOptions.Topic, "kafka-out-topic", kafkaOutputOptions.Topic, "Kafka topic")
 	flags.StringVar(&kafkaOutputOptions.ClientID, "kafka-out-client-id", kafkaOutputOptions.ClientID, "Kafka client id")
 	flags.StringVar(&kafkaOutputOptions.Message, "kafka-out-message", kafkaOutputOptions.Message, "Kafka message template")
-	flags.IntVar(&kafkaOutputOptions.FlushFrequency, "kafka-out-flush-frequency", kafkaOutputOptions.FlushFrequency, "Kafka Producer flush frequency")
-	flags.IntVar(&kafkaOutputOptions.FlushMaxMessages, "kafka-out-flush-max-messages", kafkaOutputOptions.FlushMaxMessages, "Kafka Producer flush max messages")
+	flags.IntVar(&kafkaOutputOptions.FlushFrequency, "kafka-out-flush-frequency", kafkaOutputOptions.FlushFrequency, "Kafka Producer flush frequency, deprecated, messages are sent without batching")
+	flags.IntVar(&kafkaOutputOptions.FlushMaxMessages, "kafka-out-flush-max-messages", kafkaOutputOptions.FlushMaxMessages, "Kafka Producer flush max messages, deprecated, messages are sent without batching")
+	flags.MarkDeprecated("kafka-out-flush-frequency", "messages are sent without batching")
+	flags.MarkDeprecated("kafka-out-flush-max-messages", "messages are sent without batching")
 	flags.IntVar(&kafkaOutputOptions.NetMaxOpenRequests, "kafka-out-net-max-open-requests", kafkaOutputOptions.NetMaxOpenRequests, "Kafka Net max open requests")
 	flags.IntVar(&kafkaOutputOptions.NetDialTimeout, "kafka-out-net-dial-timeout", kafkaOutputOptions.NetDialTimeout, "Kafka Net dial timeout")
 	flags.IntVar(&kafkaOutputOptions.NetReadTimeout, "kafka-out-net-read-timeout", kafkaOutputOptions.NetReadTimeout, "Kafka Net read timeout")
 	flags.IntVar(&kafkaOutputOptions.NetWriteTimeout, "kafka-out-net-write-timeout", kafkaOutputOptions.NetWriteTimeout, "Kafka Net write timeout")
+	flags.StringVar(&kafkaOutputOptions.CloudEvents, "kafka-out-cloudevents", kafkaOutputOptions.CloudEvents, "Kafka CloudEvents mode: structured, binary")
 
 	flags.StringVar(&telegramOutputOptions.IDToken, "telegram-out-id-token", telegramOutputOptions.IDToken, "Telegram ID token")
 	flags.StringVar(&telegramOutputOptions.ChatID, "telegram-out-chat-id", telegramOutputOptions.ChatID, "Telegram chat ID")
@@ -578,6 +859,7 @@ func Execute() {
 	flags.BoolVar(&telegramOutputOptions.DisableNotification, "telegram-out-disable-notification", telegramOutputOptions.DisableNotification, "Telegram disable notification")
 	flags.StringVar(&telegramOutputOptions.Forward, "telegram-out-forward", telegramOutputOptions.Forward, "Telegram forward regex pattern")
 	flags.IntVar(&telegramOutputOptions.RateLimit, "telegram-rate-limit", telegramOutputOptions.RateLimit, "Ratelimit for telegram, per minute")
+	flags.StringVar(&telegramOutputOptions.ResolveMode, "telegram-out-resolve-mode", telegramOutputOptions.ResolveMode, "Telegram resolved alert mode: reply, edit or empty to send new message")
 
 	flags.StringVar(&slackOutputOptions.Message, "slack-out-message", slackOutputOptions.Message, "Slack message template")
 	flags.StringVar(&slackOutputOptions.ChannelSelector, "slack-out-channel-selector", slackOutputOptions.ChannelSelector, "Slack Channel selector template")
@@ -587,6 +869,7 @@ func Execute() {
 	flags.StringVar(&slackOutputOptions.Channel, "slack-out-channel", slackOutputOptions.Channel, "Slack channel")
 	flags.StringVar(&slackOutputOptions.Forward, "slack-out-forward", slackOutputOptions.Forward, "Slack forward regex pattern")
 	flags.BoolVar(&slackOutputOptions.Insecure, "slack-out-insecure", slackOutputOptions.Insecure, "Slack insecure skip verify")
+	flags.StringVar(&slackOutputOptions.ResolveMode, "slack-out-resolve-mode", slackOutputOptions.ResolveMode, "Slack resolved alert mode: reply, edit or empty to send new message")
 
 	flags.StringVar(&workchatOutputOptions.URL, "workchat-out-url", workchatOutputOptions.URL, "Workchat URL")
 	flags.StringVar(&workchatOutputOptions.Message, "workchat-out-message", workchatOutputOptions.Message, "Workchat message template")
@@ -594,11 +877,13 @@ func Execute() {
 	flags.IntVar(&workchatOutputOptions.Timeout, "workchat-out-timeout", workchatOutputOptions.Timeout, "Workchat timeout")
 	flags.StringVar(&workchatOutputOptions.AlertExpression, "workchat-out-alert-expression", workchatOutputOptions.AlertExpression, "Workchat alert expression")
 	flags.StringVar(&workchatOutputOptions.NotificationType, "workchat-out-notification-type", workchatOutputOptions.NotificationType, "Workchat notification type")
+	flags.StringVar(&workchatOutputOptions.ResolveMode, "workchat-out-resolve-mode", workchatOutputOptions.ResolveMode, "Workchat resolved alert mode: reply or empty to send new message")
 
 	flags.StringVar(&pubsubOutputOptions.Credentials, "pubsub-out-credentials", pubsubOutputOptions.Credentials, "PubSub output credentials")
 	flags.StringVar(&pubsubOutputOptions.ProjectID, "pubsub-out-project-id", pubsubOutputOptions.ProjectID, "PubSub output project ID")
 	flags.StringVar(&pubsubOutputOptions.TopicSelector, "pubsub-out-topic-selector", pubsubOutputOptions.TopicSelector, "PubSub output topic selector")
 	flags.StringVar(&pubsubOutputOptions.Message, "pubsub-out-message", pubsubOutputOptions.Message, "PubSub output message")
+	flags.StringVar(&pubsubOutputOptions.CloudEvents, "pubsub-out-cloudevents", pubsubOutputOptions.CloudEvents, "PubSub output CloudEvents mode: structured, binary")
 
 	flags.StringVar(&gitlabOutputOptions.BaseURL, "gitlab-out-base-url", gitlabOutputOptions.BaseURL, "Gitlab output base URL")
 	flags.StringVar(&gitlabOutputOptions.Token, "gitlab-out-token", gitlabOutputOptions.Token, "Gitlab output token")
@@ -669,7 +954,7 @@ func Execute() {
 	flags.StringVar(&grafanaOutputOptions.Message, "grafana-out-message", grafanaOutputOptions.Message, "Grafana message template")
 	flags.StringVar(&grafanaOutputOptions.AttributesSelector, "grafana-out-attributes-selector", grafanaOutputOptions.AttributesSelector, "Grafana attributes selector template")
 
-	interceptSyscall()
+	interceptSyscall(cancel)
 
 	rootCmd.AddCommand(&cobra.Command{
 		Use:   "version",
@@ -679,6 +964,44 @@ func Execute() {
 		},
 	})
 
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "replay [file]",
+		Short: "Replay dead letters from JSONL file to their outputs",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+
+			observability := common.NewObservability(logs, traces, metrics, events)
+
+			config, err := loadConfig()
+			if err != nil {
+				logs.Error(err)
+				os.Exit(1)
+			}
+
+			p, err := newPipeline(config, eventers{newrelicEventer, grafanaEventer, datadogEventer}, observability)
+			if err != nil {
+				logs.Error(err)
+				os.Exit(1)
+			}
+
+			letters, err := common.ReadDeadLetters(args[0])
+			if err != nil {
+				logs.Error(err)
+				os.Exit(1)
+			}
+
+			for _, dl := range letters {
+				if dl.Event == nil {
+					continue
+				}
+				dl.Event.SetLogger(logs)
+				p.outputs.SendForward(dl.Event, nil, fmt.Sprintf("^%s$", regexp.QuoteMeta(dl.Output)))
+			}
+			logs.Info("Replayed %d dead letter(s)", len(letters))
+			shutdown(&sync.WaitGroup{}, p.outputs)
+		},
+	})
+
 	if err := rootCmd.Execute(); err != nil {
 		logs.Error(err)
 		os.Exit(1)
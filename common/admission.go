@@ -0,0 +1,74 @@
+package common
+
+import (
+	"errors"
+	"sync"
+
+	sreCommon "github.com/devopsext/sre/common"
+)
+
+// Enqueuer is an output which tells if event is accepted, like a bounded pool or a persistent queue
+type Enqueuer interface {
+	Enqueue(event *Event) error
+}
+
+// Admission collects results of sending events of one request, so http input can answer by them
+type Admission struct {
+	mutex  sync.Mutex
+	events int
+	errs   []error
+}
+
+// requestSpan carries admission and ingestion to events, processors link events to the span of request
+type requestSpan struct {
+	sreCommon.TracerSpan
+	admission *Admission
+	ingestion *Ingestion
+}
+
+func (a *Admission) Add(err error) {
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.events++
+	if err != nil {
+		a.errs = append(a.errs, err)
+	}
+}
+
+// Events is a count of event sends to outputs
+func (a *Admission) Events() int {
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.events
+}
+
+func (a *Admission) Err() error {
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return errors.Join(a.errs...)
+}
+
+// SpanWithAdmission returns span which passes admission to events linked to it
+func SpanWithAdmission(span sreCommon.TracerSpan, admission *Admission) sreCommon.TracerSpan {
+
+	if s, ok := span.(*requestSpan); ok {
+		return &requestSpan{TracerSpan: s.TracerSpan, admission: admission, ingestion: s.ingestion}
+	}
+	return &requestSpan{TracerSpan: span, admission: admission}
+}
+
+func (e *Event) admission() *Admission {
+
+	if s, ok := e.span.(*requestSpan); ok {
+		return s.admission
+	}
+	return nil
+}
+
+func NewAdmission() *Admission {
+	return &Admission{}
+}
@@ -0,0 +1,84 @@
+package common
+
+import (
+	"strings"
+)
+
+const (
+	AlertSeverityCritical = "critical"
+	AlertSeverityWarning  = "warning"
+	AlertSeverityInfo     = "info"
+)
+
+type AlertLink struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// Alert is a normalized alert which alerting processors put next to original event data,
+// status is firing or resolved, severity is critical, warning, info or empty if it's unknown
+type Alert struct {
+	Fingerprint string            `json:"fingerprint"`
+	Status      string            `json:"status"`
+	Severity    string            `json:"severity,omitempty"`
+	Title       string            `json:"title"`
+	Description string            `json:"description,omitempty"`
+	Source      string            `json:"source"`
+	Entity      string            `json:"entity,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Links       []*AlertLink      `json:"links,omitempty"`
+}
+
+// AddLink skips empty urls
+func (a *Alert) AddLink(title, url string) {
+
+	if strings.TrimSpace(url) == "" {
+		return
+	}
+	a.Links = append(a.Links, &AlertLink{Title: title, URL: url})
+}
+
+// EventState links alert events by fingerprint
+func (a *Alert) EventState() *EventState {
+
+	if a == nil {
+		return nil
+	}
+	return NewEventState(a.Fingerprint, a.Status)
+}
+
+// AlertSeverity maps source specific severity or priority to a normalized one
+func AlertSeverity(severity string) string {
+
+	switch strings.ToLower(strings.TrimSpace(severity)) {
+	case "critical", "crit", "fatal", "emergency", "disaster", "high", "error", "page", "p1", "p2", "sev1", "sev2":
+		return AlertSeverityCritical
+	case "warning", "warn", "average", "medium", "major", "minor", "p3", "sev3":
+		return AlertSeverityWarning
+	case "info", "information", "informational", "low", "notice", "normal", "p4", "p5", "sev4", "sev5":
+		return AlertSeverityInfo
+	}
+	return ""
+}
+
+// AlertTags parses tags like "key:value, key2:value2", tags without values are kept with empty values
+func AlertTags(tags []string) map[string]string {
+
+	if len(tags) == 0 {
+		return nil
+	}
+	labels := make(map[string]string)
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		kv := strings.SplitN(t, ":", 2)
+		if len(kv) == 2 {
+			labels[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		} else {
+			labels[t] = ""
+		}
+	}
+	return labels
+}
@@ -0,0 +1,166 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/types"
+	"github.com/devopsext/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	CloudEventsModeStructured = "structured"
+	CloudEventsModeBinary     = "binary"
+	CloudEventsContentType    = cloudevents.ApplicationCloudEventsJSON
+	CloudEventsChannel        = "channel"
+)
+
+// CloudEvent converts event into CloudEvents envelope, payload replaces event data if it's set
+func (e *Event) CloudEvent(payload []byte) (*cloudevents.Event, error) {
+
+	ce := cloudevents.NewEvent(cloudevents.VersionV1)
+
+	id := e.ID
+	if utils.IsEmpty(id) {
+		id = uuid.New().String()
+	}
+	ce.SetID(id)
+
+	source := e.Source
+	if utils.IsEmpty(source) {
+		source = "/" + e.Channel
+	}
+	ce.SetSource(source)
+	ce.SetType(e.Type)
+
+	if !utils.IsEmpty(e.Subject) {
+		ce.SetSubject(e.Subject)
+	}
+
+	t := e.Time
+	if t.IsZero() {
+		t = time.Now().UTC()
+	}
+	ce.SetTime(t)
+
+	if !utils.IsEmpty(e.Channel) {
+		ce.SetExtension(CloudEventsChannel, e.Channel)
+	}
+
+	contentType := e.ContentType
+	var data interface{} = e.Data
+	if payload != nil {
+		data = payload
+		if !json.Valid(payload) {
+			contentType = "text/plain"
+		}
+	}
+	if utils.IsEmpty(contentType) {
+		contentType = cloudevents.ApplicationJSON
+	}
+
+	if data != nil {
+		if err := ce.SetData(contentType, data); err != nil {
+			return nil, err
+		}
+	} else {
+		ce.SetDataContentType(contentType)
+	}
+
+	if err := ce.Validate(); err != nil {
+		return nil, err
+	}
+	return &ce, nil
+}
+
+// CloudEventEncode encodes event for structured or binary content mode, headers are prefixed with ce_ or ce- depending on transport
+func CloudEventEncode(ce *cloudevents.Event, mode, prefix string) ([]byte, map[string]string, error) {
+
+	headers := make(map[string]string)
+
+	switch mode {
+	case CloudEventsModeStructured:
+		b, err := json.Marshal(ce)
+		if err != nil {
+			return nil, nil, err
+		}
+		headers["content-type"] = CloudEventsContentType
+		return b, headers, nil
+	case CloudEventsModeBinary:
+		headers[prefix+"specversion"] = ce.SpecVersion()
+		headers[prefix+"id"] = ce.ID()
+		headers[prefix+"source"] = ce.Source()
+		headers[prefix+"type"] = ce.Type()
+		if !ce.Time().IsZero() {
+			headers[prefix+"time"] = types.FormatTime(ce.Time())
+		}
+		if !utils.IsEmpty(ce.Subject()) {
+			headers[prefix+"subject"] = ce.Subject()
+		}
+		if !utils.IsEmpty(ce.DataSchema()) {
+			headers[prefix+"dataschema"] = ce.DataSchema()
+		}
+		for k, v := range ce.Extensions() {
+			s, err := types.Format(v)
+			if err != nil {
+				return nil, nil, err
+			}
+			headers[prefix+k] = s
+		}
+		if !utils.IsEmpty(ce.DataContentType()) {
+			headers["content-type"] = ce.DataContentType()
+		}
+		return ce.Data(), headers, nil
+	}
+	return nil, nil, fmt.Errorf("cloudevents mode %s is not supported", mode)
+}
+
+func isJsonContentType(contentType string) bool {
+
+	mediaType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	return utils.IsEmpty(mediaType) || mediaType == cloudevents.ApplicationJSON || mediaType == "text/json" ||
+		strings.HasSuffix(mediaType, "+json")
+}
+
+// NewEventFromCloudEvent converts CloudEvents envelope into event, json data is decoded into object
+func NewEventFromCloudEvent(ce *cloudevents.Event) (*Event, error) {
+
+	if ce == nil {
+		return nil, errors.New("cloudevent is not defined")
+	}
+
+	e := &Event{
+		ID:          ce.ID(),
+		Source:      ce.Source(),
+		Type:        ce.Type(),
+		Subject:     ce.Subject(),
+		Time:        ce.Time().UTC(),
+		ContentType: ce.DataContentType(),
+	}
+
+	if channel, err := types.ToString(ce.Extensions()[CloudEventsChannel]); err == nil {
+		e.Channel = channel
+	}
+
+	data := ce.Data()
+	if len(data) == 0 {
+		return e, nil
+	}
+
+	if !isJsonContentType(e.ContentType) {
+		e.Data = string(data)
+		return e, nil
+	}
+
+	var object interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	e.Data = object
+	return e, nil
+}
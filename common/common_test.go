@@ -0,0 +1,57 @@
+package common
+
+import (
+	"context"
+	"sync"
+
+	sre "github.com/devopsext/sre/common"
+)
+
+func testObservability() *Observability {
+	return NewObservability(sre.NewLogs(), sre.NewTraces(), sre.NewMetrics(), sre.NewEvents())
+}
+
+// testOutput records delivered events, errs are returned by deliveries one by one
+type testOutput struct {
+	name   string
+	mutex  sync.Mutex
+	events []*Event
+	errs   []error
+	calls  int
+}
+
+func (o *testOutput) Name() string {
+	return o.name
+}
+
+func (o *testOutput) Send(event *Event) {
+	o.Deliver(event)
+}
+
+func (o *testOutput) Deliver(event *Event) error {
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.calls++
+	if len(o.errs) > 0 {
+		err := o.errs[0]
+		o.errs = o.errs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	o.events = append(o.events, event)
+	return nil
+}
+
+func (o *testOutput) Stop(_ context.Context) error {
+	return nil
+}
+
+func (o *testOutput) count() (int, int) {
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return len(o.events), o.calls
+}
@@ -0,0 +1,163 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ConfigItem describes an input, a processor or an output, options are decoded into the type specific options
+type ConfigItem struct {
+	Name    string          `json:"name"`
+	Type    string          `json:"type"`
+	Options json.RawMessage `json:"options,omitempty"`
+}
+
+// ConfigOutput keeps the default output name if name is empty
+type ConfigOutput struct {
+	ConfigItem
+	Retry json.RawMessage `json:"retry,omitempty"`
+	Pool  json.RawMessage `json:"pool,omitempty"`
+}
+
+type Config struct {
+	Inputs     []*ConfigItem   `json:"inputs,omitempty"`
+	Processors []*ConfigItem   `json:"processors,omitempty"`
+	Outputs    []*ConfigOutput `json:"outputs,omitempty"`
+	Dedup      json.RawMessage `json:"dedup,omitempty"`
+	Filters    []*Filter       `json:"filters,omitempty"`
+	Routes     []*Route        `json:"routes,omitempty"`
+}
+
+func decodeRaw(raw json.RawMessage, v interface{}) error {
+
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// Decode puts options over v, so v should contain defaults
+func (ci *ConfigItem) Decode(v interface{}) error {
+
+	if err := decodeRaw(ci.Options, v); err != nil {
+		return fmt.Errorf("%s %s options: %w", ci.Type, ci.Name, err)
+	}
+	return nil
+}
+
+func (co *ConfigOutput) DecodeRetry(v interface{}) error {
+
+	if err := decodeRaw(co.Retry, v); err != nil {
+		return fmt.Errorf("%s %s retry: %w", co.Type, co.Name, err)
+	}
+	return nil
+}
+
+func (co *ConfigOutput) DecodePool(v interface{}) error {
+
+	if err := decodeRaw(co.Pool, v); err != nil {
+		return fmt.Errorf("%s %s pool: %w", co.Type, co.Name, err)
+	}
+	return nil
+}
+
+func (c *Config) DecodeDedup(v interface{}) error {
+
+	if err := decodeRaw(c.Dedup, v); err != nil {
+		return fmt.Errorf("dedup: %w", err)
+	}
+	return nil
+}
+
+func validateConfigItems(kind string, items []*ConfigItem, typeAsName bool) error {
+
+	names := make(map[string]bool)
+	for _, item := range items {
+		if item == nil || strings.TrimSpace(item.Type) == "" {
+			return fmt.Errorf("%s type is not defined", kind)
+		}
+		if item.Name == "" && typeAsName {
+			item.Name = item.Type
+		}
+		name := item.Name
+		if name == "" {
+			name = strings.ToLower(item.Type)
+		}
+		if names[name] {
+			return fmt.Errorf("%s %s is duplicated", kind, name)
+		}
+		names[name] = true
+	}
+	return nil
+}
+
+func (c *Config) Validate() error {
+
+	if err := validateConfigItems("input", c.Inputs, true); err != nil {
+		return err
+	}
+	if err := validateConfigItems("processor", c.Processors, true); err != nil {
+		return err
+	}
+
+	var outputs []*ConfigItem
+	for _, o := range c.Outputs {
+		if o == nil {
+			return fmt.Errorf("output is not defined")
+		}
+		outputs = append(outputs, &o.ConfigItem)
+	}
+	if err := validateConfigItems("output", outputs, false); err != nil {
+		return err
+	}
+
+	for _, f := range c.Filters {
+		if f == nil {
+			return fmt.Errorf("filter is not defined")
+		}
+		if err := f.Compile(); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range c.Routes {
+		if r == nil {
+			return fmt.Errorf("route is not defined")
+		}
+		if err := r.Compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseConfig parses YAML or JSON config
+func ParseConfig(data []byte) (*Config, error) {
+
+	b, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func LoadConfig(path string) (*Config, error) {
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(b)
+}
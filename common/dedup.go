@@ -0,0 +1,289 @@
+package common
+
+import (
+	"bufio"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	sreCommon "github.com/devopsext/sre/common"
+	toolsRender "github.com/devopsext/tools/render"
+)
+
+type DedupOptions struct {
+	TTL      int
+	Size     int
+	Template string
+	File     string
+}
+
+type dedupEntry struct {
+	Key     string    `json:"key"`
+	Status  string    `json:"status,omitempty"`
+	Expires time.Time `json:"expires"`
+}
+
+// Dedup skips events with the same fingerprint and status within TTL, so alert which is resolved and fired again
+// is not skipped. Fingerprints are kept in LRU and optionally in file
+type Dedup struct {
+	options  DedupOptions
+	template *Template
+	mutex    sync.Mutex
+	items    map[string]*list.Element
+	order    *list.List
+	file     *os.File
+	written  int
+	logger   sreCommon.Logger
+	meter    sreCommon.Meter
+}
+
+// Fingerprint renders template over event, by default it's type, channel and alert fingerprint, event key or data hash
+func (d *Dedup) Fingerprint(e *Event) (string, error) {
+
+	var fp string
+	if d.template != nil {
+		b, err := d.template.RenderObject(e)
+		if err != nil {
+			return "", err
+		}
+		fp = strings.TrimSpace(string(b))
+	}
+
+	if fp == "" {
+		key := e.Key
+		// key of alert has status, fingerprint is the same for firing and resolved
+		if e.Alert != nil && e.Alert.Fingerprint != "" {
+			key = e.Alert.Fingerprint
+		}
+		if key == "" {
+			b, err := json.Marshal(e.Data)
+			if err != nil {
+				return "", err
+			}
+			key = string(b)
+		}
+		fp = fmt.Sprintf("%s|%s|%s", e.Type, e.Channel, key)
+	}
+
+	sum := sha256.Sum256([]byte(fp))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (d *Dedup) remove(el *list.Element) {
+
+	d.order.Remove(el)
+	delete(d.items, el.Value.(*dedupEntry).Key)
+}
+
+func (d *Dedup) add(entry *dedupEntry) {
+
+	d.items[entry.Key] = d.order.PushFront(entry)
+	for d.options.Size > 0 && d.order.Len() > d.options.Size {
+		d.remove(d.order.Back())
+	}
+}
+
+func (d *Dedup) persist(entry *dedupEntry) {
+
+	if d.file == nil {
+		return
+	}
+
+	// entry is in memory already, so it's written by compaction
+	if d.options.Size > 0 && d.written >= d.options.Size*2 {
+		if err := d.compact(); err != nil {
+			d.logger.Error(err)
+		}
+		return
+	}
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		d.logger.Error(err)
+		return
+	}
+	if _, err := d.file.Write(append(b, '\n')); err != nil {
+		d.logger.Error(err)
+		return
+	}
+	d.written++
+}
+
+// compact rewrites file with entries which are in memory
+func (d *Dedup) compact() error {
+
+	tmp := d.options.File + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	written := 0
+	now := time.Now()
+	for el := d.order.Back(); el != nil; el = el.Prev() {
+		entry := el.Value.(*dedupEntry)
+		if entry.Expires.Before(now) {
+			continue
+		}
+		b, err := json.Marshal(entry)
+		if err != nil {
+			continue
+		}
+		w.Write(append(b, '\n'))
+		written++
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, d.options.File); err != nil {
+		return err
+	}
+
+	if d.file != nil {
+		d.file.Close()
+	}
+	d.file, err = os.OpenFile(d.options.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	d.written = written
+	return nil
+}
+
+func (d *Dedup) load() error {
+
+	f, err := os.Open(d.options.File)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	now := time.Now()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry dedupEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Expires.Before(now) {
+			continue
+		}
+		if el, ok := d.items[entry.Key]; ok {
+			d.remove(el)
+		}
+		d.add(&entry)
+	}
+	return scanner.Err()
+}
+
+func dedupStatus(e *Event) string {
+
+	if e.Alert != nil {
+		return e.Alert.Status
+	}
+	return ""
+}
+
+// Duplicate returns true if event with the same fingerprint and status is seen within TTL, TTL isn't extended by duplicates.
+// Event with another status replaces the last one, so firing, resolved and firing again are all passed
+func (d *Dedup) Duplicate(e *Event) bool {
+
+	fp, err := d.Fingerprint(e)
+	if err != nil {
+		d.logger.Error(err)
+		return false
+	}
+
+	now := time.Now()
+	status := dedupStatus(e)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if el, ok := d.items[fp]; ok {
+		entry := el.Value.(*dedupEntry)
+		if entry.Expires.After(now) && entry.Status == status {
+			d.order.MoveToFront(el)
+
+			labels := make(map[string]string)
+			labels["type"] = e.Type
+			labels["channel"] = e.Channel
+
+			duplicates := d.meter.Counter("dedup", "duplicates", "Count of duplicated events", labels)
+			duplicates.Inc()
+
+			d.logger.Debug("%s event on %s is duplicated", e.Type, e.Channel)
+			return true
+		}
+		d.remove(el)
+	}
+
+	entry := &dedupEntry{Key: fp, Status: status, Expires: now.Add(time.Duration(d.options.TTL) * time.Second)}
+	d.add(entry)
+	d.persist(entry)
+	return false
+}
+
+func (d *Dedup) Close() error {
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.file == nil {
+		return nil
+	}
+	err := d.file.Close()
+	d.file = nil
+	return err
+}
+
+// NewDedup returns nil if TTL is not defined
+func NewDedup(options DedupOptions, templateOptions toolsRender.TemplateOptions, observability *Observability) (*Dedup, error) {
+
+	if options.TTL <= 0 {
+		return nil, nil
+	}
+
+	d := &Dedup{
+		options: options,
+		items:   make(map[string]*list.Element),
+		order:   list.New(),
+		logger:  observability.Logs(),
+		meter:   observability.Metrics(),
+	}
+
+	if options.Template != "" {
+		templateOptions.Name = "dedup-fingerprint"
+		templateOptions.Content = options.Template
+		t, err := NewTemplate(templateOptions, observability)
+		if err != nil {
+			return nil, err
+		}
+		d.template = t
+	}
+
+	if options.File != "" {
+		if err := os.MkdirAll(filepath.Dir(options.File), 0755); err != nil {
+			return nil, err
+		}
+		if err := d.load(); err != nil {
+			return nil, err
+		}
+		if err := d.compact(); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
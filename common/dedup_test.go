@@ -0,0 +1,132 @@
+package common
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	toolsRender "github.com/devopsext/tools/render"
+)
+
+func testDedup(t *testing.T, options DedupOptions) *Dedup {
+
+	t.Helper()
+	d, err := NewDedup(options, toolsRender.TemplateOptions{}, testObservability())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+func alertEvent(fingerprint, status string) *Event {
+	return &Event{
+		Type:    "AlertmanagerEvent",
+		Channel: "alerts",
+		Key:     fingerprint + ":" + status,
+		Alert:   &Alert{Fingerprint: fingerprint, Status: status},
+	}
+}
+
+func TestDedupDuplicate(t *testing.T) {
+
+	type step struct {
+		event     *Event
+		duplicate bool
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"same key", []step{
+			{&Event{Type: "T", Channel: "c", Key: "1"}, false},
+			{&Event{Type: "T", Channel: "c", Key: "1"}, true},
+			{&Event{Type: "T", Channel: "other", Key: "1"}, false},
+			{&Event{Type: "T", Channel: "c", Key: "2"}, false},
+		}},
+		{"same data", []step{
+			{&Event{Type: "T", Data: map[string]string{"a": "1"}}, false},
+			{&Event{Type: "T", Data: map[string]string{"a": "1"}}, true},
+			{&Event{Type: "T", Data: map[string]string{"a": "2"}}, false},
+		}},
+		{"repeated firing", []step{
+			{alertEvent("fp", StateFiring), false},
+			{alertEvent("fp", StateFiring), true},
+		}},
+		{"fired again after resolve", []step{
+			{alertEvent("fp", StateFiring), false},
+			{alertEvent("fp", StateResolved), false},
+			{alertEvent("fp", StateResolved), true},
+			{alertEvent("fp", StateFiring), false},
+			{alertEvent("fp", StateFiring), true},
+		}},
+		{"other alert", []step{
+			{alertEvent("fp1", StateFiring), false},
+			{alertEvent("fp2", StateFiring), false},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := testDedup(t, DedupOptions{TTL: 60})
+			for i, s := range tt.steps {
+				if got := d.Duplicate(s.event); got != s.duplicate {
+					t.Fatalf("step %d: Duplicate() = %v, want %v", i, got, s.duplicate)
+				}
+			}
+		})
+	}
+}
+
+func TestDedupExpiresAndEvicts(t *testing.T) {
+
+	d := testDedup(t, DedupOptions{TTL: 60, Size: 2})
+
+	e := &Event{Type: "T", Key: "1"}
+	d.Duplicate(e)
+	fp, _ := d.Fingerprint(e)
+	d.items[fp].Value.(*dedupEntry).Expires = time.Now().Add(-time.Second)
+	if d.Duplicate(e) {
+		t.Fatal("expired event is duplicated")
+	}
+
+	d.Duplicate(&Event{Type: "T", Key: "2"})
+	d.Duplicate(&Event{Type: "T", Key: "3"})
+	if d.order.Len() != 2 {
+		t.Fatalf("size %d, want 2", d.order.Len())
+	}
+	if d.Duplicate(&Event{Type: "T", Key: "1"}) {
+		t.Fatal("evicted event is duplicated")
+	}
+}
+
+func TestDedupFile(t *testing.T) {
+
+	options := DedupOptions{TTL: 60, File: filepath.Join(t.TempDir(), "dedup.jsonl")}
+
+	d := testDedup(t, options)
+	d.Duplicate(alertEvent("fp", StateFiring))
+	d.Duplicate(alertEvent("fp", StateResolved))
+	d.Close()
+
+	// status is kept, so the last resolved is duplicated and firing is not
+	d = testDedup(t, options)
+	if !d.Duplicate(alertEvent("fp", StateResolved)) {
+		t.Fatal("resolved event is not duplicated after reload")
+	}
+	if d.Duplicate(alertEvent("fp", StateFiring)) {
+		t.Fatal("firing event is duplicated after reload")
+	}
+}
+
+func TestDedupTemplate(t *testing.T) {
+
+	d := testDedup(t, DedupOptions{TTL: 60, Template: "{{ .Channel }}"})
+	if d.Duplicate(&Event{Channel: "c", Key: "1"}) {
+		t.Fatal("first event is duplicated")
+	}
+	if !d.Duplicate(&Event{Channel: "c", Key: "2"}) {
+		t.Fatal("event with the same template fingerprint is not duplicated")
+	}
+}
@@ -0,0 +1,340 @@
+package common
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/google/uuid"
+)
+
+const (
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed    = "failed"
+)
+
+// Delivery is a single delivery attempt of event to output, message IDs are remote IDs like Slack ts or Kafka offset
+type Delivery struct {
+	Time       time.Time `json:"time"`
+	EventID    string    `json:"eventId"`
+	Output     string    `json:"output"`
+	Status     string    `json:"status"`
+	Latency    float64   `json:"latency"`
+	MessageIDs []string  `json:"messageIds,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type DeliveryOptions struct {
+	Size int
+}
+
+// Deliveries keeps delivery log of last events, the oldest event is evicted when size is reached
+type Deliveries struct {
+	options DeliveryOptions
+	mutex   sync.RWMutex
+	events  map[string][]*Delivery
+	order   []string
+	next    int
+}
+
+// DeliveryOutput records deliveries of the wrapped output and traces them as child spans of event
+type DeliveryOutput struct {
+	wg         *sync.WaitGroup
+	output     Output
+	deliveries *Deliveries
+	logger     sreCommon.Logger
+	meter      sreCommon.Meter
+	traces     *sreCommon.Traces
+	inflight   int64
+	gauge      sreCommon.Gauge
+}
+
+// eventDelivery is set on event copy which is delivered by output, so outputs can report remote message IDs
+type eventDelivery struct {
+	messageIDs []string
+}
+
+// eventProgress keeps channels which event is delivered to, it's shared by copies of event
+// so retries of a partly failed delivery skip channels which got it already
+type eventProgress struct {
+	mutex    sync.Mutex
+	channels map[string]bool
+	failures map[string]func()
+}
+
+var eventProgressMutex sync.Mutex
+
+// NewEventID makes time ordered UUID, ingestion time is kept in ID even if event goes through queue
+func NewEventID() string {
+	return newEventIDAt(time.Now())
+}
+
+// newEventIDAt makes time ordered UUID of time t
+func newEventIDAt(t time.Time) string {
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return uuid.New().String()
+	}
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(t.UnixMilli()))
+	copy(id[:6], ms[2:])
+	return id.String()
+}
+
+// EventIDTime returns time of ID which is made by NewEventID
+func EventIDTime(id string) (time.Time, bool) {
+
+	u, err := uuid.Parse(id)
+	if err != nil || u.Version() != 7 {
+		return time.Time{}, false
+	}
+	sec, nsec := u.Time().UnixTime()
+	return time.Unix(sec, nsec), true
+}
+
+// SetMessageID reports remote message ID of current delivery, it's ignored if delivery log is disabled
+func (e *Event) SetMessageID(id string) {
+
+	if e.delivery == nil || id == "" {
+		return
+	}
+	e.delivery.messageIDs = append(e.delivery.messageIDs, id)
+}
+
+func (e *Event) progressOf() *eventProgress {
+
+	eventProgressMutex.Lock()
+	defer eventProgressMutex.Unlock()
+
+	if e.progress == nil {
+		e.progress = &eventProgress{channels: make(map[string]bool), failures: make(map[string]func())}
+	}
+	return e.progress
+}
+
+// Delivered says whether event is delivered to channel of output by a previous attempt
+func (e *Event) Delivered(output, channel string) bool {
+
+	p := e.progressOf()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.channels[output+"/"+channel]
+}
+
+// SetDelivered marks channel of output as delivered, outputs with several channels call it per channel
+func (e *Event) SetDelivered(output, channel string) {
+
+	p := e.progressOf()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.channels[output+"/"+channel] = true
+	delete(p.failures, output+"/"+channel)
+}
+
+// OnFailure sets func which is called if channel of output is not delivered after the final attempt,
+// func of the latest attempt replaces previous ones, so outputs report failure once instead of on every attempt
+func (e *Event) OnFailure(output, channel string, f func()) {
+
+	p := e.progressOf()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.failures[output+"/"+channel] = f
+}
+
+// failed calls failure funcs of channels which are not delivered, it's called once delivery of event is given up
+func (e *Event) failed() {
+
+	if e == nil {
+		return
+	}
+	p := e.progressOf()
+	p.mutex.Lock()
+	failures := p.failures
+	p.failures = make(map[string]func())
+	p.mutex.Unlock()
+
+	for _, f := range failures {
+		f()
+	}
+}
+
+func (ds *Deliveries) Add(d *Delivery) {
+
+	if ds == nil || d == nil {
+		return
+	}
+
+	ds.mutex.Lock()
+	defer ds.mutex.Unlock()
+
+	if _, ok := ds.events[d.EventID]; !ok {
+		if len(ds.order) < ds.options.Size {
+			ds.order = append(ds.order, d.EventID)
+		} else {
+			delete(ds.events, ds.order[ds.next])
+			ds.order[ds.next] = d.EventID
+			ds.next = (ds.next + 1) % len(ds.order)
+		}
+	}
+	ds.events[d.EventID] = append(ds.events[d.EventID], d)
+}
+
+// Find returns deliveries of event
+func (ds *Deliveries) Find(eventID string) []*Delivery {
+
+	if ds == nil {
+		return nil
+	}
+
+	ds.mutex.RLock()
+	defer ds.mutex.RUnlock()
+
+	r := make([]*Delivery, len(ds.events[eventID]))
+	copy(r, ds.events[eventID])
+	return r
+}
+
+// Last returns deliveries of last events, the newest event goes first
+func (ds *Deliveries) Last(limit int) []*Delivery {
+
+	var r []*Delivery
+	if ds == nil {
+		return r
+	}
+
+	ds.mutex.RLock()
+	defer ds.mutex.RUnlock()
+
+	n := len(ds.order)
+	if limit <= 0 || limit > n {
+		limit = n
+	}
+	for i := 1; i <= limit; i++ {
+		id := ds.order[(ds.next-i+n)%n]
+		r = append(r, ds.events[id]...)
+	}
+	return r
+}
+
+func NewDeliveries(options DeliveryOptions) *Deliveries {
+
+	if options.Size <= 0 {
+		return nil
+	}
+	return &Deliveries{
+		options: options,
+		events:  make(map[string][]*Delivery),
+	}
+}
+
+func (d *DeliveryOutput) Name() string {
+	return d.output.Name()
+}
+
+func (d *DeliveryOutput) Send(event *Event) {
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		// there are no retries, so the only attempt is final
+		if err := d.Deliver(event); err != nil {
+			event.failed()
+		}
+	}()
+}
+
+func (d *DeliveryOutput) Deliver(event *Event) error {
+
+	if event == nil {
+		return d.output.Deliver(event)
+	}
+
+	// outputs fill message IDs of their own copy, so concurrent deliveries don't share them,
+	// progress is created before the copy to be shared by retries
+	event.progressOf()
+	e := *event
+	e.delivery = &eventDelivery{}
+
+	span := StartEventSpan(d.traces, event, fmt.Sprintf("output %s", d.Name()))
+	defer span.Finish()
+	span.SetTag("event_id", event.ID)
+
+	d.gauge.Set(float64(atomic.AddInt64(&d.inflight, 1)))
+	t := time.Now()
+	err := d.output.Deliver(&e)
+	latency := time.Since(t)
+	d.gauge.Set(float64(atomic.AddInt64(&d.inflight, -1)))
+
+	delivery := &Delivery{
+		Time:       t.UTC(),
+		EventID:    event.ID,
+		Output:     d.Name(),
+		Status:     DeliveryStatusDelivered,
+		Latency:    float64(latency.Microseconds()) / 1000,
+		MessageIDs: e.delivery.messageIDs,
+	}
+	if err != nil {
+		delivery.Status = DeliveryStatusFailed
+		delivery.Error = err.Error()
+		span.Error(err)
+	}
+	d.deliveries.Add(delivery)
+	d.observe(event, delivery.Status, latency)
+
+	d.logger.Debug("%s event %s %s in %s %v", d.Name(), event.ID, delivery.Status, latency, delivery.MessageIDs)
+	return err
+}
+
+// observe delivery duration, and latency since ingestion if event is delivered
+func (d *DeliveryOutput) observe(event *Event, status string, duration time.Duration) {
+
+	labels := make(map[string]string)
+	labels["output"] = d.Name()
+	labels["status"] = status
+
+	h := d.meter.Histogram("output", "delivery_duration", "Duration of output deliveries in seconds", labels, "output")
+	h.Observe(duration.Seconds())
+
+	if status != DeliveryStatusDelivered {
+		return
+	}
+	ingested, ok := EventIDTime(event.ID)
+	if !ok {
+		return
+	}
+	delete(labels, "status")
+	h = d.meter.Histogram("output", "delivery_latency", "Latency from event ingestion till delivery in seconds", labels, "output")
+	h.Observe(time.Since(ingested).Seconds())
+}
+
+func (d *DeliveryOutput) Stop(ctx context.Context) error {
+	return d.output.Stop(ctx)
+}
+
+// NewDeliveryOutput wraps output with delivery log and spans, deliveries could be nil if the log is disabled
+func NewDeliveryOutput(wg *sync.WaitGroup, output Output, deliveries *Deliveries, observability *Observability) Output {
+
+	if output == nil || reflect.ValueOf(output).IsNil() {
+		return output
+	}
+	labels := make(map[string]string)
+	labels["output"] = output.Name()
+
+	// gauge is kept because prometheus meter binds value to the first gauge instance
+	meter := observability.Metrics()
+	return &DeliveryOutput{
+		wg:         wg,
+		output:     output,
+		deliveries: deliveries,
+		logger:     observability.Logs(),
+		meter:      meter,
+		traces:     observability.Traces(),
+		gauge:      meter.Gauge("output", "inflight", "Count of output deliveries in progress", labels, "output"),
+	}
+}
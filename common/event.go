@@ -7,13 +7,27 @@ import (
 	sreCommon "github.com/devopsext/sre/common"
 )
 
+// Event Key is a natural key of event source like alert fingerprint, it's used to find duplicates
+// ID is set once event is sent to outputs unless it's kept from input, it's carried by forwarded events
+// ID, Source, Subject and ContentType are kept from CloudEvents envelope
 type Event struct {
-	Time    time.Time              `json:"time"`
-	Channel string                 `json:"channel"`
-	Type    string                 `json:"type"`
-	Data    interface{}            `json:"data"`
-	Via     map[string]interface{} `json:"via,omitempty"`
-	logger  sreCommon.Logger
+	ID          string                 `json:"id,omitempty"`
+	Source      string                 `json:"source,omitempty"`
+	Subject     string                 `json:"subject,omitempty"`
+	ContentType string                 `json:"datacontenttype,omitempty"`
+	Time        time.Time              `json:"time"`
+	Channel     string                 `json:"channel"`
+	Type        string                 `json:"type"`
+	Data        interface{}            `json:"data"`
+	Key         string                 `json:"key,omitempty"`
+	Alert       *Alert                 `json:"alert,omitempty"`
+	State       *EventState            `json:"state,omitempty"`
+	Via         map[string]interface{} `json:"via,omitempty"`
+	TraceID     string                 `json:"traceId,omitempty"`
+	logger      sreCommon.Logger
+	delivery    *eventDelivery
+	progress    *eventProgress
+	span        sreCommon.TracerSpan
 }
 
 func (e *Event) JsonBytes() ([]byte, error) {
@@ -0,0 +1,399 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/buger/jsonparser"
+)
+
+// Expression is a jq-like condition over event json, for example:
+// .type == "KubeEvent" and (.data.type == "Normal" or .data.reason == "Pulled") and .data.message =~ "^Successfully"
+type Expression struct {
+	text string
+	root exprNode
+}
+
+type exprValue struct {
+	exists bool
+	typ    jsonparser.ValueType
+	raw    string
+}
+
+type exprNode interface {
+	eval(data []byte) exprValue
+}
+
+type exprPath struct {
+	keys []string
+}
+
+type exprLiteral struct {
+	value exprValue
+}
+
+type exprNot struct {
+	node exprNode
+}
+
+type exprLogical struct {
+	and         bool
+	left, right exprNode
+}
+
+type exprCompare struct {
+	op          string
+	left, right exprNode
+	regex       *regexp.Regexp
+}
+
+var exprTrue = exprValue{exists: true, typ: jsonparser.Boolean, raw: "true"}
+var exprFalse = exprValue{exists: true, typ: jsonparser.Boolean, raw: "false"}
+
+func exprBool(b bool) exprValue {
+	if b {
+		return exprTrue
+	}
+	return exprFalse
+}
+
+func (v exprValue) truthy() bool {
+
+	if !v.exists {
+		return false
+	}
+	switch v.typ {
+	case jsonparser.Null:
+		return false
+	case jsonparser.Boolean:
+		return v.raw == "true"
+	case jsonparser.String:
+		return v.raw != ""
+	}
+	return true
+}
+
+// null is true for json null and missing path, like in jq
+func (v exprValue) null() bool {
+	return !v.exists || v.typ == jsonparser.Null
+}
+
+func (v exprValue) number() (float64, bool) {
+
+	if !v.exists || (v.typ != jsonparser.Number && v.typ != jsonparser.String) {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(v.raw, 64)
+	return f, err == nil
+}
+
+func (p *exprPath) eval(data []byte) exprValue {
+
+	if len(p.keys) == 0 {
+		return exprValue{exists: true, typ: jsonparser.Object, raw: string(data)}
+	}
+	value, typ, _, err := jsonparser.Get(data, p.keys...)
+	if err != nil || typ == jsonparser.NotExist {
+		return exprValue{}
+	}
+	raw := string(value)
+	if typ == jsonparser.String {
+		if s, err := jsonparser.ParseString(value); err == nil {
+			raw = s
+		}
+	}
+	return exprValue{exists: true, typ: typ, raw: raw}
+}
+
+func (l *exprLiteral) eval(_ []byte) exprValue {
+	return l.value
+}
+
+func (n *exprNot) eval(data []byte) exprValue {
+	return exprBool(!n.node.eval(data).truthy())
+}
+
+func (l *exprLogical) eval(data []byte) exprValue {
+
+	left := l.left.eval(data).truthy()
+	if l.and && !left {
+		return exprFalse
+	}
+	if !l.and && left {
+		return exprTrue
+	}
+	return exprBool(l.right.eval(data).truthy())
+}
+
+func (c *exprCompare) eval(data []byte) exprValue {
+
+	left := c.left.eval(data)
+	right := c.right.eval(data)
+
+	switch c.op {
+	case "=~":
+		return exprBool(left.exists && c.regex.MatchString(left.raw))
+	case "!~":
+		return exprBool(!left.exists || !c.regex.MatchString(left.raw))
+	}
+
+	// null is equal to null only and isn't ordered
+	if left.null() || right.null() {
+		switch c.op {
+		case "==":
+			return exprBool(left.null() && right.null())
+		case "!=":
+			return exprBool(!left.null() || !right.null())
+		}
+		return exprFalse
+	}
+
+	// numeric strings are compared with numbers as numbers, other values of different types are not equal
+	var cmp int
+	ln, lok := left.number()
+	rn, rok := right.number()
+	switch {
+	case lok && rok:
+		switch {
+		case ln < rn:
+			cmp = -1
+		case ln > rn:
+			cmp = 1
+		}
+	case left.typ != right.typ:
+		return exprBool(c.op == "!=")
+	default:
+		cmp = strings.Compare(left.raw, right.raw)
+	}
+
+	switch c.op {
+	case "==":
+		return exprBool(cmp == 0)
+	case "!=":
+		return exprBool(cmp != 0)
+	case "<":
+		return exprBool(cmp < 0)
+	case "<=":
+		return exprBool(cmp <= 0)
+	case ">":
+		return exprBool(cmp > 0)
+	case ">=":
+		return exprBool(cmp >= 0)
+	}
+	return exprFalse
+}
+
+type exprParser struct {
+	tokens []string
+	pos    int
+}
+
+func exprTokens(text string) ([]string, error) {
+
+	var tokens []string
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			j := i + 1
+			for ; j < len(runes) && runes[j] != '"'; j++ {
+				if runes[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("string is not closed at %d", i)
+			}
+			tokens = append(tokens, string(runes[i:j+1]))
+			i = j + 1
+		case r == '(' || r == ')':
+			tokens = append(tokens, string(r))
+			i++
+		case strings.ContainsRune("=!<>~", r):
+			j := i + 1
+			for j < len(runes) && strings.ContainsRune("=~", runes[j]) {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		default:
+			j := i
+			for j < len(runes) && !unicode.IsSpace(runes[j]) && !strings.ContainsRune("()\"=!<>~", runes[j]) {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+func (p *exprParser) peek() string {
+
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) next() string {
+
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *exprParser) or() (exprNode, error) {
+
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "or" || p.peek() == "||" {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &exprLogical{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) and() (exprNode, error) {
+
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "and" || p.peek() == "&&" {
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &exprLogical{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) unary() (exprNode, error) {
+
+	if p.peek() == "not" || p.peek() == "!" {
+		p.next()
+		node, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &exprNot{node: node}, nil
+	}
+	return p.compare()
+}
+
+func (p *exprParser) compare() (exprNode, error) {
+
+	if p.peek() == "(" {
+		p.next()
+		node, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("closing parenthesis is expected")
+		}
+		return node, nil
+	}
+
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.peek()
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "=~", "!~":
+	default:
+		return left, nil
+	}
+	p.next()
+
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &exprCompare{op: op, left: left, right: right}
+	if op == "=~" || op == "!~" {
+		l, ok := right.(*exprLiteral)
+		if !ok || l.value.typ != jsonparser.String {
+			return nil, fmt.Errorf("%s expects string pattern", op)
+		}
+		if c.regex, err = regexp.Compile(l.value.raw); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (p *exprParser) operand() (exprNode, error) {
+
+	t := p.next()
+	switch {
+	case t == "":
+		return nil, fmt.Errorf("operand is expected")
+	case strings.HasPrefix(t, "\""):
+		s, err := strconv.Unquote(t)
+		if err != nil {
+			return nil, err
+		}
+		return &exprLiteral{value: exprValue{exists: true, typ: jsonparser.String, raw: s}}, nil
+	case strings.HasPrefix(t, "."):
+		path := strings.Trim(t, ".")
+		if path == "" {
+			return &exprPath{}, nil
+		}
+		path = strings.ReplaceAll(strings.ReplaceAll(path, "[", "."), "]", "")
+		return &exprPath{keys: DataPath(path)}, nil
+	case t == "true" || t == "false":
+		return &exprLiteral{value: exprValue{exists: true, typ: jsonparser.Boolean, raw: t}}, nil
+	case t == "null":
+		return &exprLiteral{value: exprValue{exists: true, typ: jsonparser.Null, raw: t}}, nil
+	}
+	if _, err := strconv.ParseFloat(t, 64); err != nil {
+		return nil, fmt.Errorf("unexpected %s", t)
+	}
+	return &exprLiteral{value: exprValue{exists: true, typ: jsonparser.Number, raw: t}}, nil
+}
+
+func (e *Expression) String() string {
+	return e.text
+}
+
+// Match evaluates expression over json
+func (e *Expression) Match(data []byte) bool {
+	return e.root.eval(data).truthy()
+}
+
+func NewExpression(text string) (*Expression, error) {
+
+	tokens, err := exprTokens(text)
+	if err != nil {
+		return nil, fmt.Errorf("expression %s: %w", text, err)
+	}
+
+	p := &exprParser{tokens: tokens}
+	root, err := p.or()
+	if err != nil {
+		return nil, fmt.Errorf("expression %s: %w", text, err)
+	}
+	if p.pos != len(tokens) {
+		return nil, fmt.Errorf("expression %s: unexpected %s", text, p.peek())
+	}
+	return &Expression{text: text, root: root}, nil
+}
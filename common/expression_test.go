@@ -0,0 +1,113 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestExpressionParseErrors(t *testing.T) {
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"empty", ""},
+		{"string is not closed", `.type == "K8sEvent`},
+		{"parenthesis is not closed", `(.type == "K8sEvent"`},
+		{"operand is missing", `.type ==`},
+		{"logical operand is missing", `.type == "K8sEvent" and`},
+		{"unexpected token", `.type == "K8sEvent" "Alert"`},
+		{"unexpected operand", `.type == K8sEvent`},
+		{"pattern is not string", `.data.message =~ 1`},
+		{"pattern is path", `.data.message =~ .type`},
+		{"bad pattern", `.data.message =~ "("`},
+		{"extra parenthesis", `.type == "K8sEvent")`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewExpression(tt.text); err == nil {
+				t.Fatalf("NewExpression(%q) is parsed, want error", tt.text)
+			}
+		})
+	}
+}
+
+func TestExpressionMatch(t *testing.T) {
+
+	data := []byte(`{
+		"type": "K8sEvent",
+		"data": {
+			"reason": "Pulled",
+			"message": "Pulled image nginx",
+			"severity": 3,
+			"code": "500",
+			"empty": "",
+			"enabled": true,
+			"nothing": null,
+			"items": [{"name": "first"}, {"name": "second"}]
+		}
+	}`)
+
+	tests := []struct {
+		name string
+		text string
+		want bool
+	}{
+		{"string equal", `.type == "K8sEvent"`, true},
+		{"string not equal", `.type != "K8sEvent"`, false},
+		{"number less", `.data.severity < 4`, true},
+		{"number greater or equal", `.data.severity >= 4`, false},
+		{"boolean", `.data.enabled == true`, true},
+		{"path is truthy", `.data.enabled`, true},
+		{"empty string is falsy", `.data.empty`, false},
+		{"array path", `.data.items[1].name == "second"`, true},
+		{"array index is missing", `.data.items[2].name == "third"`, false},
+		{"regex", `.data.message =~ "^Pulled"`, true},
+		{"regex not match", `.data.message !~ "^Pulled"`, false},
+		{"regex of missing path", `.data.missing =~ ".*"`, false},
+		{"not regex of missing path", `.data.missing !~ ".*"`, true},
+
+		{"missing is null", `.data.missing == null`, true},
+		{"missing is not not null", `.data.missing != null`, false},
+		{"null is null", `.data.nothing == null`, true},
+		{"null is missing", `.data.nothing == .data.missing`, true},
+		{"present is not null", `.data.reason == null`, false},
+		{"present is not null by not equal", `.data.reason != null`, true},
+		{"missing is not equal to value", `.data.missing == "Pulled"`, false},
+		{"missing is not equal by not equal", `.data.missing != "Pulled"`, true},
+		{"null is not ordered", `.data.missing < 4`, false},
+		{"missing is falsy", `.data.missing`, false},
+		{"null is falsy", `.data.nothing`, false},
+		{"null is not string null", `.data.nothing == "null"`, false},
+
+		{"numeric string equals number", `.data.code == 500`, true},
+		{"numeric string is compared as number", `.data.code > 99`, true},
+		{"string is not equal to number", `.data.reason == 1`, false},
+		{"string is not equal to number by not equal", `.data.reason != 1`, true},
+		{"string is not ordered with number", `.data.reason > 1`, false},
+		{"boolean is not equal to string", `.data.enabled == "true"`, false},
+		{"number is not equal to boolean", `.data.severity == true`, false},
+
+		{"and", `.type == "K8sEvent" and .data.severity < 4`, true},
+		{"and short", `.type == "K8sEvent" && .data.severity > 4`, false},
+		{"or", `.type == "Alert" or .data.reason == "Pulled"`, true},
+		{"or short", `.type == "Alert" || .data.reason == "Normal"`, false},
+		{"not", `not .type == "Alert"`, true},
+		{"not short", `! .data.enabled`, false},
+		{"and before or", `.type == "Alert" and .data.severity < 4 or .data.reason == "Pulled"`, true},
+		{"parenthesis", `.type == "Alert" and (.data.severity < 4 or .data.reason == "Pulled")`, false},
+		{"example", `.type == "K8sEvent" and (.data.reason == "Normal" or .data.severity < 4) and .data.message =~ "^Pulled"`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := NewExpression(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := e.Match(data); got != tt.want {
+				t.Fatalf("%s = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	sreCommon "github.com/devopsext/sre/common"
+)
+
+const (
+	FilterActionDrop = "drop"
+	FilterActionKeep = "keep"
+)
+
+// Filter drops events which match expression, or which don't match it if action is keep,
+// channel and type limit filter to input paths and event types
+type Filter struct {
+	Name       string `json:"name,omitempty"`
+	Channel    string `json:"channel,omitempty"`
+	Type       string `json:"type,omitempty"`
+	Expression string `json:"expression"`
+	Action     string `json:"action,omitempty"`
+
+	channelRegex *regexp.Regexp
+	typeRegex    *regexp.Regexp
+	expression   *Expression
+}
+
+// Filters are applied before events are routed
+type Filters struct {
+	list   []*Filter
+	logger sreCommon.Logger
+	meter  sreCommon.Meter
+}
+
+func (f *Filter) Compile() error {
+
+	if f.Name == "" {
+		f.Name = f.Expression
+	}
+
+	switch strings.ToLower(f.Action) {
+	case "", FilterActionDrop:
+		f.Action = FilterActionDrop
+	case FilterActionKeep:
+		f.Action = FilterActionKeep
+	default:
+		return fmt.Errorf("filter %s action %s is not supported", f.Name, f.Action)
+	}
+
+	var err error
+	if f.Channel != "" {
+		if f.channelRegex, err = anchoredRegex(f.Channel); err != nil {
+			return fmt.Errorf("filter %s channel: %w", f.Name, err)
+		}
+	}
+	if f.Type != "" {
+		if f.typeRegex, err = anchoredRegex(f.Type); err != nil {
+			return fmt.Errorf("filter %s type: %w", f.Name, err)
+		}
+	}
+	if f.expression, err = NewExpression(f.Expression); err != nil {
+		return fmt.Errorf("filter %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+func (f *Filter) applies(e *Event) bool {
+
+	if f.channelRegex != nil && !f.channelRegex.MatchString(e.Channel) {
+		return false
+	}
+	if f.typeRegex != nil && !f.typeRegex.MatchString(e.Type) {
+		return false
+	}
+	return true
+}
+
+// Drops checks event json
+func (f *Filter) Drops(e *Event, data []byte) bool {
+
+	if !f.applies(e) {
+		return false
+	}
+	return f.expression.Match(data) == (f.Action == FilterActionDrop)
+}
+
+func (fs *Filters) Empty() bool {
+	return len(fs.list) == 0
+}
+
+// Drop returns true if any filter drops event
+func (fs *Filters) Drop(e *Event) bool {
+
+	if fs.Empty() {
+		return false
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		fs.logger.Error(err)
+		return false
+	}
+
+	for _, f := range fs.list {
+		if !f.Drops(e, data) {
+			continue
+		}
+
+		labels := make(map[string]string)
+		labels["filter"] = f.Name
+		labels["type"] = e.Type
+
+		dropped := fs.meter.Counter("filter", "dropped", "Count of events dropped by filter", labels)
+		dropped.Inc()
+
+		fs.logger.Debug("%s event on %s is dropped by filter %s", e.Type, e.Channel, f.Name)
+		return true
+	}
+	return false
+}
+
+func NewFilters(filters []*Filter, observability *Observability) (*Filters, error) {
+
+	for _, f := range filters {
+		if err := f.Compile(); err != nil {
+			return nil, err
+		}
+	}
+	return &Filters{
+		list:   filters,
+		logger: observability.Logs(),
+		meter:  observability.Metrics(),
+	}, nil
+}
@@ -0,0 +1,55 @@
+package common
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestPipelineSampleFilters(t *testing.T) {
+
+	config, err := LoadConfig("../pipeline.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filters, err := NewFilters(config.Filters, testObservability())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zabbix, err := os.ReadFile("../test/zabbix.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zabbixEvent := func(severity string) *Event {
+		var data map[string]interface{}
+		if err := json.Unmarshal(zabbix, &data); err != nil {
+			t.Fatal(err)
+		}
+		data["EventNSeverity"] = severity
+		return &Event{Channel: "zabbix", Type: "ZabbixEvent", Data: data}
+	}
+	kubeEvent := func(typ string) *Event {
+		return &Event{Channel: "kube", Type: "KubeEvent", Data: map[string]interface{}{"type": typ, "reason": "Pulled"}}
+	}
+
+	tests := []struct {
+		name  string
+		event *Event
+		drop  bool
+	}{
+		{"zabbix warning", zabbixEvent("2"), true},
+		{"zabbix high", zabbixEvent("4"), false},
+		{"zabbix disaster", zabbixEvent("5"), false},
+		{"kube normal", kubeEvent("Normal"), true},
+		{"kube warning", kubeEvent("Warning"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if drop := filters.Drop(tt.event); drop != tt.drop {
+				t.Fatalf("Drop() = %v, want %v", drop, tt.drop)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	sreCommon "github.com/devopsext/sre/common"
+)
+
+type ingestionContextKey struct{}
+
+// Ingestion is a request or message taken by input, its events get IDs of ingestion time
+type Ingestion struct {
+	mutex sync.Mutex
+	id    string
+	time  time.Time
+	ids   int
+}
+
+func (i *Ingestion) ID() string {
+	return i.id
+}
+
+func (i *Ingestion) Time() time.Time {
+	return i.time
+}
+
+// nextID returns ID of ingestion for the first event, other events of it get new IDs of the same time
+func (i *Ingestion) nextID() string {
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.ids++
+	if i.ids == 1 {
+		return i.id
+	}
+	return newEventIDAt(i.time)
+}
+
+func ContextWithIngestion(ctx context.Context, ingestion *Ingestion) context.Context {
+	return context.WithValue(ctx, ingestionContextKey{}, ingestion)
+}
+
+func IngestionFromContext(ctx context.Context) *Ingestion {
+
+	ingestion, _ := ctx.Value(ingestionContextKey{}).(*Ingestion)
+	return ingestion
+}
+
+// SpanWithIngestion returns span which assigns IDs of ingestion to events linked to it
+func SpanWithIngestion(span sreCommon.TracerSpan, ingestion *Ingestion) sreCommon.TracerSpan {
+
+	if s, ok := span.(*requestSpan); ok {
+		return &requestSpan{TracerSpan: s.TracerSpan, admission: s.admission, ingestion: ingestion}
+	}
+	return &requestSpan{TracerSpan: span, ingestion: ingestion}
+}
+
+func (e *Event) ingestion() *Ingestion {
+
+	if s, ok := e.span.(*requestSpan); ok {
+		return s.ingestion
+	}
+	return nil
+}
+
+// NewIngestion is called by input when request or message is taken, its ID is the ID of the first event
+func NewIngestion() *Ingestion {
+
+	now := time.Now()
+	return &Ingestion{id: newEventIDAt(now), time: now}
+}
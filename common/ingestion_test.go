@@ -0,0 +1,57 @@
+package common
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIngestionAssignsEventIDs(t *testing.T) {
+
+	ingestion := NewIngestion()
+	admission := NewAdmission()
+	span := SpanWithAdmission(SpanWithIngestion(StartSpan(nil, "", "test"), ingestion), admission)
+
+	first, second, forwarded := &Event{}, &Event{}, &Event{ID: "upstream"}
+	first.SetSpan(span)
+	second.SetSpan(span)
+	forwarded.SetSpan(span)
+
+	if first.ID != ingestion.ID() {
+		t.Fatalf("first event ID %s, want ingestion ID %s", first.ID, ingestion.ID())
+	}
+	if second.ID == "" || second.ID == first.ID {
+		t.Fatalf("second event ID %q, want new ID", second.ID)
+	}
+	if forwarded.ID != "upstream" {
+		t.Fatalf("forwarded event ID %s, want upstream", forwarded.ID)
+	}
+
+	// events of one ingestion share its time, so latency is measured from ingestion
+	for _, e := range []*Event{first, second} {
+		tm, ok := EventIDTime(e.ID)
+		if !ok || !tm.Equal(ingestion.Time().Truncate(1e6)) {
+			t.Fatalf("event ID time %v, want %v", tm, ingestion.Time())
+		}
+	}
+	if first.admission() != admission {
+		t.Fatal("admission is lost by span with ingestion")
+	}
+}
+
+func TestIngestionContext(t *testing.T) {
+
+	if IngestionFromContext(context.Background()) != nil {
+		t.Fatal("ingestion of empty context")
+	}
+	ingestion := NewIngestion()
+	if IngestionFromContext(ContextWithIngestion(context.Background(), ingestion)) != ingestion {
+		t.Fatal("ingestion is not kept by context")
+	}
+
+	// span without ingestion doesn't assign ID
+	e := &Event{}
+	e.SetSpan(StartSpan(nil, "", "test"))
+	if e.ID != "" {
+		t.Fatalf("event ID %s without ingestion", e.ID)
+	}
+}
@@ -1,9 +1,10 @@
 package common
 
 import (
+	"context"
 	"sync"
 )
 
 type Input interface {
-	Start(wg *sync.WaitGroup, outputs *Outputs)
+	Start(ctx context.Context, wg *sync.WaitGroup, outputs *Outputs)
 }
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"context"
 	"reflect"
 	"sync"
 )
@@ -17,12 +18,12 @@ func (is *Inputs) Add(i Input) {
 	is.list = append(is.list, i)
 }
 
-func (is *Inputs) Start(wg *sync.WaitGroup, ots *Outputs) {
+func (is *Inputs) Start(ctx context.Context, wg *sync.WaitGroup, ots *Outputs) {
 
 	for _, i := range is.list {
 
 		if i != nil {
-			(i).Start(wg, ots)
+			(i).Start(ctx, wg, ots)
 		}
 	}
 }
@@ -0,0 +1,18 @@
+package common
+
+import (
+	"time"
+
+	sreCommon "github.com/devopsext/sre/common"
+)
+
+// ObserveAPIDuration observes duration of remote api call of output in seconds
+func ObserveAPIDuration(meter sreCommon.Meter, group, output, api string, start time.Time) {
+
+	labels := make(map[string]string)
+	labels["output"] = output
+	labels["api"] = api
+
+	duration := meter.Histogram(group, "api_duration", "Duration of remote api calls in seconds", labels, "output")
+	duration.Observe(time.Since(start).Seconds())
+}
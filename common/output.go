@@ -1,6 +1,10 @@
 package common
 
+import "context"
+
 type Output interface {
 	Send(event *Event)
+	Deliver(event *Event) error
+	Stop(ctx context.Context) error
 	Name() string
 }
@@ -1,17 +1,42 @@
 package common
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"reflect"
 	"regexp"
+	"sync"
 
 	sreCommon "github.com/devopsext/sre/common"
 	"github.com/devopsext/utils"
 )
 
 type Outputs struct {
-	list   []Output
-	logger sreCommon.Logger
+	list          []Output
+	router        *outputRouter
+	dedup         *Dedup
+	state         *State
+	deliveries    *Deliveries
+	observability *Observability
+	logger        sreCommon.Logger
+}
+
+// outputRouter can be replaced while events are sent
+type outputRouter struct {
+	mutex   sync.RWMutex
+	router  *Router
+	filters *Filters
+}
+
+// OutputName is a name of output instance from pipeline config, it's used by metrics, routes, state and queues,
+// name of output type is used if instance has no name
+func OutputName(instance, name string) string {
+
+	if utils.IsEmpty(instance) {
+		return name
+	}
+	return instance
 }
 
 func (ots *Outputs) Add(o Output) {
@@ -22,7 +47,17 @@ func (ots *Outputs) Add(o Output) {
 	ots.list = append(ots.list, o)
 }
 
-func (ots *Outputs) send(e *Event, _ []Output, pattern string) {
+func (ots *Outputs) excluded(o Output, exclude []Output) bool {
+
+	for _, x := range exclude {
+		if x != nil && x.Name() == o.Name() {
+			return true
+		}
+	}
+	return false
+}
+
+func (ots *Outputs) send(e *Event, exclude []Output, pattern string) {
 
 	if e == nil {
 		if ots.logger != nil {
@@ -60,10 +95,10 @@ func (ots *Outputs) send(e *Event, _ []Output, pattern string) {
 				}
 				continue
 			}
-			if !matched {
+			if !matched || ots.excluded(o, exclude) {
 				continue
 			}
-			o.Send(e)
+			ots.sendTo(o, e)
 		} else {
 			if ots.logger != nil {
 				ots.logger.Warn("Output is not defined")
@@ -72,16 +107,135 @@ func (ots *Outputs) send(e *Event, _ []Output, pattern string) {
 	}
 }
 
+// SetRouting compiles filters and routes and replaces both at once, nothing is replaced if any of them is invalid,
+// events are sent to all outputs if there are no routes
+func (ots *Outputs) SetRouting(filters []*Filter, routes []*Route) error {
+
+	fs, err := NewFilters(filters, ots.observability)
+	if err != nil {
+		return err
+	}
+	router, err := NewRouter(routes, ots.observability)
+	if err != nil {
+		return err
+	}
+
+	ots.router.mutex.Lock()
+	defer ots.router.mutex.Unlock()
+	ots.router.filters = fs
+	ots.router.router = router
+	return nil
+}
+
+// SetDedup enables deduplication of events before they are routed
+func (ots *Outputs) SetDedup(d *Dedup) {
+	ots.dedup = d
+}
+
+// SetState enables alert state tracking, outputs use it to reply to firing messages
+func (ots *Outputs) SetState(s *State) {
+	ots.state = s
+}
+
+func (ots *Outputs) State() *State {
+	return ots.state
+}
+
+// SetDeliveries enables delivery log, it should be set before outputs are wrapped with NewDeliveryOutput
+func (ots *Outputs) SetDeliveries(d *Deliveries) {
+	ots.deliveries = d
+}
+
+func (ots *Outputs) Deliveries() *Deliveries {
+	return ots.deliveries
+}
+
+// sendTo sends event to output, result is added to admission of request if event has it
+func (ots *Outputs) sendTo(o Output, e *Event) {
+
+	admission := e.admission()
+	if admission == nil {
+		o.Send(e)
+		return
+	}
+	if q, ok := o.(Enqueuer); ok {
+		admission.Add(q.Enqueue(e))
+		return
+	}
+	o.Send(e)
+	admission.Add(nil)
+}
+
+func (ots *Outputs) getRouter() (*Router, *Filters) {
+
+	ots.router.mutex.RLock()
+	defer ots.router.mutex.RUnlock()
+	return ots.router.router, ots.router.filters
+}
+
+func (ots *Outputs) route(e *Event, router *Router) {
+
+	sent := make(map[Output]bool)
+	for _, r := range router.Match(e) {
+		for _, o := range ots.list {
+			if o == nil || sent[o] || !r.MatchOutput(o.Name()) {
+				continue
+			}
+			sent[o] = true
+			ots.sendTo(o, e)
+		}
+	}
+}
+
 func (ots *Outputs) Send(e *Event) {
-	ots.send(e, []Output{}, ".*")
+
+	router, filters := ots.getRouter()
+	if e != nil && filters != nil && filters.Drop(e) {
+		return
+	}
+	if e != nil && ots.dedup != nil && ots.dedup.Duplicate(e) {
+		return
+	}
+	ots.state.Update(e)
+
+	if router == nil || router.Empty() || e == nil {
+		ots.send(e, []Output{}, ".*")
+		return
+	}
+	ots.route(e, router)
 }
 
 func (ots *Outputs) SendForward(e *Event, exclude []Output, pattern string) {
 	ots.send(e, exclude, pattern)
 }
 
-func NewOutputs(logger sreCommon.Logger) Outputs {
+// Stop flushes and releases all outputs, sends which are still in progress are not awaited
+func (ots *Outputs) Stop(ctx context.Context) error {
+
+	var errs []error
+	for _, o := range ots.list {
+		if o == nil {
+			continue
+		}
+		if err := o.Stop(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if ots.dedup != nil {
+		if err := ots.dedup.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := ots.state.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func NewOutputs(observability *Observability) Outputs {
 	return Outputs{
-		logger: logger,
+		router:        &outputRouter{},
+		observability: observability,
+		logger:        observability.Logs(),
 	}
 }
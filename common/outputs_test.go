@@ -0,0 +1,130 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestOutputName(t *testing.T) {
+
+	if name := OutputName("", "Slack"); name != "Slack" {
+		t.Fatalf("name %s, want Slack", name)
+	}
+	if name := OutputName("slack-oncall", "Slack"); name != "slack-oncall" {
+		t.Fatalf("name %s, want slack-oncall", name)
+	}
+}
+
+func TestRouterMatch(t *testing.T) {
+
+	routes := []*Route{
+		{Name: "critical", Type: "AlertmanagerEvent", Labels: map[string]string{"severity": "critical"}, Outputs: []string{"slack-oncall"}, Continue: true},
+		{Name: "alerts", Type: "Alertmanager.*", Outputs: []string{"slack-alerts"}},
+		{Name: "k8s", Type: "K8sEvent", Channel: "prod-.*", Outputs: []string{"kafka"}},
+		{Name: "deploys", Data: map[string]string{"env": "prod|stage"}, Outputs: []string{"telegram"}},
+		{Name: "default", Default: true, Outputs: []string{"kafka"}},
+	}
+	router, err := NewRouter(routes, testObservability())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		event *Event
+		want  []string
+	}{
+		{"critical continues", &Event{Type: "AlertmanagerEvent", Data: map[string]interface{}{"labels": map[string]string{"severity": "critical"}}}, []string{"critical", "alerts"}},
+		{"warning", &Event{Type: "AlertmanagerEvent", Data: map[string]interface{}{"labels": map[string]string{"severity": "warning"}}}, []string{"alerts"}},
+		{"channel", &Event{Type: "K8sEvent", Channel: "prod-eu"}, []string{"k8s"}},
+		{"channel is anchored", &Event{Type: "K8sEvent", Channel: "preprod-eu"}, []string{"default"}},
+		{"data", &Event{Type: "Deploy", Data: map[string]string{"env": "stage"}}, []string{"deploys"}},
+		{"default", &Event{Type: "Deploy", Data: map[string]string{"env": "dev"}}, []string{"default"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range router.Match(tt.event) {
+				got = append(got, r.Name)
+			}
+			if !equalStrings(got, tt.want) {
+				t.Fatalf("routes %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteCompileErrors(t *testing.T) {
+
+	for _, r := range []*Route{
+		{Name: "no outputs"},
+		{Name: "type", Type: "(", Outputs: []string{"a"}},
+		{Name: "output", Outputs: []string{"["}},
+	} {
+		if err := r.Compile(); err == nil {
+			t.Fatalf("route %s is compiled", r.Name)
+		}
+	}
+}
+
+func TestOutputsRouteByInstanceName(t *testing.T) {
+
+	outs := NewOutputs(testObservability())
+	oncall := &testOutput{name: "slack-oncall"}
+	alerts := &testOutput{name: "slack-alerts"}
+	kafka := &testOutput{name: "Kafka"}
+	outs.Add(oncall)
+	outs.Add(alerts)
+	outs.Add(kafka)
+
+	if err := outs.SetRouting(nil, []*Route{
+		{Type: "AlertmanagerEvent", Outputs: []string{"slack-oncall", "Kafka"}},
+		{Default: true, Outputs: []string{"slack-.*"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	outs.Send(&Event{Type: "AlertmanagerEvent"})
+	outs.Send(&Event{Type: "K8sEvent"})
+
+	// default route sends to every output which matches, but only once
+	counts := map[string]int{}
+	for _, o := range []*testOutput{oncall, alerts, kafka} {
+		n, _ := o.count()
+		counts[o.name] = n
+	}
+	if counts["slack-oncall"] != 2 || counts["slack-alerts"] != 1 || counts["Kafka"] != 1 {
+		t.Fatalf("deliveries %v", counts)
+	}
+
+	// forwarded events skip excluded output by its instance name
+	outs.SendForward(&Event{Type: "T"}, []Output{&testOutput{name: "slack-oncall"}}, "slack-.*")
+	if n, _ := oncall.count(); n != 2 {
+		t.Fatalf("excluded output got %d events, want 2", n)
+	}
+	if n, _ := alerts.count(); n != 2 {
+		t.Fatalf("forwarded output got %d events, want 2", n)
+	}
+}
+
+func TestOutputsSetRoutingIsAtomic(t *testing.T) {
+
+	outs := NewOutputs(testObservability())
+	out := &testOutput{name: "out"}
+	outs.Add(out)
+
+	if err := outs.SetRouting(nil, []*Route{{Default: true, Outputs: []string{"out"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// valid filter with invalid route replaces nothing
+	err := outs.SetRouting([]*Filter{{Name: "drop-all", Expression: ".type != null"}}, []*Route{{Type: "(", Outputs: []string{"out"}}})
+	if err == nil {
+		t.Fatal("SetRouting() = nil, want error of route")
+	}
+
+	outs.Send(&Event{Type: "T"})
+	if n, _ := out.count(); n != 1 {
+		t.Fatalf("delivered %d events, want 1", n)
+	}
+}
@@ -0,0 +1,188 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	sreCommon "github.com/devopsext/sre/common"
+)
+
+const (
+	PoolOverflowBlock      = "block"
+	PoolOverflowDropOldest = "drop-oldest"
+	PoolOverflowReject     = "reject"
+)
+
+var ErrPoolFull = errors.New("output pool is full")
+var errPoolStopped = errors.New("output pool is stopped")
+
+type PoolOptions struct {
+	Workers   int
+	QueueSize int
+	Overflow  string
+}
+
+// PoolOutput delivers events by fixed number of workers from a bounded queue, overflow policy decides what to do when it's full
+type PoolOutput struct {
+	output  Output
+	options PoolOptions
+	queue   chan *Event
+	mutex   sync.RWMutex
+	stopped bool
+	workers sync.WaitGroup
+	logger  sreCommon.Logger
+	meter   sreCommon.Meter
+	size    sreCommon.Gauge
+}
+
+func (p *PoolOutput) Name() string {
+	return p.output.Name()
+}
+
+func (p *PoolOutput) labels() map[string]string {
+
+	labels := make(map[string]string)
+	labels["output"] = p.Name()
+	return labels
+}
+
+func (p *PoolOutput) count(name, description string) {
+
+	counter := p.meter.Counter("pool", name, description, p.labels(), "output", "pool")
+	counter.Inc()
+}
+
+// Enqueue puts event into the queue, ErrPoolFull is returned if it's full and overflow is reject
+func (p *PoolOutput) Enqueue(event *Event) error {
+
+	if event == nil {
+		return nil
+	}
+
+	// queue is not closed while events are put into it
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if p.stopped {
+		return errPoolStopped
+	}
+
+	switch p.options.Overflow {
+	case PoolOverflowReject:
+		select {
+		case p.queue <- event:
+		default:
+			p.count("rejected", "Count of events rejected by full output pool")
+			return ErrPoolFull
+		}
+	case PoolOverflowDropOldest:
+		for {
+			select {
+			case p.queue <- event:
+				p.size.Set(float64(len(p.queue)))
+				return nil
+			default:
+			}
+			select {
+			case old := <-p.queue:
+				p.count("dropped", "Count of events dropped by full output pool")
+				p.logger.Warn("%s pool is full, event %s is dropped", p.Name(), old.ID)
+			default:
+			}
+		}
+	default:
+		p.queue <- event
+	}
+	p.size.Set(float64(len(p.queue)))
+	return nil
+}
+
+func (p *PoolOutput) Send(event *Event) {
+
+	if err := p.Enqueue(event); err != nil {
+		p.logger.Error("%s event %s is not sent: %v", p.Name(), event.ID, err)
+	}
+}
+
+// Deliver delivers event at once bypassing workers, pool is not combined with persistent queue
+func (p *PoolOutput) Deliver(event *Event) error {
+	return p.output.Deliver(event)
+}
+
+func (p *PoolOutput) work() {
+
+	defer p.workers.Done()
+	for event := range p.queue {
+		p.size.Set(float64(len(p.queue)))
+		// retries are inside of pool, so error is final
+		if err := p.output.Deliver(event); err != nil {
+			event.failed()
+		}
+	}
+}
+
+// Stop closes the queue and waits for workers to deliver the rest of it
+func (p *PoolOutput) Stop(ctx context.Context) error {
+
+	p.mutex.Lock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.queue)
+	}
+	p.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		p.logger.Warn("%s pool delivery is not finished in time, %d events are left", p.Name(), len(p.queue))
+	}
+	return p.output.Stop(ctx)
+}
+
+// NewPoolOutput wraps output with worker pool, output is returned as is if workers are not defined
+func NewPoolOutput(output Output, options PoolOptions, observability *Observability) Output {
+
+	if output == nil || reflect.ValueOf(output).IsNil() || options.Workers <= 0 {
+		return output
+	}
+
+	logger := observability.Logs()
+	options.Overflow = strings.ToLower(options.Overflow)
+	switch options.Overflow {
+	case PoolOverflowBlock, PoolOverflowDropOldest, PoolOverflowReject:
+	default:
+		logger.Warn("%s pool overflow %s is not supported, block is used", output.Name(), options.Overflow)
+		options.Overflow = PoolOverflowBlock
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = options.Workers
+	}
+
+	labels := make(map[string]string)
+	labels["output"] = output.Name()
+
+	// gauge is kept because prometheus meter binds value to the first gauge instance
+	meter := observability.Metrics()
+	p := &PoolOutput{
+		output:  output,
+		options: options,
+		queue:   make(chan *Event, options.QueueSize),
+		logger:  logger,
+		meter:   meter,
+		size:    meter.Gauge("pool", "size", "Count of events waiting in output pool", labels, "output", "pool"),
+	}
+	for i := 0; i < options.Workers; i++ {
+		p.workers.Add(1)
+		go p.work()
+	}
+	return p
+}
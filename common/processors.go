@@ -5,7 +5,8 @@ import (
 )
 
 type Processors struct {
-	list []Processor
+	list  []Processor
+	names map[string]Processor
 }
 
 func (ps *Processors) Add(p Processor) {
@@ -16,6 +17,16 @@ func (ps *Processors) Add(p Processor) {
 	ps.list = append(ps.list, p)
 }
 
+// AddNamed adds processor which can be found by name as well as by event type
+func (ps *Processors) AddNamed(name string, p Processor) {
+
+	if reflect.ValueOf(p).IsNil() {
+		return
+	}
+	ps.list = append(ps.list, p)
+	ps.names[name] = p
+}
+
 func (ps *Processors) Find(eventType string) Processor {
 	for _, p := range ps.list {
 		if p.EventType() == eventType {
@@ -25,6 +36,15 @@ func (ps *Processors) Find(eventType string) Processor {
 	return nil
 }
 
+// FindByName finds named processor, processor type could be used as a name too
+func (ps *Processors) FindByName(name string) Processor {
+
+	if p, ok := ps.names[name]; ok {
+		return p
+	}
+	return ps.Find(AsEventType(name))
+}
+
 func (ps *Processors) FindHttpProcessor(eventType string) HttpProcessor {
 	for _, p := range ps.list {
 		hp, ok := p.(HttpProcessor)
@@ -36,5 +56,7 @@ func (ps *Processors) FindHttpProcessor(eventType string) HttpProcessor {
 }
 
 func NewProcessors() *Processors {
-	return &Processors{}
+	return &Processors{
+		names: make(map[string]Processor),
+	}
 }
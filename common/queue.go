@@ -0,0 +1,535 @@
+package common
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+)
+
+// QueueOptions MaxAttempts limits deliveries of event which fails with retryable errors, 0 retries until it's delivered
+type QueueOptions struct {
+	Dir         string
+	SegmentSize int
+	RetryDelay  int
+	MaxAttempts int
+}
+
+// Queue is a write-ahead log of events which are stored in segment files and read in order
+type Queue struct {
+	options      QueueOptions
+	dir          string
+	mutex        sync.Mutex
+	signal       chan struct{}
+	done         chan struct{}
+	writer       *os.File
+	writeSegment int64
+	writeOffset  int64
+	readSegment  int64
+	readOffset   int64
+}
+
+// QueueOutput persists events before sending them to the wrapped output, events which can't be delivered
+// are moved to dead letters, or dropped if there are none, so they don't block the queue
+type QueueOutput struct {
+	output      Output
+	queue       *Queue
+	options     QueueOptions
+	deadLetters *DeadLetters
+	logger      sreCommon.Logger
+	meter       sreCommon.Meter
+	stopped     chan struct{}
+}
+
+const (
+	queueSegmentExt = ".log"
+	queueAckFile    = "ack"
+)
+
+var queueSegmentRegex = regexp.MustCompile(`^(\d+)\.log$`)
+var errQueueClosed = errors.New("queue is closed")
+
+func (q *Queue) segmentPath(segment int64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", segment, queueSegmentExt))
+}
+
+func (q *Queue) segments() ([]int64, error) {
+
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var r []int64
+	for _, e := range entries {
+		m := queueSegmentRegex.FindStringSubmatch(e.Name())
+		if len(m) != 2 {
+			continue
+		}
+		s, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		r = append(r, s)
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i] < r[j] })
+	return r, nil
+}
+
+// validLength returns the length of a segment without a partially written tail
+func (q *Queue) validLength(path string) (int64, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var length int64
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return length, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		length += int64(len(line))
+	}
+}
+
+func (q *Queue) openWriter(segment int64) error {
+
+	path := q.segmentPath(segment)
+	length := int64(0)
+	if _, err := os.Stat(path); err == nil {
+		l, err := q.validLength(path)
+		if err != nil {
+			return err
+		}
+		length = l
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(length); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(length, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+
+	if q.writer != nil {
+		q.writer.Close()
+	}
+	q.writer = f
+	q.writeSegment = segment
+	q.writeOffset = length
+	return nil
+}
+
+func (q *Queue) loadAck() (int64, int64, error) {
+
+	b, err := os.ReadFile(filepath.Join(q.dir, queueAckFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+
+	arr := strings.Fields(string(b))
+	if len(arr) != 2 {
+		return 0, 0, fmt.Errorf("invalid queue ack %s", string(b))
+	}
+	segment, err := strconv.ParseInt(arr[0], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	offset, err := strconv.ParseInt(arr[1], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return segment, offset, nil
+}
+
+func (q *Queue) saveAck() error {
+
+	path := filepath.Join(q.dir, queueAckFile)
+	tmp := path + ".tmp"
+	data := []byte(fmt.Sprintf("%d %d", q.readSegment, q.readOffset))
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (q *Queue) open() error {
+
+	if err := os.MkdirAll(q.dir, 0700); err != nil {
+		return err
+	}
+
+	segments, err := q.segments()
+	if err != nil {
+		return err
+	}
+
+	last := int64(1)
+	if len(segments) > 0 {
+		last = segments[len(segments)-1]
+	}
+	if err := q.openWriter(last); err != nil {
+		return err
+	}
+
+	segment, offset, err := q.loadAck()
+	if err != nil {
+		return err
+	}
+
+	first := last
+	if len(segments) > 0 {
+		first = segments[0]
+	}
+	if segment < first || segment > last {
+		segment = first
+		offset = 0
+	}
+	q.readSegment = segment
+	q.readOffset = offset
+	return nil
+}
+
+func (q *Queue) notify() {
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+// Append stores event at the end of the queue
+func (q *Queue) Append(event *Event) error {
+
+	b, err := JsonMarshal(event)
+	if err != nil {
+		return err
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.writer == nil {
+		return errQueueClosed
+	}
+
+	if q.options.SegmentSize > 0 && q.writeOffset > 0 && q.writeOffset+int64(len(b)) > int64(q.options.SegmentSize) {
+		if err := q.openWriter(q.writeSegment + 1); err != nil {
+			return err
+		}
+	}
+
+	n, err := q.writer.Write(b)
+	if err != nil {
+		return err
+	}
+	if err := q.writer.Sync(); err != nil {
+		return err
+	}
+	q.writeOffset += int64(n)
+	q.notify()
+	return nil
+}
+
+// next reads the event at the current read position, the second value is a position right after it
+func (q *Queue) next() (*Event, int64, error) {
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for {
+		if q.readSegment == q.writeSegment && q.readOffset >= q.writeOffset {
+			return nil, 0, nil
+		}
+
+		path := q.segmentPath(q.readSegment)
+		f, err := os.Open(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, 0, err
+		}
+
+		var line []byte
+		if err == nil {
+			if _, err = f.Seek(q.readOffset, io.SeekStart); err == nil {
+				line, err = bufio.NewReader(f).ReadBytes('\n')
+			}
+			f.Close()
+		}
+
+		if err == nil {
+			offset := q.readOffset + int64(len(line))
+			var event Event
+			if err := json.Unmarshal(line, &event); err != nil {
+				// skip broken record
+				q.readOffset = offset
+				if err := q.saveAck(); err != nil {
+					return nil, 0, err
+				}
+				continue
+			}
+			return &event, offset, nil
+		}
+
+		if !errors.Is(err, io.EOF) && !os.IsNotExist(err) {
+			return nil, 0, err
+		}
+
+		// segment is fully read, move to the next one
+		if q.readSegment >= q.writeSegment {
+			return nil, 0, nil
+		}
+		os.Remove(path)
+		q.readSegment++
+		q.readOffset = 0
+		if err := q.saveAck(); err != nil {
+			return nil, 0, err
+		}
+	}
+}
+
+func (q *Queue) ack(offset int64) error {
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.readOffset = offset
+	return q.saveAck()
+}
+
+// Next blocks until an event is available and returns it with the function which acknowledges it
+func (q *Queue) Next() (*Event, func() error, error) {
+
+	for {
+		event, offset, err := q.next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if event != nil {
+			return event, func() error { return q.ack(offset) }, nil
+		}
+		select {
+		case <-q.signal:
+		case <-q.done:
+			return nil, nil, errQueueClosed
+		}
+	}
+}
+
+func (q *Queue) Close() error {
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.writer == nil {
+		return nil
+	}
+	close(q.done)
+	err := q.writer.Close()
+	q.writer = nil
+	return err
+}
+
+func NewQueue(name string, options QueueOptions) (*Queue, error) {
+
+	q := &Queue{
+		options: options,
+		dir:     filepath.Join(options.Dir, strings.ToLower(name)),
+		signal:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	if err := q.open(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (qo *QueueOutput) Name() string {
+	return qo.output.Name()
+}
+
+func (qo *QueueOutput) labels() map[string]string {
+
+	labels := make(map[string]string)
+	labels["output"] = qo.Name()
+	return labels
+}
+
+// Deliver puts event into the queue, it is delivered to the output later
+func (qo *QueueOutput) Deliver(event *Event) error {
+
+	if event == nil {
+		return nil
+	}
+
+	requests := qo.meter.Counter("queue", "requests", "Count of all queue requests", qo.labels(), "output")
+	requests.Inc()
+
+	if err := qo.queue.Append(event); err != nil {
+		errors := qo.meter.Counter("queue", "errors", "Count of all queue errors", qo.labels(), "output")
+		errors.Inc()
+		return err
+	}
+	return nil
+}
+
+// Enqueue puts event into the queue, wrapped output decides if it's accepted when the queue fails
+func (qo *QueueOutput) Enqueue(event *Event) error {
+
+	err := qo.Deliver(event)
+	if err == nil {
+		return nil
+	}
+	qo.logger.Error("%s queue append failed, sending directly: %v", qo.Name(), err)
+	if e, ok := qo.output.(Enqueuer); ok {
+		return e.Enqueue(event)
+	}
+	qo.output.Send(event)
+	return nil
+}
+
+func (qo *QueueOutput) Send(event *Event) {
+	qo.Enqueue(event)
+}
+
+func (qo *QueueOutput) wait(delay time.Duration) bool {
+
+	select {
+	case <-time.After(delay):
+		return true
+	case <-qo.queue.done:
+		return false
+	}
+}
+
+// deliver repeats delivery while error is retryable and attempts are left, false is returned if queue is closed
+func (qo *QueueOutput) deliver(event *Event, delay time.Duration) bool {
+
+	attempt := 0
+	for {
+		attempt++
+		err := qo.output.Deliver(event)
+		if err == nil {
+			return true
+		}
+		if !IsRetryable(err) || (qo.options.MaxAttempts > 0 && attempt >= qo.options.MaxAttempts) {
+			qo.deadLetters.Put(qo, event, attempt, err)
+			return true
+		}
+		qo.logger.Debug("%s delivery failed, retry in %s: %v", qo.Name(), delay, err)
+		if !qo.wait(delay) {
+			return false
+		}
+	}
+}
+
+func (qo *QueueOutput) run() {
+
+	defer close(qo.stopped)
+
+	delay := time.Duration(qo.options.RetryDelay) * time.Second
+	if delay <= 0 {
+		delay = time.Second
+	}
+
+	for {
+		event, ack, err := qo.queue.Next()
+		if errors.Is(err, errQueueClosed) {
+			return
+		}
+		if err != nil {
+			qo.logger.Error("%s queue read failed: %v", qo.Name(), err)
+			if !qo.wait(delay) {
+				return
+			}
+			continue
+		}
+		event.SetLogger(qo.logger)
+
+		if !qo.deliver(event, delay) {
+			// event stays in the queue and is delivered after restart
+			return
+		}
+
+		if err := ack(); err != nil {
+			qo.logger.Error("%s queue ack failed: %v", qo.Name(), err)
+		}
+	}
+}
+
+// Stop closes the queue, waits for the current delivery and stops the wrapped output
+func (qo *QueueOutput) Stop(ctx context.Context) error {
+
+	if err := qo.queue.Close(); err != nil {
+		qo.logger.Error("%s queue close failed: %v", qo.Name(), err)
+	}
+
+	select {
+	case <-qo.stopped:
+	case <-ctx.Done():
+		qo.logger.Warn("%s queue delivery is not finished in time", qo.Name())
+	}
+	return qo.output.Stop(ctx)
+}
+
+// NewQueueOutput wraps output with a queue, output is returned as is if queue dir is not defined
+func NewQueueOutput(output Output, options QueueOptions, deadLetters *DeadLetters, observability *Observability) Output {
+
+	if output == nil || reflect.ValueOf(output).IsNil() {
+		return output
+	}
+
+	logger := observability.Logs()
+	if utils.IsEmpty(options.Dir) {
+		return output
+	}
+
+	queue, err := NewQueue(output.Name(), options)
+	if err != nil {
+		logger.Error("%s queue is not available: %v", output.Name(), err)
+		return output
+	}
+	if deadLetters == nil {
+		deadLetters = NewDeadLetters(RetryOptions{}, nil, observability)
+	}
+
+	qo := &QueueOutput{
+		output:      output,
+		queue:       queue,
+		options:     options,
+		deadLetters: deadLetters,
+		logger:      logger,
+		meter:       observability.Metrics(),
+		stopped:     make(chan struct{}),
+	}
+	go qo.run()
+	return qo
+}
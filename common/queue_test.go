@@ -0,0 +1,267 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, cond func() bool) {
+
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition is not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestQueueRecoversPartlyWrittenSegment(t *testing.T) {
+
+	options := QueueOptions{Dir: t.TempDir()}
+	q, err := NewQueue("test", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"1", "2"} {
+		if err := q.Append(&Event{ID: id, Type: "T"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := q.segmentPath(q.writeSegment)
+	q.Close()
+
+	// crash in the middle of a record
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"id":"3","ty`)
+	f.Close()
+
+	q, err = NewQueue("test", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	if err := q.Append(&Event{ID: "4", Type: "T"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		e, ack, err := q.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, e.ID)
+		if err := ack(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"1", "2", "4"}; !equalStrings(ids, want) {
+		t.Fatalf("ids %v, want %v", ids, want)
+	}
+}
+
+func TestQueueResumesFromAck(t *testing.T) {
+
+	options := QueueOptions{Dir: t.TempDir(), SegmentSize: 64}
+	q, err := NewQueue("test", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		if err := q.Append(&Event{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e, ack, err := q.Next()
+	if err != nil || e.ID != "1" {
+		t.Fatalf("first event %v: %v", e, err)
+	}
+	ack()
+	q.Close()
+
+	q, err = NewQueue("test", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	e, _, err = q.Next()
+	if err != nil || e.ID != "2" {
+		t.Fatalf("event after restart %v: %v", e, err)
+	}
+}
+
+func TestQueueOutputDropsPermanentErrors(t *testing.T) {
+
+	obs := testObservability()
+	out := &testOutput{name: "out", errs: []error{NewStatusError(400, errors.New("bad request"))}}
+	dir := t.TempDir()
+	file := filepath.Join(dir, "dead.jsonl")
+	dl := NewDeadLetters(RetryOptions{DeadLetterFile: file}, nil, obs)
+
+	qo := NewQueueOutput(out, QueueOptions{Dir: dir, RetryDelay: 1}, dl, obs)
+	qo.Send(&Event{ID: "bad", Type: "T"})
+	qo.Send(&Event{ID: "good", Type: "T"})
+
+	// permanent error doesn't block the next event
+	waitFor(t, func() bool { n, _ := out.count(); return n == 1 })
+	qo.Stop(context.Background())
+
+	if _, calls := out.count(); calls != 2 {
+		t.Fatalf("calls %d, want 2", calls)
+	}
+	letters, err := ReadDeadLetters(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 1 || letters[0].Event.ID != "bad" || letters[0].Status != 400 {
+		t.Fatalf("dead letters %+v", letters)
+	}
+
+	// both events are acked, nothing is delivered again after restart
+	again := &testOutput{name: "out"}
+	qo = NewQueueOutput(again, QueueOptions{Dir: dir, RetryDelay: 1}, dl, obs)
+	time.Sleep(100 * time.Millisecond)
+	qo.Stop(context.Background())
+	if _, calls := again.count(); calls != 0 {
+		t.Fatalf("calls after restart %d, want 0", calls)
+	}
+}
+
+func TestQueueOutputStopsAfterMaxAttempts(t *testing.T) {
+
+	obs := testObservability()
+	retryable := errors.New("connection refused")
+	out := &testOutput{name: "out", errs: []error{retryable, retryable, retryable}}
+
+	qo := NewQueueOutput(out, QueueOptions{Dir: t.TempDir(), RetryDelay: 1, MaxAttempts: 2}, nil, obs)
+	qo.Send(&Event{ID: "1"})
+	qo.Send(&Event{ID: "2"})
+
+	// first event is given up after two attempts, second one is delivered on its second attempt
+	waitFor(t, func() bool { n, _ := out.count(); return n == 1 })
+	qo.Stop(context.Background())
+
+	out.mutex.Lock()
+	defer out.mutex.Unlock()
+	if out.calls != 4 || out.events[0].ID != "2" {
+		t.Fatalf("calls %d, delivered %s, want 4 calls and event 2", out.calls, out.events[0].ID)
+	}
+}
+
+func TestQueueOutputKeepsEventAfterRetries(t *testing.T) {
+
+	obs := testObservability()
+	dir := t.TempDir()
+	retryable := errors.New("connection refused")
+	errs := make([]error, 10)
+	for i := range errs {
+		errs[i] = retryable
+	}
+	out := &testOutput{name: "out", errs: errs}
+
+	// outage is longer than retry attempts, queue without max attempts keeps event
+	r := NewRetryOutput(&sync.WaitGroup{}, out, RetryOptions{MaxAttempts: 2, Delay: 1}, nil, obs)
+	qo := NewQueueOutput(r, QueueOptions{Dir: dir, RetryDelay: 1}, nil, obs)
+	qo.Send(&Event{ID: "1"})
+
+	waitFor(t, func() bool { _, calls := out.count(); return calls >= 4 })
+	qo.Stop(context.Background())
+	if n, _ := out.count(); n != 0 {
+		t.Fatalf("delivered %d events during outage", n)
+	}
+
+	// event is delivered after restart when output is back
+	again := &testOutput{name: "out"}
+	r = NewRetryOutput(&sync.WaitGroup{}, again, RetryOptions{MaxAttempts: 2, Delay: 1}, nil, obs)
+	qo = NewQueueOutput(r, QueueOptions{Dir: dir, RetryDelay: 1}, nil, obs)
+	waitFor(t, func() bool { n, _ := again.count(); return n == 1 })
+	qo.Stop(context.Background())
+
+	again.mutex.Lock()
+	defer again.mutex.Unlock()
+	if again.events[0].ID != "1" {
+		t.Fatalf("delivered %s, want 1", again.events[0].ID)
+	}
+}
+
+// multiOutput delivers to several channels and fails on some of them like Slack or Telegram
+type multiOutput struct {
+	mutex    sync.Mutex
+	channels []string
+	failing  map[string]int
+	sent     map[string]int
+}
+
+func (m *multiOutput) Name() string                 { return "multi" }
+func (m *multiOutput) Send(event *Event)            { m.Deliver(event) }
+func (m *multiOutput) Stop(_ context.Context) error { return nil }
+
+func (m *multiOutput) Deliver(event *Event) error {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var errs []error
+	for _, ch := range m.channels {
+		if event.Delivered(m.Name(), ch) {
+			continue
+		}
+		if m.failing[ch] > 0 {
+			m.failing[ch]--
+			errs = append(errs, errors.New(ch+" is not available"))
+			continue
+		}
+		m.sent[ch]++
+		event.SetDelivered(m.Name(), ch)
+	}
+	return errors.Join(errs...)
+}
+
+func TestQueueOutputSkipsDeliveredChannels(t *testing.T) {
+
+	obs := testObservability()
+	out := &multiOutput{
+		channels: []string{"a", "b", "c"},
+		failing:  map[string]int{"b": 2},
+		sent:     make(map[string]int),
+	}
+	d := NewDeliveryOutput(nil, out, nil, obs)
+	qo := NewQueueOutput(d, QueueOptions{Dir: t.TempDir(), RetryDelay: 1}, nil, obs)
+	qo.Send(&Event{ID: "1"})
+
+	waitFor(t, func() bool {
+		out.mutex.Lock()
+		defer out.mutex.Unlock()
+		return out.sent["b"] == 1
+	})
+	qo.Stop(context.Background())
+
+	for _, ch := range out.channels {
+		if out.sent[ch] != 1 {
+			t.Fatalf("channel %s got %d messages, want 1", ch, out.sent[ch])
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
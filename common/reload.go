@@ -0,0 +1,117 @@
+package common
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	sreCommon "github.com/devopsext/sre/common"
+)
+
+type Reloadable interface {
+	Name() string
+	Reload() (bool, error)
+}
+
+type ReloaderOptions struct {
+	Interval int
+}
+
+// Reloader checks reloadables periodically or on demand
+type Reloader struct {
+	options ReloaderOptions
+	list    []Reloadable
+	mutex   sync.Mutex
+	logger  sreCommon.Logger
+	meter   sreCommon.Meter
+}
+
+var reloadables []Reloadable
+var reloadablesMutex sync.Mutex
+
+// RegisterReloadable makes r visible to all reloaders, templates register themselves
+func RegisterReloadable(r Reloadable) {
+
+	reloadablesMutex.Lock()
+	defer reloadablesMutex.Unlock()
+	reloadables = append(reloadables, r)
+}
+
+func (r *Reloader) Add(rl Reloadable) {
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.list = append(r.list, rl)
+}
+
+func (r *Reloader) all() []Reloadable {
+
+	reloadablesMutex.Lock()
+	list := append([]Reloadable{}, reloadables...)
+	reloadablesMutex.Unlock()
+
+	r.mutex.Lock()
+	list = append(list, r.list...)
+	r.mutex.Unlock()
+	return list
+}
+
+// Reload reloads everything which is changed, failed reloadables keep their previous versions
+func (r *Reloader) Reload() {
+
+	for _, rl := range r.all() {
+
+		changed, err := rl.Reload()
+		if !changed && err == nil {
+			continue
+		}
+
+		labels := make(map[string]string)
+		labels["reload"] = rl.Name()
+
+		requests := r.meter.Counter("reload", "requests", "Count of all reloads", labels)
+		requests.Inc()
+
+		if err != nil {
+			errors := r.meter.Counter("reload", "errors", "Count of all reload errors", labels)
+			errors.Inc()
+			r.logger.Error("%s reload failed, previous version is kept: %v", rl.Name(), err)
+			continue
+		}
+		r.logger.Info("%s reloaded", rl.Name())
+	}
+}
+
+// Start checks changes every interval until ctx is done
+func (r *Reloader) Start(ctx context.Context, wg *sync.WaitGroup) {
+
+	if r.options.Interval <= 0 {
+		return
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(time.Duration(r.options.Interval) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.Reload()
+			}
+		}
+	}()
+}
+
+func NewReloader(options ReloaderOptions, observability *Observability) *Reloader {
+
+	return &Reloader{
+		options: options,
+		logger:  observability.Logs(),
+		meter:   observability.Metrics(),
+	}
+}
@@ -0,0 +1,395 @@
+package common
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+)
+
+type RetryOptions struct {
+	MaxAttempts      int
+	Delay            int
+	MaxDelay         int
+	Factor           float64
+	Jitter           float64
+	DeadLetterFile   string
+	DeadLetterOutput string
+}
+
+// DeadLetters keeps events which can't be delivered in a JSONL file or forwards them to dead letter outputs
+type DeadLetters struct {
+	options RetryOptions
+	outputs *Outputs
+	logger  sreCommon.Logger
+	meter   sreCommon.Meter
+}
+
+// RetryOutput retries failed deliveries of the wrapped output and moves exhausted events to a dead letter
+type RetryOutput struct {
+	wg          *sync.WaitGroup
+	output      Output
+	options     RetryOptions
+	deadLetters *DeadLetters
+	logger      sreCommon.Logger
+	meter       sreCommon.Meter
+	done        chan struct{}
+	once        sync.Once
+}
+
+// StatusError keeps http status code of a failed delivery
+type StatusError struct {
+	Code int
+	Err  error
+}
+
+type DeadLetter struct {
+	Time     time.Time `json:"time"`
+	Output   string    `json:"output"`
+	Attempts int       `json:"attempts"`
+	Status   int       `json:"status,omitempty"`
+	Error    string    `json:"error"`
+	Event    *Event    `json:"event"`
+}
+
+const deadLetterVia = "DeadLetter"
+
+var deadLetterMutex sync.Mutex
+
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+func NewStatusError(code int, err error) error {
+	return &StatusError{Code: code, Err: err}
+}
+
+// ErrorStatusCode finds http status code of StatusError in error chain, 0 is returned if there is none
+func ErrorStatusCode(err error) int {
+
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Code
+	}
+	return 0
+}
+
+// HttpPostRaw posts body like utils.HttpPostRaw, error of unsuccessful response keeps its status code
+func HttpPostRaw(client *http.Client, URL, contentType string, authorization string, raw []byte) ([]byte, error) {
+
+	b, code, err := utils.HttpPostRawOutCode(client, URL, contentType, authorization, raw)
+	if err != nil && code > 0 {
+		return b, NewStatusError(code, err)
+	}
+	return b, err
+}
+
+// IsRetryable says whether delivery should be repeated, client errors are permanent except timeouts and rate limits
+func IsRetryable(err error) bool {
+
+	code := ErrorStatusCode(err)
+	if code >= 400 && code < 500 {
+		return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// ReadDeadLetters reads dead letters from JSONL file
+func ReadDeadLetters(path string) ([]*DeadLetter, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r []*DeadLetter
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), math.MaxInt32)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var dl DeadLetter
+		if err := json.Unmarshal(line, &dl); err != nil {
+			return r, err
+		}
+		r = append(r, &dl)
+	}
+	return r, scanner.Err()
+}
+
+func (r *RetryOutput) Name() string {
+	return r.output.Name()
+}
+
+func (r *RetryOutput) labels() map[string]string {
+
+	labels := make(map[string]string)
+	labels["output"] = r.Name()
+	return labels
+}
+
+func (r *RetryOutput) delay(attempt int) time.Duration {
+
+	d := float64(r.options.Delay) * math.Pow(r.options.Factor, float64(attempt-1))
+	if r.options.MaxDelay > 0 && d > float64(r.options.MaxDelay) {
+		d = float64(r.options.MaxDelay)
+	}
+	if r.options.Jitter > 0 {
+		d = d + d*r.options.Jitter*(2*rand.Float64()-1)
+	}
+	if d < 0 {
+		d = 0
+	}
+	return time.Duration(d) * time.Millisecond
+}
+
+func (d *DeadLetters) labels(output Output) map[string]string {
+
+	labels := make(map[string]string)
+	labels["output"] = output.Name()
+	return labels
+}
+
+func (d *DeadLetters) writeFile(dl *DeadLetter) error {
+
+	b, err := JsonMarshal(dl)
+	if err != nil {
+		return err
+	}
+
+	deadLetterMutex.Lock()
+	defer deadLetterMutex.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(d.options.DeadLetterFile), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(d.options.DeadLetterFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(b); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func (d *DeadLetters) forward(output Output, dl *DeadLetter) {
+
+	via := make(map[string]interface{})
+	for k, v := range dl.Event.Via {
+		via[k] = v
+	}
+	via[deadLetterVia] = map[string]interface{}{
+		"output":   dl.Output,
+		"attempts": dl.Attempts,
+		"status":   dl.Status,
+		"error":    dl.Error,
+	}
+
+	e := Event{
+		ID:      dl.Event.ID,
+		Time:    dl.Event.Time,
+		Channel: dl.Event.Channel,
+		Type:    dl.Event.Type,
+		Data:    dl.Event.Data,
+		Via:     via,
+	}
+	e.SetLogger(d.logger)
+
+	d.outputs.SendForward(&e, []Output{output}, d.options.DeadLetterOutput)
+}
+
+// Enabled says whether there is a place to keep dead letters
+func (d *DeadLetters) Enabled() bool {
+	return d != nil && (!utils.IsEmpty(d.options.DeadLetterFile) || !utils.IsEmpty(d.options.DeadLetterOutput))
+}
+
+// drop gives up event which has no place to be kept, it's final so the event is not delivered again
+func (d *DeadLetters) drop(output Output, event *Event, attempts int, err error) {
+
+	event.failed()
+
+	dropped := d.meter.Counter("retry", "dropped", "Count of all events dropped without dead letter", d.labels(output), "output")
+	dropped.Inc()
+	d.logger.Warn("%s event %s is dropped after %d attempt(s): %v", output.Name(), event.ID, attempts, err)
+}
+
+// Put stores event which output can't deliver, event is dropped if there is no place to store it
+func (d *DeadLetters) Put(output Output, event *Event, attempts int, err error) {
+
+	event.failed()
+
+	toFile := !utils.IsEmpty(d.options.DeadLetterFile)
+	// an event which is already a dead letter is not forwarded again to avoid loops
+	_, dead := event.Via[deadLetterVia]
+	toOutput := !utils.IsEmpty(d.options.DeadLetterOutput) && d.outputs != nil && !dead
+
+	if !toFile && !toOutput {
+		d.drop(output, event, attempts, err)
+		return
+	}
+
+	dl := &DeadLetter{
+		Time:     time.Now().UTC(),
+		Output:   output.Name(),
+		Attempts: attempts,
+		Status:   ErrorStatusCode(err),
+		Error:    err.Error(),
+		Event:    event,
+	}
+
+	deadletters := d.meter.Counter("retry", "deadletters", "Count of all dead letters", d.labels(output), "output")
+	deadletters.Inc()
+
+	if toFile {
+		if e := d.writeFile(dl); e != nil {
+			d.logger.Error("%s dead letter file write failed: %v", output.Name(), e)
+			if !toOutput {
+				d.drop(output, event, attempts, err)
+				return
+			}
+		}
+	}
+	if toOutput {
+		d.forward(output, dl)
+	}
+	d.logger.Debug("%s event moved to dead letter after %d attempt(s): %v", output.Name(), attempts, err)
+}
+
+func NewDeadLetters(options RetryOptions, outputs *Outputs, observability *Observability) *DeadLetters {
+
+	return &DeadLetters{
+		options: options,
+		outputs: outputs,
+		logger:  observability.Logs(),
+		meter:   observability.Metrics(),
+	}
+}
+
+func (r *RetryOutput) Send(event *Event) {
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		// there is no queue to keep event which is not delivered
+		if attempts, err := r.deliver(event); err != nil {
+			r.deadLetters.drop(r, event, attempts, err)
+		}
+	}()
+}
+
+// Deliver returns error if attempts are over and there are no dead letters, so the queue keeps event and delivers it later
+func (r *RetryOutput) Deliver(event *Event) error {
+
+	_, err := r.deliver(event)
+	return err
+}
+
+func (r *RetryOutput) deliver(event *Event) (int, error) {
+
+	if event == nil {
+		return 0, nil
+	}
+
+	attempt := 0
+	for {
+		attempt++
+		err := r.output.Deliver(event)
+		if err == nil {
+			return attempt, nil
+		}
+
+		errors := r.meter.Counter("retry", "errors", "Count of all failed delivery attempts", r.labels(), "output")
+		errors.Inc()
+
+		if !IsRetryable(err) {
+			r.deadLetters.Put(r, event, attempt, err)
+			return attempt, nil
+		}
+		if attempt >= r.options.MaxAttempts {
+			if !r.deadLetters.Enabled() {
+				return attempt, err
+			}
+			r.deadLetters.Put(r, event, attempt, err)
+			return attempt, nil
+		}
+
+		delay := r.delay(attempt)
+		r.logger.Debug("%s delivery attempt %d failed, retry in %s: %v", r.Name(), attempt, delay, err)
+
+		retries := r.meter.Counter("retry", "retries", "Count of all delivery retries", r.labels(), "output")
+		retries.Inc()
+
+		select {
+		case <-time.After(delay):
+		case <-r.done:
+			// without dead letters event is left to the queue, it's delivered again after restart
+			if !r.deadLetters.Enabled() {
+				return attempt, err
+			}
+			r.deadLetters.Put(r, event, attempt, err)
+			return attempt, nil
+		}
+	}
+}
+
+// Stop interrupts pending retries and stops the wrapped output
+func (r *RetryOutput) Stop(ctx context.Context) error {
+
+	r.once.Do(func() {
+		close(r.done)
+	})
+	return r.output.Stop(ctx)
+}
+
+// NewRetryOutput wraps output with retries, output is returned as is if neither retries nor dead letter are defined
+func NewRetryOutput(wg *sync.WaitGroup, output Output, options RetryOptions, deadLetters *DeadLetters, observability *Observability) Output {
+
+	if output == nil || reflect.ValueOf(output).IsNil() {
+		return output
+	}
+
+	if options.MaxAttempts <= 1 && !deadLetters.Enabled() {
+		return output
+	}
+	if options.MaxAttempts < 1 {
+		options.MaxAttempts = 1
+	}
+	if options.Factor < 1 {
+		options.Factor = 1
+	}
+	if deadLetters == nil {
+		deadLetters = NewDeadLetters(RetryOptions{}, nil, observability)
+	}
+
+	return &RetryOutput{
+		wg:          wg,
+		output:      output,
+		options:     options,
+		deadLetters: deadLetters,
+		logger:      observability.Logs(),
+		meter:       observability.Metrics(),
+		done:        make(chan struct{}),
+	}
+}
@@ -0,0 +1,212 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestIsRetryable(t *testing.T) {
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"plain", errors.New("connection refused"), true},
+		{"status text is not parsed", errors.New("400 Bad Request"), true},
+		{"bad request", NewStatusError(http.StatusBadRequest, errors.New("bad")), false},
+		{"not found", NewStatusError(http.StatusNotFound, errors.New("not found")), false},
+		{"timeout", NewStatusError(http.StatusRequestTimeout, errors.New("timeout")), true},
+		{"rate limit", NewStatusError(http.StatusTooManyRequests, errors.New("slow down")), true},
+		{"server", NewStatusError(http.StatusBadGateway, errors.New("bad gateway")), true},
+		{"wrapped", fmt.Errorf("send: %w", NewStatusError(http.StatusForbidden, errors.New("forbidden"))), false},
+		{"joined", errors.Join(errors.New("a"), NewStatusError(http.StatusUnauthorized, errors.New("b"))), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Fatalf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorStatusCode(t *testing.T) {
+
+	if code := ErrorStatusCode(nil); code != 0 {
+		t.Fatalf("nil error code %d", code)
+	}
+	if code := ErrorStatusCode(errors.New("503 Service Unavailable")); code != 0 {
+		t.Fatalf("text error code %d, want 0", code)
+	}
+	err := fmt.Errorf("post: %w", NewStatusError(http.StatusServiceUnavailable, errors.New("unavailable")))
+	if code := ErrorStatusCode(err); code != http.StatusServiceUnavailable {
+		t.Fatalf("code %d, want 503", code)
+	}
+}
+
+func TestRetryOutputDeadLetters(t *testing.T) {
+
+	permanent := NewStatusError(http.StatusBadRequest, errors.New("bad request"))
+	retryable := errors.New("connection reset")
+
+	tests := []struct {
+		name     string
+		errs     []error
+		file     bool
+		calls    int
+		letters  int
+		attempts int
+	}{
+		{"delivered after retry", []error{retryable}, true, 2, 0, 0},
+		{"permanent is not retried", []error{permanent}, true, 1, 1, 1},
+		{"attempts are exhausted", []error{retryable, retryable, retryable}, true, 3, 1, 3},
+		{"dropped without dead letters", []error{permanent}, false, 1, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			obs := testObservability()
+			file := ""
+			if tt.file {
+				file = filepath.Join(t.TempDir(), "dead.jsonl")
+			}
+			options := RetryOptions{MaxAttempts: 3, Delay: 1, DeadLetterFile: file}
+			out := &testOutput{name: "out", errs: tt.errs}
+			r := NewRetryOutput(&sync.WaitGroup{}, out, options, NewDeadLetters(options, nil, obs), obs)
+
+			// dead letter or drop is final, so the caller doesn't deliver event again
+			if err := r.Deliver(&Event{ID: "1", Type: "T"}); err != nil {
+				t.Fatalf("Deliver() = %v, want nil", err)
+			}
+			if _, calls := out.count(); calls != tt.calls {
+				t.Fatalf("calls %d, want %d", calls, tt.calls)
+			}
+			if file == "" {
+				return
+			}
+			letters, _ := ReadDeadLetters(file)
+			if len(letters) != tt.letters {
+				t.Fatalf("dead letters %d, want %d", len(letters), tt.letters)
+			}
+			if tt.letters > 0 && letters[0].Attempts != tt.attempts {
+				t.Fatalf("attempts %d, want %d", letters[0].Attempts, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestRetryOutputReturnsErrorWithoutDeadLetters(t *testing.T) {
+
+	obs := testObservability()
+	retryable := errors.New("connection reset")
+	out := &testOutput{name: "out", errs: []error{retryable, retryable, retryable}}
+	r := NewRetryOutput(&sync.WaitGroup{}, out, RetryOptions{MaxAttempts: 2, Delay: 1}, nil, obs)
+
+	// queue keeps event if attempts are over and there is no dead letter
+	if err := r.Deliver(&Event{ID: "1"}); !errors.Is(err, retryable) {
+		t.Fatalf("Deliver() = %v, want %v", err, retryable)
+	}
+	if _, calls := out.count(); calls != 2 {
+		t.Fatalf("calls %d, want 2", calls)
+	}
+
+	// permanent error is final anyway
+	out = &testOutput{name: "out", errs: []error{NewStatusError(http.StatusBadRequest, errors.New("bad"))}}
+	r = NewRetryOutput(&sync.WaitGroup{}, out, RetryOptions{MaxAttempts: 2, Delay: 1}, nil, obs)
+	if err := r.Deliver(&Event{ID: "2"}); err != nil {
+		t.Fatalf("Deliver() = %v, want nil", err)
+	}
+}
+
+func TestRetryOutputStopKeepsEventForQueue(t *testing.T) {
+
+	obs := testObservability()
+	options := RetryOptions{MaxAttempts: 5, Delay: 60000}
+	out := &testOutput{name: "out", errs: []error{errors.New("connection reset")}}
+	r := NewRetryOutput(&sync.WaitGroup{}, out, options, nil, obs)
+
+	result := make(chan error, 1)
+	go func() {
+		result <- r.Deliver(&Event{ID: "1"})
+	}()
+	waitFor(t, func() bool { _, calls := out.count(); return calls == 1 })
+	r.Stop(context.Background())
+
+	// without dead letters error is returned, so queue keeps event till restart
+	if err := <-result; err == nil {
+		t.Fatal("Deliver() = nil, want error after stop")
+	}
+}
+
+// channelsOutput fails each channel given times and reports failure of channel on every failed attempt
+type channelsOutput struct {
+	testOutput
+	fails    map[string]int
+	failures map[string]int
+}
+
+func (o *channelsOutput) Deliver(event *Event) error {
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	var errs []error
+	for _, channel := range []string{"a", "b"} {
+		if event.Delivered(o.name, channel) {
+			continue
+		}
+		if o.fails[channel] == 0 {
+			event.SetDelivered(o.name, channel)
+			continue
+		}
+		o.fails[channel]--
+		c := channel
+		event.OnFailure(o.name, channel, func() {
+			o.mutex.Lock()
+			defer o.mutex.Unlock()
+			o.failures[c]++
+		})
+		errs = append(errs, fmt.Errorf("channel %s failed", channel))
+	}
+	return errors.Join(errs...)
+}
+
+func TestRetryOutputReportsFailureOnce(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		fails    map[string]int
+		failures map[string]int
+	}{
+		{"all attempts fail", map[string]int{"a": 3, "b": 3}, map[string]int{"a": 1, "b": 1}},
+		{"one channel is delivered", map[string]int{"a": 1, "b": 3}, map[string]int{"b": 1}},
+		{"channels are delivered by retries", map[string]int{"a": 1, "b": 2}, map[string]int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			out := &channelsOutput{testOutput: testOutput{name: "out"}, fails: tt.fails, failures: make(map[string]int)}
+			wg := &sync.WaitGroup{}
+			r := NewRetryOutput(wg, out, RetryOptions{MaxAttempts: 3, Delay: 1}, nil, testObservability())
+
+			// event is dropped without queue and dead letters, it's the final attempt
+			r.Send(&Event{ID: "1"})
+			wg.Wait()
+
+			out.mutex.Lock()
+			defer out.mutex.Unlock()
+			if len(out.failures) != len(tt.failures) || out.failures["a"] != tt.failures["a"] || out.failures["b"] != tt.failures["b"] {
+				t.Fatalf("failures %v, want %v", out.failures, tt.failures)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/buger/jsonparser"
+)
+
+// Route sends events which match type, channel, data and labels patterns to outputs, outputs are names or patterns
+type Route struct {
+	Name     string            `json:"name,omitempty"`
+	Type     string            `json:"type,omitempty"`
+	Channel  string            `json:"channel,omitempty"`
+	Data     map[string]string `json:"data,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Outputs  []string          `json:"outputs"`
+	Continue bool              `json:"continue,omitempty"`
+	Default  bool              `json:"default,omitempty"`
+
+	typeRegex    *regexp.Regexp
+	channelRegex *regexp.Regexp
+	dataRegexs   map[string]*regexp.Regexp
+	outputRegexs []*regexp.Regexp
+}
+
+func anchoredRegex(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(fmt.Sprintf("^(?:%s)$", pattern))
+}
+
+// DataPath splits dotted path like alerts.0.labels.severity into jsonparser keys
+func DataPath(path string) []string {
+
+	var keys []string
+	for _, k := range strings.Split(path, ".") {
+		if _, err := strconv.Atoi(k); err == nil {
+			k = fmt.Sprintf("[%s]", k)
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// DataValue returns value by path from json, strings are unquoted, missing path returns false
+func DataValue(data []byte, path string) (string, bool) {
+
+	value, typ, _, err := jsonparser.Get(data, DataPath(path)...)
+	if err != nil || typ == jsonparser.NotExist || typ == jsonparser.Null {
+		return "", false
+	}
+	if typ == jsonparser.String {
+		if s, err := jsonparser.ParseString(value); err == nil {
+			return s, true
+		}
+	}
+	return string(value), true
+}
+
+func (r *Route) Compile() error {
+
+	if len(r.Outputs) == 0 {
+		return fmt.Errorf("route %s has no outputs", r.Name)
+	}
+
+	var err error
+	if r.Type != "" {
+		if r.typeRegex, err = anchoredRegex(r.Type); err != nil {
+			return fmt.Errorf("route %s type: %w", r.Name, err)
+		}
+	}
+	if r.Channel != "" {
+		if r.channelRegex, err = anchoredRegex(r.Channel); err != nil {
+			return fmt.Errorf("route %s channel: %w", r.Name, err)
+		}
+	}
+
+	r.dataRegexs = make(map[string]*regexp.Regexp)
+	for path, pattern := range r.Data {
+		re, err := anchoredRegex(pattern)
+		if err != nil {
+			return fmt.Errorf("route %s data %s: %w", r.Name, path, err)
+		}
+		r.dataRegexs[path] = re
+	}
+	for label, pattern := range r.Labels {
+		re, err := anchoredRegex(pattern)
+		if err != nil {
+			return fmt.Errorf("route %s label %s: %w", r.Name, label, err)
+		}
+		r.dataRegexs["labels."+label] = re
+	}
+
+	r.outputRegexs = nil
+	for _, o := range r.Outputs {
+		re, err := anchoredRegex(o)
+		if err != nil {
+			return fmt.Errorf("route %s output: %w", r.Name, err)
+		}
+		r.outputRegexs = append(r.outputRegexs, re)
+	}
+	return nil
+}
+
+func (r *Route) hasData() bool {
+	return len(r.dataRegexs) > 0
+}
+
+// Match checks event fields, data is event data json which is needed for data and labels patterns
+func (r *Route) Match(e *Event, data []byte) bool {
+
+	if r.typeRegex != nil && !r.typeRegex.MatchString(e.Type) {
+		return false
+	}
+	if r.channelRegex != nil && !r.channelRegex.MatchString(e.Channel) {
+		return false
+	}
+	for path, re := range r.dataRegexs {
+		value, ok := DataValue(data, path)
+		if !ok || !re.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Route) MatchOutput(name string) bool {
+
+	for _, re := range r.outputRegexs {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package common
+
+import (
+	"encoding/json"
+
+	sreCommon "github.com/devopsext/sre/common"
+)
+
+// Router evaluates routes in order, matched route stops evaluation unless it has continue,
+// default routes are used if nothing is matched
+type Router struct {
+	routes   []*Route
+	defaults []*Route
+	data     bool
+	logger   sreCommon.Logger
+	meter    sreCommon.Meter
+}
+
+func (r *Router) Empty() bool {
+	return len(r.routes) == 0 && len(r.defaults) == 0
+}
+
+// Match returns routes for event
+func (r *Router) Match(e *Event) []*Route {
+
+	var data []byte
+	if r.data {
+		b, err := json.Marshal(e.Data)
+		if err != nil {
+			r.logger.Error(err)
+		}
+		data = b
+	}
+
+	var matched []*Route
+	for _, route := range r.routes {
+		if !route.Match(e, data) {
+			continue
+		}
+		matched = append(matched, route)
+		if !route.Continue {
+			break
+		}
+	}
+	if len(matched) > 0 {
+		return matched
+	}
+
+	labels := make(map[string]string)
+	labels["type"] = e.Type
+	labels["channel"] = e.Channel
+
+	unmatched := r.meter.Counter("router", "unmatched", "Count of events which match no route", labels)
+	unmatched.Inc()
+
+	if len(r.defaults) == 0 {
+		r.logger.Debug("No route found for %s event on %s", e.Type, e.Channel)
+	}
+	return r.defaults
+}
+
+func NewRouter(routes []*Route, observability *Observability) (*Router, error) {
+
+	r := &Router{
+		logger: observability.Logs(),
+		meter:  observability.Metrics(),
+	}
+	for _, route := range routes {
+		if err := route.Compile(); err != nil {
+			return nil, err
+		}
+		if route.Default {
+			r.defaults = append(r.defaults, route)
+			continue
+		}
+		r.data = r.data || route.hasData()
+		r.routes = append(r.routes, route)
+	}
+	return r, nil
+}
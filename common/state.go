@@ -0,0 +1,332 @@
+package common
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	sreCommon "github.com/devopsext/sre/common"
+)
+
+const (
+	StateFiring   = "firing"
+	StateResolved = "resolved"
+
+	// outputs reply to or edit firing message when alert is resolved
+	StateModeReply = "reply"
+	StateModeEdit  = "edit"
+
+	// changes of state are written to file once per interval
+	stateFlushInterval = time.Second
+	// entries which are not updated for TTL are removed once per interval, lookups skip them till then
+	stateExpireInterval = time.Minute
+)
+
+// EventState links firing and resolved events of the same alert, duration is seconds from firing till resolved,
+// ends at is a resolve time of source if it has one
+type EventState struct {
+	Fingerprint string    `json:"fingerprint"`
+	Status      string    `json:"status"`
+	StartsAt    time.Time `json:"startsAt,omitempty"`
+	EndsAt      time.Time `json:"endsAt,omitempty"`
+	Duration    float64   `json:"duration,omitempty"`
+}
+
+// StateMessage is a message sent by output on firing, target is output specific address like Slack channel ID
+type StateMessage struct {
+	Output  string `json:"output"`
+	Channel string `json:"channel"`
+	Target  string `json:"target,omitempty"`
+	ID      string `json:"id"`
+}
+
+type StateOptions struct {
+	TTL  int
+	File string
+}
+
+type stateEntry struct {
+	StartsAt time.Time       `json:"startsAt"`
+	Updated  time.Time       `json:"updated"`
+	Resolved bool            `json:"resolved,omitempty"`
+	Messages []*StateMessage `json:"messages,omitempty"`
+}
+
+// State keeps alerts by fingerprint with messages which were sent on firing, file is written in background
+type State struct {
+	options StateOptions
+	mutex   sync.Mutex
+	entries map[string]*stateEntry
+	logger  sreCommon.Logger
+	dirty   chan struct{}
+	done    chan struct{}
+	closed  chan struct{}
+	once    sync.Once
+	err     error
+}
+
+func NewEventState(fingerprint, status string) *EventState {
+
+	if fingerprint == "" {
+		return nil
+	}
+	return &EventState{Fingerprint: fingerprint, Status: status}
+}
+
+func (es *EventState) Firing() bool {
+	return es != nil && es.Status == StateFiring
+}
+
+func (es *EventState) Resolved() bool {
+	return es != nil && es.Status == StateResolved
+}
+
+// SetEndsAt keeps resolve time of source, end of firing alert is an expected one, so it's skipped
+func (es *EventState) SetEndsAt(t time.Time) {
+
+	if !es.Resolved() || t.IsZero() || t.Unix() <= 0 {
+		return
+	}
+	es.EndsAt = t.UTC()
+}
+
+// expired says whether entry is not updated for TTL
+func (s *State) expired(entry *stateEntry, now time.Time) bool {
+	return s.options.TTL > 0 && now.Sub(entry.Updated) > time.Duration(s.options.TTL)*time.Second
+}
+
+// expire removes expired entries and says whether any of them is removed
+func (s *State) expire(now time.Time) bool {
+
+	removed := false
+	for fp, entry := range s.entries {
+		if s.expired(entry, now) {
+			delete(s.entries, fp)
+			removed = true
+		}
+	}
+	return removed
+}
+
+// changed marks entries to be written, changes which are made till the next write are written once
+func (s *State) changed() {
+
+	if s.options.File == "" {
+		return
+	}
+	select {
+	case s.dirty <- struct{}{}:
+	default:
+	}
+}
+
+func (s *State) write() error {
+
+	s.mutex.Lock()
+	b, err := json.Marshal(s.entries)
+	s.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp := s.options.File + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.options.File)
+}
+
+// flush writes entries if they are changed
+func (s *State) flush() error {
+
+	select {
+	case <-s.dirty:
+		return s.write()
+	default:
+		return nil
+	}
+}
+
+// run writes changed entries and removes expired ones once per interval, so events don't wait for the file or the scan
+func (s *State) run(flushInterval, expireInterval time.Duration) {
+
+	defer close(s.closed)
+
+	var flush, expire <-chan time.Time
+	if s.options.File != "" {
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+		flush = ticker.C
+	}
+	if s.options.TTL > 0 {
+		ticker := time.NewTicker(expireInterval)
+		defer ticker.Stop()
+		expire = ticker.C
+	}
+
+	for {
+		select {
+		case <-flush:
+			if err := s.flush(); err != nil {
+				s.logger.Error(err)
+			}
+		case now := <-expire:
+			s.mutex.Lock()
+			if s.expire(now) {
+				s.changed()
+			}
+			s.mutex.Unlock()
+		case <-s.done:
+			s.err = s.flush()
+			return
+		}
+	}
+}
+
+func (s *State) load() error {
+
+	b, err := os.ReadFile(s.options.File)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, &s.entries); err != nil {
+		return err
+	}
+	if s.entries == nil {
+		s.entries = make(map[string]*stateEntry)
+	}
+	s.expire(time.Now())
+	return nil
+}
+
+// Update remembers firing alert and sets start and duration of resolved one
+func (s *State) Update(e *Event) {
+
+	if s == nil || e == nil || e.State == nil {
+		return
+	}
+
+	now := time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry, ok := s.entries[e.State.Fingerprint]
+	if ok && s.expired(entry, now) {
+		ok = false
+	}
+	switch {
+	case e.State.Firing():
+		if !ok || entry.Resolved {
+			startsAt := e.Time
+			if startsAt.IsZero() {
+				startsAt = now
+			}
+			entry = &stateEntry{StartsAt: startsAt}
+			s.entries[e.State.Fingerprint] = entry
+		}
+	case e.State.Resolved():
+		if !ok {
+			// start is unknown, so event time is used, it's a start time for some sources like Alertmanager
+			entry = &stateEntry{StartsAt: e.Time}
+			s.entries[e.State.Fingerprint] = entry
+		}
+		entry.Resolved = true
+	default:
+		return
+	}
+	entry.Updated = now
+
+	e.State.StartsAt = entry.StartsAt
+	endsAt := e.State.EndsAt
+	if endsAt.IsZero() {
+		endsAt = now
+	}
+	if e.State.Resolved() && !entry.StartsAt.IsZero() && endsAt.After(entry.StartsAt) {
+		e.State.Duration = endsAt.Sub(entry.StartsAt).Seconds()
+	}
+	s.changed()
+}
+
+// Sent remembers message of firing event
+func (s *State) Sent(e *Event, m *StateMessage) {
+
+	if s == nil || e == nil || !e.State.Firing() || m == nil || m.ID == "" {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry, ok := s.entries[e.State.Fingerprint]
+	if !ok || entry.Resolved {
+		return
+	}
+	entry.Messages = append(entry.Messages, m)
+	entry.Updated = time.Now()
+	s.changed()
+}
+
+// Firing returns message sent by output to channel on firing of resolved event
+func (s *State) Firing(e *Event, output, channel string) *StateMessage {
+
+	if s == nil || e == nil || !e.State.Resolved() {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry, ok := s.entries[e.State.Fingerprint]
+	if !ok || s.expired(entry, time.Now()) {
+		return nil
+	}
+	for _, m := range entry.Messages {
+		if m.Output == output && m.Channel == channel {
+			return m
+		}
+	}
+	return nil
+}
+
+// Close writes the last changes and stops the background
+func (s *State) Close() error {
+
+	if s == nil || s.done == nil {
+		return nil
+	}
+	s.once.Do(func() { close(s.done) })
+	<-s.closed
+	return s.err
+}
+
+func NewState(options StateOptions, observability *Observability) (*State, error) {
+
+	s := &State{
+		options: options,
+		entries: make(map[string]*stateEntry),
+		logger:  observability.Logs(),
+	}
+
+	if options.File != "" {
+		if err := os.MkdirAll(filepath.Dir(options.File), 0755); err != nil {
+			return nil, err
+		}
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+		s.dirty = make(chan struct{}, 1)
+	}
+
+	if options.File != "" || options.TTL > 0 {
+		s.done = make(chan struct{})
+		s.closed = make(chan struct{})
+		go s.run(stateFlushInterval, stateExpireInterval)
+	}
+	return s, nil
+}
@@ -0,0 +1,140 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func stateEvent(status string, t time.Time) *Event {
+	return &Event{Time: t, State: NewEventState("fp", status)}
+}
+
+func TestStateDuration(t *testing.T) {
+
+	startsAt := time.Now().Add(-time.Hour).UTC()
+
+	tests := []struct {
+		name   string
+		endsAt time.Time
+		want   float64
+	}{
+		{"ends at of source", startsAt.Add(90 * time.Second), 90},
+		{"zero ends at", time.Time{}, time.Hour.Seconds()},
+		{"unix zero ends at", time.Unix(0, 0), time.Hour.Seconds()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			s, err := NewState(StateOptions{}, testObservability())
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.Update(stateEvent(StateFiring, startsAt))
+
+			resolved := stateEvent(StateResolved, time.Now())
+			resolved.State.SetEndsAt(tt.endsAt)
+			s.Update(resolved)
+
+			if !resolved.State.StartsAt.Equal(startsAt) {
+				t.Fatalf("starts at %v, want %v", resolved.State.StartsAt, startsAt)
+			}
+			// duration till now is a bit longer than an hour
+			if d := resolved.State.Duration; d < tt.want || d > tt.want+5 {
+				t.Fatalf("duration %v, want %v", d, tt.want)
+			}
+		})
+	}
+}
+
+func TestStateSetEndsAt(t *testing.T) {
+
+	endsAt := time.Now()
+	firing := NewEventState("fp", StateFiring)
+	firing.SetEndsAt(endsAt)
+	if !firing.EndsAt.IsZero() {
+		t.Fatal("firing alert has ends at")
+	}
+
+	var none *EventState
+	none.SetEndsAt(endsAt)
+}
+
+func TestStateWritesInBackground(t *testing.T) {
+
+	file := filepath.Join(t.TempDir(), "state.json")
+	s, err := NewState(StateOptions{File: file}, testObservability())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		e := stateEvent(StateFiring, time.Now())
+		s.Update(e)
+		s.Sent(e, &StateMessage{Output: "Slack", Channel: "alerts", ID: "1"})
+	}
+	// file is written by writer, not by updates
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("file is written on update: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = NewState(StateOptions{File: file}, testObservability())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	m := s.Firing(stateEvent(StateResolved, time.Now()), "Slack", "alerts")
+	if m == nil || m.ID != "1" {
+		t.Fatalf("firing message %+v after restart", m)
+	}
+}
+
+func TestStateWriterFlushesChanges(t *testing.T) {
+
+	file := filepath.Join(t.TempDir(), "state.json")
+	s, err := NewState(StateOptions{File: file}, testObservability())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.Update(stateEvent(StateFiring, time.Now()))
+	waitFor(t, func() bool { _, err := os.Stat(file); return err == nil })
+}
+
+func TestStateExpire(t *testing.T) {
+
+	s, err := NewState(StateOptions{TTL: 60}, testObservability())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	old := stateEvent(StateFiring, time.Now().Add(-time.Hour))
+	s.Update(old)
+	s.Sent(old, &StateMessage{Output: "Slack", Channel: "alerts", ID: "1"})
+	s.Update(&Event{Time: time.Now(), State: NewEventState("other", StateFiring)})
+
+	s.mutex.Lock()
+	s.entries["fp"].Updated = time.Now().Add(-2 * time.Minute)
+	s.mutex.Unlock()
+
+	// expired entry is skipped by lookups before it's removed in background
+	if m := s.Firing(stateEvent(StateResolved, time.Now()), "Slack", "alerts"); m != nil {
+		t.Fatalf("firing message %+v of expired alert", m)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.expire(time.Now()) {
+		t.Fatal("expired entry isn't removed")
+	}
+	if _, ok := s.entries["fp"]; ok || len(s.entries) != 1 {
+		t.Fatalf("entries %v, want other alert only", s.entries)
+	}
+}
@@ -0,0 +1,109 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	toolsRender "github.com/devopsext/tools/render"
+)
+
+// Template is a text template which is compiled again when its file is changed
+type Template struct {
+	options  toolsRender.TemplateOptions
+	source   string
+	content  string
+	logger   *Observability
+	mutex    sync.RWMutex
+	template *toolsRender.TextTemplate
+}
+
+// Name is a template name with its file
+func (t *Template) Name() string {
+
+	if t.isFile() {
+		return fmt.Sprintf("%s:%s", t.options.Name, t.source)
+	}
+	return t.options.Name
+}
+
+func (t *Template) current() *toolsRender.TextTemplate {
+
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.template
+}
+
+func (t *Template) RenderObject(obj interface{}) ([]byte, error) {
+
+	start := time.Now()
+	b, err := t.current().RenderObject(obj)
+
+	if meter := t.logger.Metrics(); meter != nil {
+		labels := make(map[string]string)
+		labels["template"] = t.options.Name
+		duration := meter.Histogram("template", "render_duration", "Duration of template rendering in seconds", labels, "template")
+		duration.Observe(time.Since(start).Seconds())
+	}
+	return b, err
+}
+
+func (t *Template) isFile() bool {
+
+	if t.source == "" {
+		return false
+	}
+	fi, err := os.Stat(t.source)
+	return err == nil && !fi.IsDir()
+}
+
+// Reload compiles template from its file if content is changed, previous version is kept on error
+func (t *Template) Reload() (bool, error) {
+
+	if !t.isFile() {
+		return false, nil
+	}
+
+	content := Content(t.source)
+	if content == t.content {
+		return false, nil
+	}
+
+	opts := t.options
+	opts.Content = content
+	template, err := toolsRender.NewTextTemplate(opts, t.logger)
+	if err != nil {
+		return false, err
+	}
+
+	t.mutex.Lock()
+	t.template = template
+	t.content = content
+	t.mutex.Unlock()
+	return true, nil
+}
+
+// NewTemplate makes template from options where content is a file path or template itself, file templates are reloadable
+func NewTemplate(options toolsRender.TemplateOptions, observability *Observability) (*Template, error) {
+
+	source := options.Content
+	options.Content = Content(source)
+
+	template, err := toolsRender.NewTextTemplate(options, observability)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Template{
+		options:  options,
+		source:   source,
+		content:  options.Content,
+		logger:   observability,
+		template: template,
+	}
+	if t.isFile() {
+		RegisterReloadable(t)
+	}
+	return t, nil
+}
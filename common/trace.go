@@ -0,0 +1,105 @@
+package common
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+)
+
+const TraceParentHeader = "traceparent"
+
+// W3C traceparent is version-traceid-parentid-flags
+var traceParentRegex = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}$`)
+
+type spanContextKey struct{}
+
+// TraceParentID returns trace ID of W3C traceparent, empty string is returned if it's invalid
+func TraceParentID(s string) string {
+
+	m := traceParentRegex.FindStringSubmatch(strings.ToLower(strings.TrimSpace(s)))
+	if len(m) != 2 || strings.Trim(m[1], "0") == "" {
+		return ""
+	}
+	return m[1]
+}
+
+// TraceID finds trace ID in traceparent or in trace ID header, get is a header or attribute getter
+func TraceID(get func(string) string, header string) string {
+
+	if traceID := TraceParentID(get(TraceParentHeader)); !utils.IsEmpty(traceID) {
+		return traceID
+	}
+	if utils.IsEmpty(header) {
+		return ""
+	}
+	return strings.TrimSpace(get(header))
+}
+
+func ContextWithSpan(ctx context.Context, span sreCommon.TracerSpan) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+func SpanFromContext(ctx context.Context) sreCommon.TracerSpan {
+
+	span, _ := ctx.Value(spanContextKey{}).(sreCommon.TracerSpan)
+	return span
+}
+
+// StartSpan starts root span, trace is continued if trace ID is set
+func StartSpan(traces *sreCommon.Traces, traceID, name string) sreCommon.TracerSpan {
+
+	if traces == nil {
+		traces = &sreCommon.Traces{}
+	}
+
+	var span sreCommon.TracerSpan
+	if utils.IsEmpty(traceID) {
+		span = traces.StartSpan()
+	} else {
+		span = traces.StartSpanWithTraceID(traceID, "")
+	}
+	return span.SetName(name)
+}
+
+// StartChildSpan starts child span of parent, root span is started if there is no parent
+func StartChildSpan(traces *sreCommon.Traces, parent sreCommon.TracerSpan, name string) sreCommon.TracerSpan {
+
+	if parent == nil {
+		return StartSpan(traces, "", name)
+	}
+	if traces == nil {
+		traces = &sreCommon.Traces{}
+	}
+	return traces.StartChildSpan(parent.GetContext()).SetName(name)
+}
+
+// StartEventSpan starts child span of event, trace is continued by ID if event lost its span in a queue
+func StartEventSpan(traces *sreCommon.Traces, e *Event, name string) sreCommon.TracerSpan {
+
+	if e.span != nil {
+		return StartChildSpan(traces, e.span, name)
+	}
+	return StartSpan(traces, e.TraceID, name)
+}
+
+// SetSpan links event to span, trace ID is available for templates, event gets ID of ingestion if it has no ID
+func (e *Event) SetSpan(span sreCommon.TracerSpan) {
+
+	if span == nil {
+		return
+	}
+	e.span = span
+	if i := e.ingestion(); i != nil && e.ID == "" {
+		e.ID = i.nextID()
+	}
+	if ctx := span.GetContext(); ctx != nil {
+		e.TraceID = ctx.GetTraceID()
+	}
+}
+
+func (e *Event) Span() sreCommon.TracerSpan {
+	return e.span
+}
@@ -3,7 +3,9 @@ package common
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"reflect"
 	"strings"
 
@@ -14,6 +16,16 @@ func AsEventType(s string) string {
 	return fmt.Sprintf("%sEvent", s)
 }
 
+// HttpBodyErrorStatus returns 413 if request body is over max size, other read errors are 400
+func HttpBodyErrorStatus(err error) int {
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
 func JsonMarshal(t interface{}) ([]byte, error) {
 	buffer := &bytes.Buffer{}
 	encoder := json.NewEncoder(buffer)
@@ -1,15 +1,23 @@
 package input
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
-	"crypto/x509"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
 	"github.com/devopsext/events/common"
 	"github.com/devopsext/events/processor"
 	sreCommon "github.com/devopsext/sre/common"
@@ -18,6 +26,7 @@ import (
 
 type HttpInputOptions struct {
 	HealthcheckURL    string
+	DeliveriesURL     string
 	K8sURL            string
 	KubeURL           string
 	WinEventURL       string
@@ -25,6 +34,10 @@ type HttpInputOptions struct {
 	AlertmanagerURL   string
 	GitlabURL         string
 	DataDogURL        string
+	NewRelicURL       string
+	GrafanaURL        string
+	PagerDutyURL      string
+	OpsgenieURL       string
 	Site24x7URL       string
 	CloudflareURL     string
 	GoogleURL         string
@@ -34,6 +47,11 @@ type HttpInputOptions struct {
 	VCenterURL        string
 	ObserviumEventURL string
 	TeamcityURL       string
+	// Paths maps urls to named processors
+	Paths map[string]string
+	// Auth maps urls to their authentication, AuthConfig is a file or content with the same map in YAML or JSON
+	Auth       map[string]*HttpAuth
+	AuthConfig string
 
 	ServerName    string
 	Listen        string
@@ -42,7 +60,21 @@ type HttpInputOptions struct {
 	Cert          string
 	Key           string
 	Chain         string
+	ClientAuth    string
 	HeaderTraceID string
+	// Accepted responds 202 once outputs accepted events instead of processor response
+	Accepted bool
+
+	// MaxBodySize is in bytes, timeouts are in seconds, rate limits are requests per second per url and per client IP
+	MaxBodySize       int64
+	ReadHeaderTimeout int
+	ReadTimeout       int
+	WriteTimeout      int
+	IdleTimeout       int
+	PathRateLimit     int
+	PathRateBurst     int
+	ClientRateLimit   int
+	ClientRateBurst   int
 }
 
 type HttpInput struct {
@@ -50,6 +82,9 @@ type HttpInput struct {
 	processors *common.Processors
 	logger     sreCommon.Logger
 	meter      sreCommon.Meter
+	traces     *sreCommon.Traces
+	paths      *httpLimiter
+	clients    *httpLimiter
 }
 
 type HttpProcessHandleFunc = func(w http.ResponseWriter, r *http.Request)
@@ -70,16 +105,207 @@ func (h *HttpInput) processURL(url string, mux *http.ServeMux, p common.HttpProc
 
 			requests.Inc()
 
-			err := p.HandleHttpRequest(w, r)
+			if !h.limit(w, r, url, labels) {
+				return
+			}
+
+			if err := h.authorize(r, url); err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					tooLarge := h.meter.Counter("http", "too_large", "Count of all http input requests over max body size", labels, "input")
+					tooLarge.Inc()
+					http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+					return
+				}
+				h.logger.Debug("Http request to %s is unauthorized: %v", path, err)
+				unauthorized := h.meter.Counter("http", "unauthorized", "Count of all unauthorized http input requests", labels, "input")
+				unauthorized.Inc()
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			// event IDs are assigned at ingestion, processors get them by request context
+			ingestion := common.NewIngestion()
+			traceID := common.TraceID(r.Header.Get, h.options.HeaderTraceID)
+			span := common.StartSpan(h.traces, traceID, fmt.Sprintf("http %s", path))
+			span.SetTag("path", path)
+			defer span.Finish()
+
+			// processor response is kept until outputs accept its events
+			response := newHttpResponse(w)
+			admission := common.NewAdmission()
+
+			ctx := common.ContextWithIngestion(common.ContextWithSpan(r.Context(), span), ingestion)
+			err := h.handle(response, r.WithContext(ctx), p, admission)
 			if err != nil {
+				span.Error(err)
 				errors := h.meter.Counter("http", "errors", "Count of all http input errors", labels, "input")
 				errors.Inc()
 			}
+			h.respond(w, response, admission, labels)
 		})
 	}
 }
 
-func (h *HttpInput) Start(wg *sync.WaitGroup, outputs *common.Outputs) {
+// limit checks rate limits of url and client, and caps body size, false is returned if request is rejected
+func (h *HttpInput) limit(w http.ResponseWriter, r *http.Request, url string, labels map[string]string) bool {
+
+	reject := func(limit string, delay time.Duration) bool {
+
+		h.logger.Debug("Http request to %s from %s is over %s rate limit", r.URL.Path, clientIP(r), limit)
+		l := make(map[string]string)
+		for k, v := range labels {
+			l[k] = v
+		}
+		l["limit"] = limit
+		limited := h.meter.Counter("http", "limited", "Count of all rate limited http input requests", l, "input")
+		limited.Inc()
+
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter(delay)))
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return false
+	}
+
+	if ok, delay := h.paths.Allow(url); !ok {
+		return reject("path", delay)
+	}
+	if ok, delay := h.clients.Allow(clientIP(r)); !ok {
+		return reject("client", delay)
+	}
+
+	if h.options.MaxBodySize <= 0 {
+		return true
+	}
+	if r.ContentLength > h.options.MaxBodySize {
+		tooLarge := h.meter.Counter("http", "too_large", "Count of all http input requests over max body size", labels, "input")
+		tooLarge.Inc()
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return false
+	}
+	// chunked bodies fail on read when they are over the size
+	r.Body = http.MaxBytesReader(w, r.Body, h.options.MaxBodySize)
+	return true
+}
+
+// authorize verifies request by auth of url, requests to urls without auth are passed
+func (h *HttpInput) authorize(r *http.Request, url string) error {
+
+	auth := h.options.Auth[url]
+	if auth == nil {
+		return nil
+	}
+	return auth.Verify(r)
+}
+
+// respond answers 503 if outputs rejected events, 202 if they accepted events and it's enabled, otherwise processor response is sent
+func (h *HttpInput) respond(w http.ResponseWriter, response *httpResponse, admission *common.Admission, labels map[string]string) {
+
+	if err := admission.Err(); err != nil {
+		h.logger.Warn("Http request to %s is rejected: %v", labels["path"], err)
+		rejected := h.meter.Counter("http", "rejected", "Count of all http input requests rejected by outputs", labels, "input")
+		rejected.Inc()
+
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	if h.options.Accepted && admission.Events() > 0 && response.status < http.StatusMultipleChoices {
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	response.flush()
+}
+
+// cloudEvent decodes request in CloudEvents structured or binary mode, nil is returned for plain requests
+func (h *HttpInput) cloudEvent(r *http.Request) (*cloudevents.Event, error) {
+
+	m := cehttp.NewMessageFromHttpRequest(r)
+	if m.ReadEncoding() == binding.EncodingUnknown {
+		return nil, nil
+	}
+	return binding.ToEvent(r.Context(), m)
+}
+
+// handle passes CloudEvents of known event types to their processors as is,
+// data of other CloudEvents is unwrapped and handled by url processor like a plain request
+func (h *HttpInput) handle(w http.ResponseWriter, r *http.Request, p common.HttpProcessor, admission *common.Admission) error {
+
+	span := common.StartChildSpan(h.traces, common.SpanFromContext(r.Context()), p.EventType())
+	span = common.SpanWithIngestion(common.SpanWithAdmission(span, admission), common.IngestionFromContext(r.Context()))
+	defer span.Finish()
+	r = r.WithContext(common.ContextWithSpan(r.Context(), span))
+
+	ce, err := h.cloudEvent(r)
+	if err != nil {
+		h.logger.Error("Can't decode cloudevent: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+	if ce == nil {
+		return p.HandleHttpRequest(w, r)
+	}
+
+	h.logger.Debug("CloudEvent => %s", ce.String())
+
+	if cp := h.processors.Find(ce.Type()); cp != nil {
+
+		e, err := common.NewEventFromCloudEvent(ce)
+		if err != nil {
+			h.logger.Error("Can't convert cloudevent: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return err
+		}
+		if utils.IsEmpty(e.Channel) {
+			e.Channel = strings.TrimLeft(r.URL.Path, "/")
+		}
+		e.SetLogger(h.logger)
+		e.SetSpan(span)
+
+		if err := cp.HandleEvent(e); err != nil {
+			h.logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return nil
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(ce.Data()))
+	r.ContentLength = int64(len(ce.Data()))
+	r.Header.Set("Content-Type", ce.DataContentType())
+	return p.HandleHttpRequest(w, r)
+}
+
+// deliveries responds with delivery log of event by id or of last events by limit
+func (h *HttpInput) deliveries(w http.ResponseWriter, r *http.Request, deliveries *common.Deliveries) {
+
+	var list []*common.Delivery
+	id := r.URL.Query().Get("id")
+	if !utils.IsEmpty(id) {
+		list = deliveries.Find(id)
+	} else {
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil {
+			limit = 100
+		}
+		list = deliveries.Last(limit)
+	}
+
+	b, err := json.Marshal(list)
+	if err != nil {
+		h.logger.Error("Can't encode deliveries: %v", err)
+		http.Error(w, fmt.Sprintf("could not encode deliveries: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(b); err != nil {
+		h.logger.Error("Can't write response: %v", err)
+	}
+}
+
+func (h *HttpInput) Start(ctx context.Context, wg *sync.WaitGroup, outputs *common.Outputs) {
 
 	wg.Add(1)
 	go func(wg *sync.WaitGroup) {
@@ -87,57 +313,20 @@ func (h *HttpInput) Start(wg *sync.WaitGroup, outputs *common.Outputs) {
 		defer wg.Done()
 		h.logger.Info("Start http input...")
 
-		var caPool *x509.CertPool
-		var certificates []tls.Certificate
+		var certificates *httpCertificates
+		var clientAuth tls.ClientAuthType
 
 		if h.options.Tls {
 
-			// load certififcate
-			var cert []byte
-			if _, err := os.Stat(h.options.Cert); err == nil {
-
-				cert, err = os.ReadFile(h.options.Cert)
-				if err != nil {
-					h.logger.Panic(err)
-				}
-			} else {
-				cert = []byte(h.options.Cert)
-			}
-
-			// load key
-			var key []byte
-			if _, err := os.Stat(h.options.Key); err == nil {
-				key, err = os.ReadFile(h.options.Key)
-				if err != nil {
-					h.logger.Panic(err)
-				}
-			} else {
-				key = []byte(h.options.Key)
-			}
-
-			// make pair from certificate and pair
-			pair, err := tls.X509KeyPair(cert, key)
+			var err error
+			clientAuth, err = httpClientAuthType(h.options.ClientAuth)
 			if err != nil {
 				h.logger.Panic(err)
 			}
 
-			certificates = append(certificates, pair)
-
-			// load CA chain
-			var chain []byte
-			if _, err := os.Stat(h.options.Chain); err == nil {
-				chain, err = os.ReadFile(h.options.Chain)
-				if err != nil {
-					h.logger.Panic(err)
-				}
-			} else {
-				chain = []byte(h.options.Chain)
-			}
-
-			// make pool of chains
-			caPool = x509.NewCertPool()
-			if !caPool.AppendCertsFromPEM(chain) {
-				h.logger.Debug("CA chain is invalid")
+			certificates, err = newHttpCertificates(h.options.Cert, h.options.Key, h.options.Chain, clientAuth)
+			if err != nil {
+				h.logger.Panic(err)
 			}
 		}
 
@@ -151,6 +340,17 @@ func (h *HttpInput) Start(wg *sync.WaitGroup, outputs *common.Outputs) {
 			})
 		}
 
+		if !utils.IsEmpty(h.options.DeliveriesURL) {
+			mux.HandleFunc(h.options.DeliveriesURL, func(w http.ResponseWriter, r *http.Request) {
+				// delivery log isn't open even if input is made without LoadAuth
+				if h.options.Auth[h.options.DeliveriesURL] == nil || h.authorize(r, h.options.DeliveriesURL) != nil {
+					http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+					return
+				}
+				h.deliveries(w, r, outputs.Deliveries())
+			})
+		}
+
 		processors := h.getProcessors(h.processors, outputs)
 		for u, p := range processors {
 			h.processURL(u, mux, p)
@@ -164,28 +364,37 @@ func (h *HttpInput) Start(wg *sync.WaitGroup, outputs *common.Outputs) {
 		h.logger.Info("Http input is up. Listening...")
 
 		srv := &http.Server{
-			Handler:  mux,
-			ErrorLog: nil,
+			Handler:           mux,
+			ErrorLog:          nil,
+			ReadHeaderTimeout: time.Duration(h.options.ReadHeaderTimeout) * time.Second,
+			ReadTimeout:       time.Duration(h.options.ReadTimeout) * time.Second,
+			WriteTimeout:      time.Duration(h.options.WriteTimeout) * time.Second,
+			IdleTimeout:       time.Duration(h.options.IdleTimeout) * time.Second,
 		}
 
+		go func() {
+			<-ctx.Done()
+			h.logger.Info("Stop http input...")
+			// waits for active requests, the whole shutdown is bounded by the caller
+			if err := srv.Shutdown(context.Background()); err != nil {
+				h.logger.Error(err)
+			}
+		}()
+
 		if h.options.Tls {
 
-			srv.TLSConfig = &tls.Config{
-				Certificates:       certificates,
-				RootCAs:            caPool,
+			srv.TLSConfig = certificates.config(&tls.Config{
+				ClientAuth:         clientAuth,
 				InsecureSkipVerify: h.options.Insecure,
 				ServerName:         h.options.ServerName,
-			}
+			})
 
 			err = srv.ServeTLS(listener, "", "")
-			if err != nil {
-				h.logger.Panic(err)
-			}
 		} else {
 			err = srv.Serve(listener)
-			if err != nil {
-				h.logger.Panic(err)
-			}
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			h.logger.Panic(err)
 		}
 	}(wg)
 }
@@ -211,6 +420,10 @@ func (h *HttpInput) getProcessors(_ *common.Processors, _ *common.Outputs) map[s
 	h.setProcessor(m, h.options.GitlabURL, processor.GitlabProcessorType())
 	h.setProcessor(m, h.options.RancherURL, processor.RancherProcessorType())
 	h.setProcessor(m, h.options.DataDogURL, processor.DataDogProcessorType())
+	h.setProcessor(m, h.options.NewRelicURL, processor.NewRelicProcessorType())
+	h.setProcessor(m, h.options.GrafanaURL, processor.GrafanaProcessorType())
+	h.setProcessor(m, h.options.PagerDutyURL, processor.PagerDutyProcessorType())
+	h.setProcessor(m, h.options.OpsgenieURL, processor.OpsgenieProcessorType())
 	h.setProcessor(m, h.options.Site24x7URL, processor.Site24x7ProcessorType())
 	h.setProcessor(m, h.options.CloudflareURL, processor.CloudflareProcessorType())
 	h.setProcessor(m, h.options.GoogleURL, processor.GoogleProcessorType())
@@ -219,6 +432,15 @@ func (h *HttpInput) getProcessors(_ *common.Processors, _ *common.Outputs) map[s
 	h.setProcessor(m, h.options.VCenterURL, processor.VCenterProcessorType())
 	h.setProcessor(m, h.options.CustomJsonURL, processor.CustomJsonProcessorType())
 	h.setProcessor(m, h.options.TeamcityURL, processor.TeamcityProcessorType())
+
+	for url, name := range h.options.Paths {
+		hp, ok := h.processors.FindByName(name).(common.HttpProcessor)
+		if !ok || utils.IsEmpty(url) {
+			h.logger.Error("Http processor %s is not found for %s", name, url)
+			continue
+		}
+		m[url] = hp
+	}
 	return m
 }
 
@@ -229,5 +451,8 @@ func NewHttpInput(options HttpInputOptions, processors *common.Processors, obser
 		processors: processors,
 		logger:     observability.Logs(),
 		meter:      observability.Metrics(),
+		traces:     observability.Traces(),
+		paths:      newHttpLimiter(options.PathRateLimit, options.PathRateBurst),
+		clients:    newHttpLimiter(options.ClientRateLimit, options.ClientRateBurst),
 	}
 }
@@ -0,0 +1,218 @@
+package input
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/devopsext/events/common"
+	"github.com/devopsext/utils"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	HttpAuthHeader = "header"
+	HttpAuthHmac   = "hmac"
+	HttpAuthBearer = "bearer"
+	HttpAuthBasic  = "basic"
+)
+
+// HttpAuth verifies requests of url: shared secret in header (like X-Gitlab-Token), HMAC-SHA256 of body in header,
+// bearer token or basic auth. Signature is hex encoded unless encoding is base64, prefix like "sha256=" is trimmed.
+// Identities are allowed common names or SANs of client certificate, they are checked in addition to type if it's set
+type HttpAuth struct {
+	Type       string   `json:"type"`
+	Header     string   `json:"header,omitempty"`
+	Prefix     string   `json:"prefix,omitempty"`
+	Encoding   string   `json:"encoding,omitempty"`
+	Secret     string   `json:"secret,omitempty"`
+	User       string   `json:"user,omitempty"`
+	Password   string   `json:"password,omitempty"`
+	Identities []string `json:"identities,omitempty"`
+}
+
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func (a *HttpAuth) signature(body []byte) string {
+
+	mac := hmac.New(sha256.New, []byte(a.Secret))
+	mac.Write(body)
+	sum := mac.Sum(nil)
+
+	if strings.EqualFold(a.Encoding, "base64") {
+		return base64.StdEncoding.EncodeToString(sum)
+	}
+	return hex.EncodeToString(sum)
+}
+
+func (a *HttpAuth) header(r *http.Request) (string, error) {
+
+	if utils.IsEmpty(a.Header) {
+		return "", fmt.Errorf("%s auth header is not defined", a.Type)
+	}
+	value := r.Header.Get(a.Header)
+	if utils.IsEmpty(value) {
+		return "", fmt.Errorf("%s header is missing", a.Header)
+	}
+	return value, nil
+}
+
+func (a *HttpAuth) verifyIdentity(r *http.Request) error {
+
+	// only verified certificates are trusted, client auth could request them without verification
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return errors.New("verified client certificate is missing")
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	for _, id := range clientIdentities(cert) {
+		if !utils.IsEmpty(id) && utils.Contains(a.Identities, id) {
+			return nil
+		}
+	}
+	return fmt.Errorf("client %s is not allowed", cert.Subject.CommonName)
+}
+
+// Verify checks request, body is read for signature and restored for processor
+func (a *HttpAuth) Verify(r *http.Request) error {
+
+	if err := a.Validate(); err != nil {
+		return err
+	}
+
+	if len(a.Identities) > 0 {
+		if err := a.verifyIdentity(r); err != nil {
+			return err
+		}
+		if utils.IsEmpty(a.Type) {
+			return nil
+		}
+	}
+
+	switch strings.ToLower(a.Type) {
+	case HttpAuthHeader:
+		value, err := a.header(r)
+		if err != nil {
+			return err
+		}
+		if !secureEqual(value, a.Secret) {
+			return fmt.Errorf("%s header is invalid", a.Header)
+		}
+	case HttpAuthHmac:
+		value, err := a.header(r)
+		if err != nil {
+			return err
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		value = strings.TrimPrefix(strings.TrimSpace(value), a.Prefix)
+		expected := a.signature(body)
+		if !strings.EqualFold(a.Encoding, "base64") {
+			value = strings.ToLower(value)
+		}
+		if !secureEqual(value, expected) {
+			return errors.New("signature is invalid")
+		}
+	case HttpAuthBearer:
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			return errors.New("bearer token is missing")
+		}
+		if !secureEqual(strings.TrimSpace(token), a.Secret) {
+			return errors.New("bearer token is invalid")
+		}
+	case HttpAuthBasic:
+		user, password, ok := r.BasicAuth()
+		if !ok {
+			return errors.New("basic auth is missing")
+		}
+		// both are compared to keep time constant
+		userOk := secureEqual(user, a.User)
+		passwordOk := secureEqual(password, a.Password)
+		if !userOk || !passwordOk {
+			return errors.New("basic auth is invalid")
+		}
+	default:
+		return fmt.Errorf("auth type %s is not supported", a.Type)
+	}
+	return nil
+}
+
+// Validate checks that fields required by type are set, empty secret would let requests with empty header through
+func (a *HttpAuth) Validate() error {
+
+	if a == nil {
+		return errors.New("auth is not defined")
+	}
+	if utils.IsEmpty(a.Type) {
+		if len(a.Identities) > 0 {
+			return nil
+		}
+		return errors.New("auth type or identities are not defined")
+	}
+
+	switch strings.ToLower(a.Type) {
+	case HttpAuthHeader, HttpAuthHmac:
+		if utils.IsEmpty(a.Header) {
+			return fmt.Errorf("%s auth header is not defined", a.Type)
+		}
+		if utils.IsEmpty(a.Secret) {
+			return fmt.Errorf("%s auth secret is not defined", a.Type)
+		}
+	case HttpAuthBearer:
+		if utils.IsEmpty(a.Secret) {
+			return fmt.Errorf("%s auth secret is not defined", a.Type)
+		}
+	case HttpAuthBasic:
+		if utils.IsEmpty(a.User) || utils.IsEmpty(a.Password) {
+			return fmt.Errorf("%s auth user or password is not defined", a.Type)
+		}
+	default:
+		return fmt.Errorf("auth type %s is not supported", a.Type)
+	}
+	return nil
+}
+
+// LoadAuth merges auth from AuthConfig file or content, urls of pipeline config are kept, every auth is validated,
+// deliveries url must have auth
+func (o *HttpInputOptions) LoadAuth() error {
+
+	auth := make(map[string]*HttpAuth)
+	if !utils.IsEmpty(o.AuthConfig) {
+		var m map[string]*HttpAuth
+		if err := yaml.Unmarshal([]byte(common.Content(o.AuthConfig)), &m); err != nil {
+			return fmt.Errorf("http auth: %w", err)
+		}
+		for url, a := range m {
+			auth[url] = a
+		}
+	}
+	for url, a := range o.Auth {
+		auth[url] = a
+	}
+
+	for url, a := range auth {
+		if err := a.Validate(); err != nil {
+			return fmt.Errorf("http auth of %s: %w", url, err)
+		}
+	}
+	// delivery log exposes events and errors of outputs, so it isn't served without auth
+	if !utils.IsEmpty(o.DeliveriesURL) && auth[o.DeliveriesURL] == nil {
+		return fmt.Errorf("http deliveries url %s requires auth", o.DeliveriesURL)
+	}
+	o.Auth = auth
+	return nil
+}
@@ -0,0 +1,146 @@
+package input
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHttpAuthValidate(t *testing.T) {
+
+	tests := []struct {
+		name string
+		auth *HttpAuth
+		ok   bool
+	}{
+		{"nil", nil, false},
+		{"empty", &HttpAuth{}, false},
+		{"identities only", &HttpAuth{Identities: []string{"client"}}, true},
+		{"header", &HttpAuth{Type: "header", Header: "X-Gitlab-Token", Secret: "s"}, true},
+		{"header without secret", &HttpAuth{Type: "header", Header: "X-Gitlab-Token"}, false},
+		{"header without header", &HttpAuth{Type: "header", Secret: "s"}, false},
+		{"hmac without secret", &HttpAuth{Type: "hmac", Header: "X-Signature"}, false},
+		{"bearer", &HttpAuth{Type: "Bearer", Secret: "s"}, true},
+		{"bearer without secret", &HttpAuth{Type: "bearer"}, false},
+		{"basic", &HttpAuth{Type: "basic", User: "u", Password: "p"}, true},
+		{"basic without password", &HttpAuth{Type: "basic", User: "u"}, false},
+		{"basic without user", &HttpAuth{Type: "basic", Password: "p"}, false},
+		{"unknown", &HttpAuth{Type: "digest", Secret: "s"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.auth.Validate()
+			if (err == nil) != tt.ok {
+				t.Fatalf("Validate() = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestHttpInputOptionsLoadAuth(t *testing.T) {
+
+	o := HttpInputOptions{
+		AuthConfig: `
+/gitlab:
+  type: header
+  header: X-Gitlab-Token
+  secret: file
+/k8s:
+  type: bearer
+  secret: file
+`,
+		Auth: map[string]*HttpAuth{
+			"/k8s": {Type: "bearer", Secret: "pipeline"},
+		},
+	}
+	if err := o.LoadAuth(); err != nil {
+		t.Fatal(err)
+	}
+	if len(o.Auth) != 2 || o.Auth["/k8s"].Secret != "pipeline" || o.Auth["/gitlab"].Secret != "file" {
+		t.Fatalf("auth %+v", o.Auth)
+	}
+
+	// empty secret fails startup instead of accepting requests without header
+	o = HttpInputOptions{AuthConfig: "/gitlab:\n  type: header\n  header: X-Gitlab-Token\n"}
+	if err := o.LoadAuth(); err == nil || !strings.Contains(err.Error(), "/gitlab") {
+		t.Fatalf("LoadAuth() = %v, want error of /gitlab", err)
+	}
+	o = HttpInputOptions{Auth: map[string]*HttpAuth{"/basic": {Type: "basic", User: "u"}}}
+	if err := o.LoadAuth(); err == nil {
+		t.Fatal("LoadAuth() = nil, want error of basic auth without password")
+	}
+
+	// delivery log is served with auth only
+	o = HttpInputOptions{DeliveriesURL: "/deliveries"}
+	if err := o.LoadAuth(); err == nil || !strings.Contains(err.Error(), "/deliveries") {
+		t.Fatalf("LoadAuth() = %v, want error of /deliveries", err)
+	}
+	o = HttpInputOptions{DeliveriesURL: "/deliveries", Auth: map[string]*HttpAuth{"/deliveries": {Type: "bearer", Secret: "s"}}}
+	if err := o.LoadAuth(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHttpAuthVerify(t *testing.T) {
+
+	body := `{"kind":"Event"}`
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write([]byte(body))
+	sum := mac.Sum(nil)
+
+	header := &HttpAuth{Type: "header", Header: "X-Gitlab-Token", Secret: "token"}
+	hexHmac := &HttpAuth{Type: "hmac", Header: "X-Hub-Signature-256", Prefix: "sha256=", Secret: "key"}
+	b64Hmac := &HttpAuth{Type: "hmac", Header: "X-Signature", Encoding: "base64", Secret: "key"}
+	bearer := &HttpAuth{Type: "bearer", Secret: "token"}
+	basic := &HttpAuth{Type: "basic", User: "u", Password: "p"}
+	empty := &HttpAuth{Type: "header", Header: "X-Gitlab-Token"}
+
+	tests := []struct {
+		name    string
+		auth    *HttpAuth
+		headers map[string]string
+		basic   []string
+		ok      bool
+	}{
+		{"header", header, map[string]string{"X-Gitlab-Token": "token"}, nil, true},
+		{"header invalid", header, map[string]string{"X-Gitlab-Token": "other"}, nil, false},
+		{"header missing", header, nil, nil, false},
+		{"hmac hex", hexHmac, map[string]string{"X-Hub-Signature-256": "sha256=" + strings.ToUpper(hex.EncodeToString(sum))}, nil, true},
+		{"hmac invalid", hexHmac, map[string]string{"X-Hub-Signature-256": "sha256=00"}, nil, false},
+		{"hmac base64", b64Hmac, map[string]string{"X-Signature": base64.StdEncoding.EncodeToString(sum)}, nil, true},
+		{"bearer", bearer, map[string]string{"Authorization": "Bearer token"}, nil, true},
+		{"bearer invalid", bearer, map[string]string{"Authorization": "Bearer other"}, nil, false},
+		{"bearer missing", bearer, map[string]string{"Authorization": "token"}, nil, false},
+		{"basic", basic, nil, []string{"u", "p"}, true},
+		{"basic invalid", basic, nil, []string{"u", "x"}, false},
+		{"empty secret", empty, map[string]string{"X-Gitlab-Token": ""}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			r := httptest.NewRequest("POST", "/k8s", strings.NewReader(body))
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if tt.basic != nil {
+				r.SetBasicAuth(tt.basic[0], tt.basic[1])
+			}
+
+			err := tt.auth.Verify(r)
+			if (err == nil) != tt.ok {
+				t.Fatalf("Verify() = %v, want ok %v", err, tt.ok)
+			}
+			// body is restored for processor
+			if b, _ := io.ReadAll(r.Body); string(b) != body {
+				t.Fatalf("body %q is not restored", b)
+			}
+		})
+	}
+}
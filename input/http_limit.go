@@ -0,0 +1,104 @@
+package input
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// idle client limiters are forgotten, their buckets are full again anyway
+const httpLimiterIdle = 10 * time.Minute
+
+type httpLimiterItem struct {
+	limiter *rate.Limiter
+	seen    time.Time
+}
+
+// httpLimiter is a token bucket per key like path or client IP
+type httpLimiter struct {
+	limit rate.Limit
+	burst int
+	mutex sync.Mutex
+	items map[string]*httpLimiterItem
+	swept time.Time
+}
+
+func (l *httpLimiter) sweep(now time.Time) {
+
+	if now.Sub(l.swept) < httpLimiterIdle {
+		return
+	}
+	for key, item := range l.items {
+		if now.Sub(item.seen) > httpLimiterIdle {
+			delete(l.items, key)
+		}
+	}
+	l.swept = now
+}
+
+// Allow takes a token of key, otherwise it returns how long to wait for the next one
+func (l *httpLimiter) Allow(key string) (bool, time.Duration) {
+
+	if l == nil {
+		return true, 0
+	}
+
+	now := time.Now()
+
+	l.mutex.Lock()
+	l.sweep(now)
+	item, ok := l.items[key]
+	if !ok {
+		item = &httpLimiterItem{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.items[key] = item
+	}
+	item.seen = now
+	l.mutex.Unlock()
+
+	r := item.limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return false, time.Second
+	}
+	delay := r.DelayFrom(now)
+	if delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// newHttpLimiter makes limiter of requests per second, burst is the same as limit if it's not set
+func newHttpLimiter(limit, burst int) *httpLimiter {
+
+	if limit <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = limit
+	}
+	return &httpLimiter{
+		limit: rate.Limit(limit),
+		burst: burst,
+		items: make(map[string]*httpLimiterItem),
+		swept: time.Now(),
+	}
+}
+
+// retryAfter is Retry-After value in whole seconds
+func retryAfter(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
+
+// clientIP is a remote address of connection, forwarded headers are not trusted
+func clientIP(r *http.Request) string {
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,50 @@
+package input
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// httpResponse keeps status and body of processor, headers are set on the real response if it's defined
+type httpResponse struct {
+	w      http.ResponseWriter
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *httpResponse) Header() http.Header {
+
+	if r.w == nil {
+		return r.header
+	}
+	return r.w.Header()
+}
+
+func (r *httpResponse) WriteHeader(status int) {
+
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *httpResponse) Write(b []byte) (int, error) {
+
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(b)
+}
+
+func (r *httpResponse) flush() {
+
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.w.WriteHeader(r.status)
+	r.w.Write(r.body.Bytes())
+}
+
+func newHttpResponse(w http.ResponseWriter) *httpResponse {
+	return &httpResponse{w: w, header: make(http.Header)}
+}
@@ -0,0 +1,170 @@
+package input
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/devopsext/events/common"
+	"github.com/devopsext/utils"
+)
+
+// httpCertificates keeps server certificate and client CA pool, they are loaded again when files are changed,
+// verify requires the pool, otherwise client certificates would be verified by system roots
+type httpCertificates struct {
+	cert    string
+	key     string
+	chain   string
+	verify  bool
+	content string
+	mutex   sync.RWMutex
+	pair    *tls.Certificate
+	pool    *x509.CertPool
+}
+
+var httpClientAuthTypes = map[string]tls.ClientAuthType{
+	"":                   tls.NoClientCert,
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require":            tls.RequireAnyClientCert,
+	"verify-if-given":    tls.VerifyClientCertIfGiven,
+	"require-and-verify": tls.RequireAndVerifyClientCert,
+}
+
+func httpClientAuthType(s string) (tls.ClientAuthType, error) {
+
+	t, ok := httpClientAuthTypes[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return tls.NoClientCert, fmt.Errorf("client auth %s is not supported", s)
+	}
+	return t, nil
+}
+
+// httpClientAuthVerifies says whether client certificates are verified by CA chain
+func httpClientAuthVerifies(t tls.ClientAuthType) bool {
+	return t == tls.VerifyClientCertIfGiven || t == tls.RequireAndVerifyClientCert
+}
+
+// ValidateTLS checks client auth policy, policies which verify certificates need CA chain
+func (o *HttpInputOptions) ValidateTLS() error {
+
+	if !o.Tls {
+		return nil
+	}
+	t, err := httpClientAuthType(o.ClientAuth)
+	if err != nil {
+		return err
+	}
+	if httpClientAuthVerifies(t) && utils.IsEmpty(common.Content(o.Chain)) {
+		return fmt.Errorf("client auth %s requires CA chain", o.ClientAuth)
+	}
+	return nil
+}
+
+func isFile(s string) bool {
+
+	fi, err := os.Stat(s)
+	return err == nil && !fi.IsDir()
+}
+
+func (c *httpCertificates) Name() string {
+	return "http tls"
+}
+
+func (c *httpCertificates) isFile() bool {
+	return isFile(c.cert) || isFile(c.key) || isFile(c.chain)
+}
+
+// Reload loads certificates if any of them is changed, previous ones are kept on error
+func (c *httpCertificates) Reload() (bool, error) {
+
+	cert := common.Content(c.cert)
+	key := common.Content(c.key)
+	chain := common.Content(c.chain)
+
+	content := cert + key + chain
+	if content == c.content {
+		return false, nil
+	}
+
+	pair, err := tls.X509KeyPair([]byte(cert), []byte(key))
+	if err != nil {
+		return false, err
+	}
+
+	var pool *x509.CertPool
+	if !utils.IsEmpty(chain) {
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(chain)) {
+			return false, errors.New("CA chain is invalid")
+		}
+	}
+	if c.verify && pool == nil {
+		return false, errors.New("CA chain is required by client auth")
+	}
+
+	c.mutex.Lock()
+	c.pair = &pair
+	c.pool = pool
+	c.content = content
+	c.mutex.Unlock()
+	return true, nil
+}
+
+func (c *httpCertificates) current() (*tls.Certificate, *x509.CertPool) {
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.pair, c.pool
+}
+
+// config makes server config per connection, so reloaded certificates are used by new connections
+func (c *httpCertificates) config(base *tls.Config) *tls.Config {
+
+	cfg := base.Clone()
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+
+		pair, pool := c.current()
+		client := base.Clone()
+		client.Certificates = []tls.Certificate{*pair}
+		client.ClientCAs = pool
+		return client, nil
+	}
+	return cfg
+}
+
+func newHttpCertificates(cert, key, chain string, clientAuth tls.ClientAuthType) (*httpCertificates, error) {
+
+	c := &httpCertificates{
+		cert:   cert,
+		key:    key,
+		chain:  chain,
+		verify: httpClientAuthVerifies(clientAuth),
+	}
+	if _, err := c.Reload(); err != nil {
+		return nil, err
+	}
+	if c.isFile() {
+		common.RegisterReloadable(c)
+	}
+	return c, nil
+}
+
+// clientIdentities returns common name and SANs of certificate
+func clientIdentities(cert *x509.Certificate) []string {
+
+	r := []string{cert.Subject.CommonName}
+	r = append(r, cert.DNSNames...)
+	r = append(r, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		r = append(r, ip.String())
+	}
+	for _, u := range cert.URIs {
+		r = append(r, u.String())
+	}
+	return r
+}
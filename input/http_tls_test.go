@@ -0,0 +1,92 @@
+package input
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func testCertificate(t *testing.T) (string, string) {
+
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "events"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return string(cert), string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: k}))
+}
+
+func TestHttpInputOptionsValidateTLS(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		tls        bool
+		clientAuth string
+		chain      string
+		ok         bool
+	}{
+		{"no tls", false, "require-and-verify", "", true},
+		{"none", true, "none", "", true},
+		{"request", true, "request", "", true},
+		{"require", true, "require", "", true},
+		{"verify if given without chain", true, "verify-if-given", "", false},
+		{"require and verify without chain", true, "require-and-verify", "", false},
+		{"require and verify", true, "require-and-verify", "-----BEGIN CERTIFICATE-----", true},
+		{"unknown", true, "always", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := HttpInputOptions{Tls: tt.tls, ClientAuth: tt.clientAuth, Chain: tt.chain}
+			err := o.ValidateTLS()
+			if (err == nil) != tt.ok {
+				t.Fatalf("ValidateTLS() = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestHttpCertificatesVerifyRequiresChain(t *testing.T) {
+
+	cert, key := testCertificate(t)
+
+	if _, err := newHttpCertificates(cert, key, "", tls.RequireAndVerifyClientCert); err == nil {
+		t.Fatal("newHttpCertificates() = nil, want error without CA chain")
+	}
+	if _, err := newHttpCertificates(cert, key, "", tls.RequestClientCert); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := newHttpCertificates(cert, key, cert, tls.VerifyClientCertIfGiven)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, pool := c.current(); pool == nil {
+		t.Fatal("client CA pool is not loaded")
+	}
+}
@@ -0,0 +1,339 @@
+package input
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/devopsext/events/common"
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+)
+
+type KafkaInputOptions struct {
+	ClientID      string
+	Brokers       string
+	Topics        []string
+	GroupID       string
+	Offset        string
+	Processor     string
+	Channel       string
+	RetryDelay    int
+	SaslMechanism string
+	SaslUser      string
+	SaslPassword  string
+	Tls           bool
+	Insecure      bool
+	Cert          string
+	Key           string
+	Chain         string
+}
+
+// KafkaInput consumes topics by consumer group, messages are events like PubSub input has or raw payloads of named processor.
+// Offset is committed when message is processed, otherwise the group session is restarted and message is consumed again
+type KafkaInput struct {
+	options    KafkaInputOptions
+	group      sarama.ConsumerGroup
+	processors *common.Processors
+	logger     sreCommon.Logger
+	meter      sreCommon.Meter
+	traces     *sreCommon.Traces
+}
+
+type kafkaInputHandler struct {
+	input *KafkaInput
+}
+
+func (h *kafkaInputHandler) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *kafkaInputHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *kafkaInputHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+
+	for {
+		select {
+		case m, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			if err := h.input.process(session.Context(), m); err != nil {
+				return err
+			}
+			session.MarkMessage(m, "")
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
+
+// process returns error only if message should be consumed again, invalid messages are skipped
+func (k *KafkaInput) process(ctx context.Context, m *sarama.ConsumerMessage) error {
+
+	labels := make(map[string]string)
+	labels["topic"] = m.Topic
+	labels["input"] = "kafka"
+
+	requests := k.meter.Counter("kafka", "requests", "Count of all kafka input requests", labels, "input")
+	requests.Inc()
+
+	errors := k.meter.Counter("kafka", "errors", "Count of all kafka input errors", labels, "input")
+
+	headers := make(map[string]string)
+	for _, h := range m.Headers {
+		if h != nil {
+			headers[string(h.Key)] = string(h.Value)
+		}
+	}
+
+	var event *common.Event
+	traceID := common.TraceID(func(k string) string { return headers[k] }, "")
+
+	var err error
+	if utils.IsEmpty(k.options.Processor) {
+		event, err = k.decode(m, headers)
+		if event != nil && utils.IsEmpty(traceID) {
+			traceID = event.TraceID
+		}
+	}
+
+	span := common.StartSpan(k.traces, traceID, fmt.Sprintf("kafka %s", m.Topic))
+	defer span.Finish()
+	span.SetTag("offset", fmt.Sprintf("%s/%d/%d", m.Topic, m.Partition, m.Offset))
+
+	if err == nil {
+		if event != nil {
+			err = k.processEvent(event, span)
+		} else {
+			err = k.processRaw(ctx, m, headers, span)
+		}
+	}
+	if err == nil {
+		return nil
+	}
+
+	span.Error(err)
+	errors.Inc()
+
+	if isPayloadError(err) {
+		k.logger.Error("Kafka message %s/%d/%d is skipped: %v", m.Topic, m.Partition, m.Offset, err)
+		return nil
+	}
+	k.logger.Error("Kafka message %s/%d/%d is not processed: %v", m.Topic, m.Partition, m.Offset, err)
+	return err
+}
+
+// decode makes event of message, event ID is taken from header if message has no ID
+func (k *KafkaInput) decode(m *sarama.ConsumerMessage, headers map[string]string) (*common.Event, error) {
+
+	var event common.Event
+	if err := json.Unmarshal(m.Value, &event); err != nil {
+		return nil, &rawPayloadError{message: err.Error()}
+	}
+	if utils.IsEmpty(event.ID) {
+		event.ID = headers["event_id"]
+	}
+	return &event, nil
+}
+
+func (k *KafkaInput) processEvent(event *common.Event, span sreCommon.TracerSpan) error {
+
+	p := k.processors.Find(event.Type)
+	if p == nil {
+		k.logger.Debug("Kafka processor is not found for %s", event.Type)
+		return nil
+	}
+	event.SetLogger(k.logger)
+
+	child := common.StartChildSpan(k.traces, span, p.EventType())
+	defer child.Finish()
+	return handleEvent(p, event, child)
+}
+
+func (k *KafkaInput) processRaw(ctx context.Context, m *sarama.ConsumerMessage, headers map[string]string, span sreCommon.TracerSpan) error {
+
+	p, ok := k.processors.FindByName(k.options.Processor).(common.HttpProcessor)
+	if !ok {
+		return fmt.Errorf("kafka processor %s is not found", k.options.Processor)
+	}
+
+	channel := k.options.Channel
+	if utils.IsEmpty(channel) {
+		channel = m.Topic
+	}
+
+	child := common.StartChildSpan(k.traces, span, p.EventType())
+	defer child.Finish()
+	return handleRaw(ctx, p, channel, m.Value, headers, child)
+}
+
+func (k *KafkaInput) Start(ctx context.Context, wg *sync.WaitGroup, outputs *common.Outputs) {
+
+	wg.Add(1)
+	go func(wg *sync.WaitGroup) {
+
+		defer wg.Done()
+		k.logger.Info("Start kafka input...")
+
+		go func() {
+			for err := range k.group.Errors() {
+				k.logger.Error(err)
+			}
+		}()
+
+		delay := time.Duration(k.options.RetryDelay) * time.Second
+		if delay <= 0 {
+			delay = time.Second
+		}
+
+		handler := &kafkaInputHandler{input: k}
+		k.logger.Info("Kafka input is up. Listening...")
+
+		// consume returns on rebalance or when message is not processed, session is joined again
+		for {
+			err := k.group.Consume(ctx, k.options.Topics, handler)
+			if errors.Is(err, sarama.ErrClosedConsumerGroup) || ctx.Err() != nil {
+				break
+			}
+			if err != nil {
+				k.logger.Error(err)
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(delay):
+			}
+		}
+
+		k.logger.Info("Stop kafka input...")
+		if err := k.group.Close(); err != nil {
+			k.logger.Error(err)
+		}
+	}(wg)
+}
+
+// ValidateProcessor checks processor of raw payloads, otherwise messages are consumed again and again without processor.
+// Input without brokers is skipped, so its processor isn't checked
+func (o *KafkaInputOptions) ValidateProcessor(processors *common.Processors) error {
+
+	if utils.IsEmpty(o.Brokers) || utils.IsEmpty(o.Processor) {
+		return nil
+	}
+	if _, ok := processors.FindByName(o.Processor).(common.HttpProcessor); !ok {
+		return fmt.Errorf("kafka input processor %s is not found", o.Processor)
+	}
+	return nil
+}
+
+func kafkaInputTls(options KafkaInputOptions) (*tls.Config, error) {
+
+	cfg := &tls.Config{InsecureSkipVerify: options.Insecure}
+
+	if !utils.IsEmpty(options.Cert) && !utils.IsEmpty(options.Key) {
+		pair, err := tls.X509KeyPair([]byte(common.Content(options.Cert)), []byte(common.Content(options.Key)))
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{pair}
+	}
+
+	if !utils.IsEmpty(options.Chain) {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(common.Content(options.Chain))) {
+			return nil, errors.New("kafka CA chain is invalid")
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+func kafkaInputConfig(options KafkaInputOptions) (*sarama.Config, error) {
+
+	config := sarama.NewConfig()
+	if !utils.IsEmpty(options.ClientID) {
+		config.ClientID = options.ClientID
+	}
+	config.Consumer.Return.Errors = true
+	config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	if strings.EqualFold(options.Offset, "oldest") {
+		config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	}
+
+	if !utils.IsEmpty(options.SaslMechanism) {
+		mechanism := sarama.SASLMechanism(strings.ToUpper(options.SaslMechanism))
+		switch mechanism {
+		case sarama.SASLTypePlaintext:
+		case sarama.SASLTypeSCRAMSHA256, sarama.SASLTypeSCRAMSHA512:
+			config.Net.SASL.SCRAMClientGeneratorFunc = kafkaScramClientGenerator(mechanism)
+		default:
+			return nil, fmt.Errorf("kafka SASL mechanism %s is not supported", options.SaslMechanism)
+		}
+		config.Net.SASL.Enable = true
+		config.Net.SASL.Mechanism = mechanism
+		config.Net.SASL.User = options.SaslUser
+		config.Net.SASL.Password = options.SaslPassword
+	}
+
+	if options.Tls {
+		cfg, err := kafkaInputTls(options)
+		if err != nil {
+			return nil, err
+		}
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = cfg
+	}
+	return config, nil
+}
+
+func NewKafkaInput(options KafkaInputOptions, processors *common.Processors, observability *common.Observability) *KafkaInput {
+
+	logger := observability.Logs()
+
+	var topics []string
+	for _, t := range options.Topics {
+		if !utils.IsEmpty(strings.TrimSpace(t)) {
+			topics = append(topics, strings.TrimSpace(t))
+		}
+	}
+	options.Topics = topics
+
+	if utils.IsEmpty(options.Brokers) || len(options.Topics) == 0 || utils.IsEmpty(options.GroupID) {
+		logger.Debug("Kafka input brokers, topics or group ID is not defined. Skipped")
+		return nil
+	}
+
+	if err := options.ValidateProcessor(processors); err != nil {
+		logger.Error(err)
+		return nil
+	}
+
+	config, err := kafkaInputConfig(options)
+	if err != nil {
+		logger.Error(err)
+		return nil
+	}
+
+	group, err := sarama.NewConsumerGroup(strings.Split(options.Brokers, ","), options.GroupID, config)
+	if err != nil {
+		logger.Error(err)
+		return nil
+	}
+
+	return &KafkaInput{
+		options:    options,
+		group:      group,
+		processors: processors,
+		logger:     logger,
+		meter:      observability.Metrics(),
+		traces:     observability.Traces(),
+	}
+}
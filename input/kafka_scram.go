@@ -0,0 +1,47 @@
+package input
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+
+	"github.com/IBM/sarama"
+	"github.com/xdg-go/scram"
+)
+
+// kafkaScramClient is a SCRAM client of sarama which uses conversation of xdg-go/scram as sarama examples do
+type kafkaScramClient struct {
+	*scram.Client
+	*scram.ClientConversation
+	scram.HashGeneratorFcn
+}
+
+func (c *kafkaScramClient) Begin(userName, password, authzID string) error {
+
+	client, err := c.HashGeneratorFcn.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.Client = client
+	c.ClientConversation = client.NewConversation()
+	return nil
+}
+
+func (c *kafkaScramClient) Step(challenge string) (string, error) {
+	return c.ClientConversation.Step(challenge)
+}
+
+func (c *kafkaScramClient) Done() bool {
+	return c.ClientConversation.Done()
+}
+
+// kafkaScramClientGenerator returns sarama generator of SCRAM clients for SCRAM-SHA-256 or SCRAM-SHA-512 mechanism
+func kafkaScramClientGenerator(mechanism sarama.SASLMechanism) func() sarama.SCRAMClient {
+
+	h := scram.HashGeneratorFcn(sha256.New)
+	if mechanism == sarama.SASLTypeSCRAMSHA512 {
+		h = sha512.New
+	}
+	return func() sarama.SCRAMClient {
+		return &kafkaScramClient{HashGeneratorFcn: h}
+	}
+}
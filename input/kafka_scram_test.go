@@ -0,0 +1,96 @@
+package input
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/IBM/sarama"
+)
+
+// conversation of RFC 7677
+const (
+	scramNonce       = "rOprNGfwEbeRWgbNEkqO"
+	scramServerFirst = "r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096"
+	scramClientFinal = "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ="
+	scramServerFinal = "v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4="
+)
+
+func scramClient(t *testing.T) sarama.SCRAMClient {
+
+	t.Helper()
+	c := kafkaScramClientGenerator(sarama.SASLTypeSCRAMSHA256)()
+	if err := c.Begin("user", "pencil", ""); err != nil {
+		t.Fatal(err)
+	}
+	// conversation takes nonce of client on first step
+	c.(*kafkaScramClient).Client.WithNonceGenerator(func() string { return scramNonce })
+	return c
+}
+
+func TestKafkaScramClient(t *testing.T) {
+
+	c := scramClient(t)
+	first, err := c.Step("")
+	if err != nil || first != "n,,n=user,r="+scramNonce {
+		t.Fatalf("client first %q: %v", first, err)
+	}
+	final, err := c.Step(scramServerFirst)
+	if err != nil || final != scramClientFinal {
+		t.Fatalf("client final %q: %v", final, err)
+	}
+	if c.Done() {
+		t.Fatal("conversation is done before server final")
+	}
+	if _, err := c.Step(scramServerFinal); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Done() {
+		t.Fatal("conversation is not done")
+	}
+}
+
+func TestKafkaScramClientErrors(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		serverFirst string
+		serverFinal string
+		err         string
+	}{
+		{"nonce of other client", strings.Replace(scramServerFirst, scramNonce, "other", 1), "", "nonce"},
+		{"bad salt", strings.Replace(scramServerFirst, "s=W22", "s=!22", 1), "", "base64"},
+		{"bad iterations", strings.Replace(scramServerFirst, "i=4096", "i=0", 1), "", "iterations"},
+		{"server error", scramServerFirst, "e=invalid-proof", "invalid-proof"},
+		{"bad server signature", scramServerFirst, "v=AAAA", "validation"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := scramClient(t)
+			c.Step("")
+			_, err := c.Step(tt.serverFirst)
+			if err == nil {
+				_, err = c.Step(tt.serverFinal)
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error %v, want %s", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestKafkaInputConfigSasl(t *testing.T) {
+
+	for _, mechanism := range []string{"plain", "SCRAM-SHA-256", "scram-sha-512"} {
+		config, err := kafkaInputConfig(KafkaInputOptions{SaslMechanism: mechanism, SaslUser: "u", SaslPassword: "p"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := config.Validate(); err != nil {
+			t.Fatalf("%s config: %v", mechanism, err)
+		}
+	}
+	if _, err := kafkaInputConfig(KafkaInputOptions{SaslMechanism: "GSSAPI"}); err == nil {
+		t.Fatal("GSSAPI is accepted")
+	}
+}
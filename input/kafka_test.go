@@ -0,0 +1,192 @@
+package input
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/devopsext/events/common"
+	sre "github.com/devopsext/sre/common"
+)
+
+// flakyProcessor answers raw requests with 503 till it fails given times, bad requests are rejected with 400
+type flakyProcessor struct {
+	mutex sync.Mutex
+	fails int
+	calls int
+}
+
+func (p *flakyProcessor) EventType() string {
+	return "TestEvent"
+}
+
+func (p *flakyProcessor) HandleEvent(e *common.Event) error {
+	return nil
+}
+
+func (p *flakyProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.calls++
+
+	if r.Header.Get("bad") != "" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return nil
+	}
+	if p.calls <= p.fails {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return nil
+	}
+	_, err := w.Write([]byte("OK"))
+	return err
+}
+
+// kafkaSession keeps marked offsets, other methods of session are not used by handler
+type kafkaSession struct {
+	sarama.ConsumerGroupSession
+	ctx    context.Context
+	marked []int64
+}
+
+func (s *kafkaSession) Context() context.Context {
+	return s.ctx
+}
+
+func (s *kafkaSession) MarkMessage(m *sarama.ConsumerMessage, _ string) {
+	s.marked = append(s.marked, m.Offset)
+}
+
+type kafkaClaim struct {
+	sarama.ConsumerGroupClaim
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *kafkaClaim) Messages() <-chan *sarama.ConsumerMessage {
+	return c.messages
+}
+
+func testKafkaInput(processor string, p common.Processor) *KafkaInput {
+
+	obs := common.NewObservability(sre.NewLogs(), sre.NewTraces(), sre.NewMetrics(), sre.NewEvents())
+	processors := common.NewProcessors()
+	if p != nil {
+		processors.AddNamed("raw", p)
+	}
+	return &KafkaInput{
+		options:    KafkaInputOptions{Brokers: "localhost:9092", Processor: processor},
+		processors: processors,
+		logger:     obs.Logs(),
+		meter:      obs.Metrics(),
+		traces:     obs.Traces(),
+	}
+}
+
+func kafkaMessage(offset int64, value string, headers ...*sarama.RecordHeader) *sarama.ConsumerMessage {
+	return &sarama.ConsumerMessage{Topic: "events", Partition: 0, Offset: offset, Value: []byte(value), Headers: headers}
+}
+
+// consume passes messages to handler like consumer group does and returns offsets marked by handler
+func consume(t *testing.T, k *KafkaInput, messages ...*sarama.ConsumerMessage) ([]int64, error) {
+
+	t.Helper()
+	claim := &kafkaClaim{messages: make(chan *sarama.ConsumerMessage, len(messages))}
+	for _, m := range messages {
+		claim.messages <- m
+	}
+	close(claim.messages)
+
+	session := &kafkaSession{ctx: context.Background()}
+	handler := &kafkaInputHandler{input: k}
+	err := handler.ConsumeClaim(session, claim)
+	return session.marked, err
+}
+
+func TestKafkaInputProcess(t *testing.T) {
+
+	bad := &sarama.RecordHeader{Key: []byte("bad"), Value: []byte("1")}
+
+	tests := []struct {
+		name      string
+		processor string
+		fails     int
+		message   *sarama.ConsumerMessage
+		err       bool
+	}{
+		{"event", "", 0, kafkaMessage(1, `{"type":"TestEvent","data":{}}`), false},
+		{"event of unknown type", "", 0, kafkaMessage(1, `{"type":"Unknown"}`), false},
+		{"invalid event", "", 0, kafkaMessage(1, "{"), false},
+		{"raw", "raw", 0, kafkaMessage(1, `{}`), false},
+		{"raw rejected", "raw", 0, kafkaMessage(1, `{}`, bad), false},
+		{"raw failed", "raw", 1, kafkaMessage(1, `{}`), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := testKafkaInput(tt.processor, &flakyProcessor{fails: tt.fails})
+			if err := k.process(context.Background(), tt.message); (err != nil) != tt.err {
+				t.Fatalf("process() = %v, want error %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestKafkaInputHandlerMarksProcessed(t *testing.T) {
+
+	bad := &sarama.RecordHeader{Key: []byte("bad"), Value: []byte("1")}
+	k := testKafkaInput("raw", &flakyProcessor{})
+
+	// invalid payload is skipped, so its offset is marked as well
+	marked, err := consume(t, k, kafkaMessage(1, `{}`), kafkaMessage(2, `{}`, bad), kafkaMessage(3, `{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(marked) != 3 || marked[0] != 1 || marked[1] != 2 || marked[2] != 3 {
+		t.Fatalf("marked %v, want [1 2 3]", marked)
+	}
+}
+
+func TestKafkaInputHandlerRetriesOnError(t *testing.T) {
+
+	p := &flakyProcessor{fails: 1}
+	k := testKafkaInput("raw", p)
+
+	// failed message isn't marked and next messages aren't processed till session is joined again
+	marked, err := consume(t, k, kafkaMessage(1, `{}`), kafkaMessage(2, `{}`))
+	if err == nil || len(marked) != 0 || p.calls != 1 {
+		t.Fatalf("consume() = %v, %v after %d calls, want error without marks", marked, err, p.calls)
+	}
+
+	marked, err = consume(t, k, kafkaMessage(1, `{}`), kafkaMessage(2, `{}`))
+	if err != nil || len(marked) != 2 || marked[0] != 1 {
+		t.Fatalf("consume() = %v, %v, want [1 2]", marked, err)
+	}
+}
+
+func TestKafkaInputOptionsValidateProcessor(t *testing.T) {
+
+	processors := common.NewProcessors()
+	processors.AddNamed("raw", &flakyProcessor{})
+
+	tests := []struct {
+		name    string
+		options KafkaInputOptions
+		ok      bool
+	}{
+		{"events", KafkaInputOptions{Brokers: "localhost:9092"}, true},
+		{"named processor", KafkaInputOptions{Brokers: "localhost:9092", Processor: "raw"}, true},
+		{"processor type", KafkaInputOptions{Brokers: "localhost:9092", Processor: "Test"}, true},
+		{"unknown processor", KafkaInputOptions{Brokers: "localhost:9092", Processor: "unknown"}, false},
+		{"input without brokers", KafkaInputOptions{Processor: "unknown"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.options.ValidateProcessor(processors); (err == nil) != tt.ok {
+				t.Fatalf("ValidateProcessor() = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package input
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
@@ -21,14 +22,14 @@ type NomadInputOptions struct {
 type NomadInput struct {
 	options    NomadInputOptions
 	client     *nomad.Client
-	ctx        context.Context
 	processors *common.Processors
 	eventer    sreCommon.Eventer
 	logger     sreCommon.Logger
 	meter      sreCommon.Meter
+	traces     *sreCommon.Traces
 }
 
-func (n *NomadInput) Start(wg *sync.WaitGroup, outputs *common.Outputs) {
+func (n *NomadInput) Start(ctx context.Context, wg *sync.WaitGroup, outputs *common.Outputs) {
 
 	q := &nomad.QueryOptions{}
 	regions, err := n.client.Regions().List()
@@ -62,8 +63,11 @@ func (n *NomadInput) Start(wg *sync.WaitGroup, outputs *common.Outputs) {
 			for {
 				// Use absurdly high index num to query only last events (no backlog)
 				// math.MaxUint64 doesn't work, MaxUint32 does work, but its lesser number
-				eventCh, err := stream.Stream(n.ctx, topics, 9999999999, q)
+				eventCh, err := stream.Stream(ctx, topics, 9999999999, q)
 				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
 					n.logger.Error(err)
 					time.Sleep(5 * time.Second)
 					continue
@@ -72,6 +76,9 @@ func (n *NomadInput) Start(wg *sync.WaitGroup, outputs *common.Outputs) {
 				chanOk := true
 				for {
 					select {
+					case <-ctx.Done():
+						n.logger.Info("Stop nomad input for %s region %s", n.options.Address, region)
+						return
 					case es, ok := <-eventCh:
 						if !ok {
 							n.logger.Error("Stream channel closed, restarting")
@@ -94,11 +101,17 @@ func (n *NomadInput) Start(wg *sync.WaitGroup, outputs *common.Outputs) {
 							requests := n.meter.Counter("nomad", "requests", "Count of all nomad input requests", labels, "input")
 							errors := n.meter.Counter("nomad", "errors", "Count of all nomad input errors", labels, "input")
 							requests.Inc()
-							err := p.ProcessEvent(ne)
+
+							span := common.StartSpan(n.traces, "", fmt.Sprintf("nomad %s", region))
+							child := common.StartChildSpan(n.traces, span, p.EventType())
+							err := p.ProcessEvent(ne, common.SpanWithIngestion(child, common.NewIngestion()))
 							if err != nil {
+								child.Error(err)
 								n.logger.Error(err)
 								errors.Inc()
 							}
+							child.Finish()
+							span.Finish()
 						}
 					}
 					if !chanOk {
@@ -131,9 +144,9 @@ func NewNomadInput(options NomadInputOptions, processors *common.Processors, obs
 		options:    options,
 		client:     client,
 		processors: processors,
-		ctx:        context.Background(),
 		eventer:    observability.Events(),
 		logger:     observability.Logs(),
 		meter:      observability.Metrics(),
+		traces:     observability.Traces(),
 	}
 }
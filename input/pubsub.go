@@ -3,6 +3,7 @@ package input
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"sync"
 
@@ -13,23 +14,27 @@ import (
 	"google.golang.org/api/option"
 )
 
+// PubSubInputOptions Processor is a name of processor which parses raw messages like Google monitoring notifications,
+// messages are events if it's empty. Channel of raw messages is subscription if it's empty
 type PubSubInputOptions struct {
 	Credentials  string
 	ProjectID    string
 	Subscription string
+	Processor    string
+	Channel      string
 }
 
 type PubSubInput struct {
 	options    PubSubInputOptions
 	client     *pubsub.Client
-	ctx        context.Context
 	processors *common.Processors
 	eventer    sreCommon.Eventer
 	logger     sreCommon.Logger
 	meter      sreCommon.Meter
+	traces     *sreCommon.Traces
 }
 
-func (ps *PubSubInput) Start(wg *sync.WaitGroup, outputs *common.Outputs) {
+func (ps *PubSubInput) Start(ctx context.Context, wg *sync.WaitGroup, outputs *common.Outputs) {
 	wg.Add(1)
 	go func(wg *sync.WaitGroup) {
 		defer wg.Done()
@@ -38,7 +43,8 @@ func (ps *PubSubInput) Start(wg *sync.WaitGroup, outputs *common.Outputs) {
 		sub := ps.client.Subscription(ps.options.Subscription)
 		ps.logger.Info("PubSub input is up. Listening...")
 
-		err := sub.Receive(ps.ctx, func(ctx context.Context, m *pubsub.Message) {
+		// receive returns when ctx is cancelled and all running callbacks are done
+		err := sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
 
 			labels := make(map[string]string)
 			labels["subscription"] = sub.String()
@@ -49,32 +55,96 @@ func (ps *PubSubInput) Start(wg *sync.WaitGroup, outputs *common.Outputs) {
 
 			requests.Inc()
 
-			var event common.Event
-			if err := json.Unmarshal(m.Data, &event); err != nil {
-				errors.Inc()
+			err := ps.process(ctx, m)
+			if err == nil {
+				m.Ack()
 				return
 			}
-
-			p := ps.processors.Find(event.Type)
-			if p == nil {
-				ps.logger.Debug("PubSub processor is not found for %s", event.Type)
+			errors.Inc()
+
+			// invalid payload is not fixed by delivering it again, so it's acked
+			if isPayloadError(err) {
+				skipped := ps.meter.Counter("pubsub", "skipped", "Count of all pubsub input messages skipped as invalid", labels, "input")
+				skipped.Inc()
+				ps.logger.Error("PubSub message %s is skipped: %v", m.ID, err)
+				m.Ack()
 				return
 			}
 
-			event.SetLogger(ps.logger)
-
-			err := p.HandleEvent(&event)
-			if err != nil {
-				errors.Inc()
-			}
+			// message is delivered again if it's not processed, subscription dead letter policy limits attempts
+			ps.logger.Error("PubSub message %s is not processed: %v", m.ID, err)
+			m.Nack()
 		})
 
 		if err != nil {
 			ps.logger.Error(err)
 		}
+
+		ps.logger.Info("Stop pubsub input...")
+		if err := ps.client.Close(); err != nil {
+			ps.logger.Error(err)
+		}
 	}(wg)
 }
 
+func (ps *PubSubInput) process(ctx context.Context, m *pubsub.Message) error {
+
+	get := func(k string) string { return m.Attributes[k] }
+
+	if !utils.IsEmpty(ps.options.Processor) {
+		p, ok := ps.processors.FindByName(ps.options.Processor).(common.HttpProcessor)
+		if !ok {
+			return fmt.Errorf("pubsub processor %s is not found", ps.options.Processor)
+		}
+
+		channel := ps.options.Channel
+		if utils.IsEmpty(channel) {
+			channel = ps.options.Subscription
+		}
+
+		span := common.StartSpan(ps.traces, common.TraceID(get, ""), fmt.Sprintf("pubsub %s", ps.options.Subscription))
+		defer span.Finish()
+
+		child := common.StartChildSpan(ps.traces, span, p.EventType())
+		defer child.Finish()
+
+		err := handleRaw(ctx, p, channel, m.Data, m.Attributes, child)
+		if err != nil {
+			child.Error(err)
+		}
+		return err
+	}
+
+	var event common.Event
+	if err := json.Unmarshal(m.Data, &event); err != nil {
+		return &rawPayloadError{message: err.Error()}
+	}
+
+	p := ps.processors.Find(event.Type)
+	if p == nil {
+		ps.logger.Debug("PubSub processor is not found for %s", event.Type)
+		return nil
+	}
+
+	event.SetLogger(ps.logger)
+
+	traceID := common.TraceID(get, "")
+	if utils.IsEmpty(traceID) {
+		traceID = event.TraceID
+	}
+	span := common.StartSpan(ps.traces, traceID, fmt.Sprintf("pubsub %s", ps.options.Subscription))
+	defer span.Finish()
+
+	child := common.StartChildSpan(ps.traces, span, p.EventType())
+	defer child.Finish()
+
+	err := handleEvent(p, &event, child)
+	if err != nil {
+		child.Error(err)
+	}
+	return err
+}
+
 func NewPubSubInput(options PubSubInputOptions, processors *common.Processors, observability *common.Observability) *PubSubInput {
 	logger := observability.Logs()
 	if utils.IsEmpty(options.Credentials) || utils.IsEmpty(options.ProjectID) || utils.IsEmpty(options.Subscription) {
@@ -99,10 +169,10 @@ func NewPubSubInput(options PubSubInputOptions, processors *common.Processors, o
 	return &PubSubInput{
 		options:    options,
 		client:     client,
-		ctx:        ctx,
 		processors: processors,
 		eventer:    observability.Events(),
 		logger:     observability.Logs(),
 		meter:      observability.Metrics(),
+		traces:     observability.Traces(),
 	}
 }
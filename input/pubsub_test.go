@@ -0,0 +1,88 @@
+package input
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/devopsext/events/common"
+	sre "github.com/devopsext/sre/common"
+)
+
+// statusProcessor answers raw requests with status
+type statusProcessor struct {
+	status int
+}
+
+func (p *statusProcessor) EventType() string {
+	return "TestEvent"
+}
+
+func (p *statusProcessor) HandleEvent(e *common.Event) error {
+	return nil
+}
+
+func (p *statusProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
+	if p.status != http.StatusOK {
+		http.Error(w, http.StatusText(p.status), p.status)
+		return nil
+	}
+	_, err := w.Write([]byte("OK"))
+	return err
+}
+
+func testPubSubInput(processor string, p common.Processor) *PubSubInput {
+
+	obs := common.NewObservability(sre.NewLogs(), sre.NewTraces(), sre.NewMetrics(), sre.NewEvents())
+	processors := common.NewProcessors()
+	if p != nil {
+		processors.AddNamed(processor, p)
+	}
+	return &PubSubInput{
+		options:    PubSubInputOptions{Subscription: "events", Processor: processor},
+		processors: processors,
+		logger:     obs.Logs(),
+		meter:      obs.Metrics(),
+		traces:     obs.Traces(),
+	}
+}
+
+func TestPubSubInputPayloadErrors(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		processor string
+		status    int
+		data      string
+		payload   bool
+		err       bool
+	}{
+		{"invalid event", "", 0, "{", true, true},
+		{"unknown event type", "", 0, `{"type":"Unknown"}`, false, false},
+		{"raw processed", "raw", http.StatusOK, `{}`, false, false},
+		{"raw rejected", "raw", http.StatusBadRequest, `{}`, true, true},
+		{"raw too large", "raw", http.StatusRequestEntityTooLarge, `{}`, true, true},
+		{"raw failed", "raw", http.StatusServiceUnavailable, `{}`, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			var p common.Processor
+			if tt.processor != "" {
+				p = &statusProcessor{status: tt.status}
+			}
+			ps := testPubSubInput(tt.processor, p)
+
+			err := ps.process(context.Background(), &pubsub.Message{ID: "1", Data: []byte(tt.data)})
+			if (err != nil) != tt.err {
+				t.Fatalf("process() = %v, want error %v", err, tt.err)
+			}
+			// payload errors are acked, others are nacked to be delivered again
+			if isPayloadError(err) != tt.payload {
+				t.Fatalf("isPayloadError(%v) = %v, want %v", err, isPayloadError(err), tt.payload)
+			}
+		})
+	}
+}
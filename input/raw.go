@@ -0,0 +1,74 @@
+package input
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/devopsext/events/common"
+	sreCommon "github.com/devopsext/sre/common"
+)
+
+// rawPayloadError is returned if processor rejects payload as invalid, such message is not delivered again
+type rawPayloadError struct {
+	status  int
+	message string
+}
+
+func (e *rawPayloadError) Error() string {
+	return fmt.Sprintf("payload is rejected with %d: %s", e.status, e.message)
+}
+
+func isPayloadError(err error) bool {
+
+	var e *rawPayloadError
+	return errors.As(err, &e)
+}
+
+// handleRaw passes message payload to http processor like a request to /channel, headers are message headers or attributes.
+// Error is returned if processor fails or outputs reject its events, so message could be delivered again
+func handleRaw(ctx context.Context, p common.HttpProcessor, channel string, body []byte, headers map[string]string, span sreCommon.TracerSpan) error {
+
+	admission := common.NewAdmission()
+	ingestion := common.NewIngestion()
+	span = common.SpanWithIngestion(common.SpanWithAdmission(span, admission), ingestion)
+	ctx = common.ContextWithIngestion(common.ContextWithSpan(ctx, span), ingestion)
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, "/"+strings.TrimLeft(channel, "/"), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+
+	response := newHttpResponse(nil)
+	err = p.HandleHttpRequest(response, r)
+
+	message := strings.TrimSpace(response.body.String())
+	if response.status >= http.StatusBadRequest && response.status < http.StatusInternalServerError {
+		return &rawPayloadError{status: response.status, message: message}
+	}
+	if err != nil {
+		return err
+	}
+	if response.status >= http.StatusInternalServerError {
+		return fmt.Errorf("%s processor responded %d: %s", p.EventType(), response.status, message)
+	}
+	return admission.Err()
+}
+
+// handleEvent passes decoded event to its processor, event keeps its ID or gets ID of ingestion, error is returned if outputs reject it
+func handleEvent(p common.Processor, e *common.Event, span sreCommon.TracerSpan) error {
+
+	admission := common.NewAdmission()
+	e.SetSpan(common.SpanWithIngestion(common.SpanWithAdmission(span, admission), common.NewIngestion()))
+	if err := p.HandleEvent(e); err != nil {
+		return err
+	}
+	return admission.Err()
+}
@@ -57,6 +57,7 @@ type VCenterInput struct {
 	processors   *common.Processors
 	logger       sreCommon.Logger
 	meter        sreCommon.Meter
+	traces       *sreCommon.Traces
 	wg           waitgroup.WaitGroup
 	ceAttributes map[string]string // custom cloudevent context attributes added to events
 }
@@ -249,7 +250,7 @@ func fullPath(file, dir string) string {
 // closeWithErrCapture runs function and on error return error by argument including the given error (usually
 // from caller function).
 func closeWithErrCapture(err *error, closer io.Closer, errMsg string) {
-	*err = errors.Wrapf(closer.Close(), errMsg)
+	*err = errors.Wrap(closer.Close(), errMsg)
 }
 
 func getDetails(event vctypes.BaseEvent) vcEventInfo {
@@ -378,10 +379,11 @@ func NewVCenterInput(options VCenterInputOptions, processors *common.Processors,
 		processors:   processors,
 		logger:       observability.Logs(),
 		meter:        observability.Metrics(),
+		traces:       observability.Traces(),
 		ceAttributes: ceAttributes,
 	}
 }
-func (vc *VCenterInput) Start(wg *sync.WaitGroup, _ *common.Outputs) {
+func (vc *VCenterInput) Start(ctx context.Context, wg *sync.WaitGroup, _ *common.Outputs) {
 	var (
 		begin *time.Time
 		cp    *checkpoint
@@ -393,6 +395,7 @@ func (vc *VCenterInput) Start(wg *sync.WaitGroup, _ *common.Outputs) {
 	go func(wg *sync.WaitGroup) {
 		defer wg.Done()
 		vc.logger.Info("Start vcenter input...")
+		vc.ctx = ctx
 
 		// begin of event stream defaults to current vCenter time (UTC)
 		begin, err = methods.GetCurrentTime(vc.ctx, vc.client)
@@ -556,19 +559,14 @@ func (vc *VCenterInput) processEvents(vcBaseEvents []vctypes.BaseEvent) (*vcLast
 
 		vc.logger.Debug("invoking processor", "eventID", ce.ID())
 
-		jsonBytes, err := json.Marshal(ce)
+		curevent, err := common.NewEventFromCloudEvent(ce)
 		if err != nil {
-			vc.logger.Error("skipping cause couldnt Marchall", "jsonBytes", jsonBytes, "error", err)
+			vc.logger.Error("skipping cause couldnt convert CloudEvent", "event", e, "error", err)
 			errCount++
 			continue
 		}
-
-		curevent := &common.Event{
-			Data:    string(jsonBytes),
-			Channel: vc.client.URL().Hostname(),
-			Type:    "VCenterEvent",
-		}
-		curevent.SetTime(time.Now().UTC())
+		curevent.Channel = vc.client.URL().Hostname()
+		curevent.Type = "VCenterEvent"
 		curevent.SetLogger(vc.logger)
 
 		p := vc.processors.Find(curevent.Type)
@@ -584,7 +582,17 @@ func (vc *VCenterInput) processEvents(vcBaseEvents []vctypes.BaseEvent) (*vcLast
 		requests := vc.meter.Counter("vcenter", "requests", "Count of all vc input requests", labels, "input")
 		requests.Inc()
 
+		span := common.StartSpan(vc.traces, "", fmt.Sprintf("vcenter %s", curevent.Channel))
+		span.SetTag("subject", curevent.Subject)
+		child := common.StartChildSpan(vc.traces, span, p.EventType())
+		curevent.SetSpan(common.SpanWithIngestion(child, common.NewIngestion()))
+
 		err = p.HandleEvent(curevent)
+		if err != nil {
+			child.Error(err)
+		}
+		child.Finish()
+		span.Finish()
 		if err != nil {
 			vc.logger.Debug("some problems with %v", err)
 			errors := vc.meter.Counter("vcenter", "errors", "Count of all vc input errors", labels, "input")
@@ -1,6 +1,7 @@
 package output
 
 import (
+	"context"
 	"net"
 	"strings"
 	"sync"
@@ -12,21 +13,22 @@ import (
 )
 
 type CollectorOutputOptions struct {
-	Address string
-	Message string
+	Instance string
+	Address  string
+	Message  string
 }
 
 type CollectorOutput struct {
 	wg         *sync.WaitGroup
 	options    CollectorOutputOptions
 	connection *net.UDPConn
-	message    *toolsRender.TextTemplate
+	message    *common.Template
 	logger     sreCommon.Logger
 	meter      sreCommon.Meter
 }
 
 func (c *CollectorOutput) Name() string {
-	return "Collector"
+	return common.OutputName(c.options.Instance, "Collector")
 }
 
 func (c *CollectorOutput) Send(event *common.Event) {
@@ -34,44 +36,58 @@ func (c *CollectorOutput) Send(event *common.Event) {
 	c.wg.Add(1)
 	go func() {
 		defer c.wg.Done()
-
-		if event == nil {
-			c.logger.Debug("Event is empty")
-			return
-		}
-
-		b, err := c.message.RenderObject(event)
-		if err != nil {
-			c.logger.Error(err)
-			return
-		}
-
-		message := strings.TrimSpace(string(b))
-		if utils.IsEmpty(message) {
-			c.logger.Debug("Collector message is empty")
-			return
-		}
-
-		labels := make(map[string]string)
-		labels["event_channel"] = event.Channel
-		labels["event_type"] = event.Type
-		labels["collector_address"] = c.options.Address
-		labels["output"] = c.Name()
-
-		requests := c.meter.Counter("collector", "requests", "Count of all collector requests", labels, "output")
-		requests.Inc()
-
-		c.logger.Debug("Collector message => %s", message)
-
-		_, err = c.connection.Write(b)
-		if err != nil {
-			errors := c.meter.Counter("collector", "errors", "Count of all collector errors", labels, "output")
-			errors.Inc()
-			c.logger.Error(err)
-		}
+		c.Deliver(event)
 	}()
 }
 
+func (c *CollectorOutput) Deliver(event *common.Event) error {
+
+	if event == nil {
+		c.logger.Debug("Event is empty")
+		return nil
+	}
+
+	b, err := c.message.RenderObject(event)
+	if err != nil {
+		c.logger.Error(err)
+		return nil
+	}
+
+	message := strings.TrimSpace(string(b))
+	if utils.IsEmpty(message) {
+		c.logger.Debug("Collector message is empty")
+		return nil
+	}
+
+	labels := make(map[string]string)
+	labels["event_channel"] = event.Channel
+	labels["event_type"] = event.Type
+	labels["collector_address"] = c.options.Address
+	labels["output"] = c.Name()
+
+	requests := c.meter.Counter("collector", "requests", "Count of all collector requests", labels, "output")
+	requests.Inc()
+
+	c.logger.Debug("Collector message => %s", message)
+
+	_, err = c.connection.Write(b)
+	if err != nil {
+		errors := c.meter.Counter("collector", "errors", "Count of all collector errors", labels, "output")
+		errors.Inc()
+		c.logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+func (c *CollectorOutput) Stop(_ context.Context) error {
+
+	if c.connection == nil {
+		return nil
+	}
+	return c.connection.Close()
+}
+
 func makeCollectorOutputConnection(address string, logger sreCommon.Logger) *net.UDPConn {
 
 	if utils.IsEmpty(address) {
@@ -112,10 +128,10 @@ func NewCollectorOutput(wg *sync.WaitGroup, options CollectorOutputOptions,
 
 	messageOpts := toolsRender.TemplateOptions{
 		Name:       "collector-message",
-		Content:    common.Content(options.Message),
+		Content:    options.Message,
 		TimeFormat: templateOptions.TimeFormat,
 	}
-	message, err := toolsRender.NewTextTemplate(messageOpts, observability)
+	message, err := common.NewTemplate(messageOpts, observability)
 	if err != nil {
 		logger.Error(err)
 		return nil
@@ -1,6 +1,7 @@
 package output
 
 import (
+	"context"
 	"encoding/json"
 	"strings"
 	"sync"
@@ -14,6 +15,7 @@ import (
 )
 
 type DataDogOutputOptions struct {
+	Instance           string
 	Name               string
 	Message            string
 	AttributesSelector string
@@ -21,9 +23,9 @@ type DataDogOutputOptions struct {
 
 type DataDogOutput struct {
 	wg             *sync.WaitGroup
-	name           *toolsRender.TextTemplate
-	message        *toolsRender.TextTemplate
-	attributes     *toolsRender.TextTemplate
+	name           *common.Template
+	message        *common.Template
+	attributes     *common.Template
 	options        DataDogOutputOptions
 	logger         sreCommon.Logger
 	meter          sreCommon.Meter
@@ -31,7 +33,7 @@ type DataDogOutput struct {
 }
 
 func (d *DataDogOutput) Name() string {
-	return "Datadog"
+	return common.OutputName(d.options.Instance, "Datadog")
 }
 
 func (d *DataDogOutput) getAttributes(o interface{}) (map[string]string, error) {
@@ -73,72 +75,86 @@ func (d *DataDogOutput) getAttributes(o interface{}) (map[string]string, error)
 }
 
 func (d *DataDogOutput) Send(event *common.Event) {
+
 	d.wg.Add(1)
 	go func() {
 		defer d.wg.Done()
+		d.Deliver(event)
+	}()
+}
 
-		if event == nil {
-			d.logger.Debug("Event is empty")
-			return
-		}
+func (d *DataDogOutput) Deliver(event *common.Event) error {
 
-		if event.Data == nil {
-			d.logger.Error("Event data is empty")
-			return
-		}
+	if event == nil {
+		d.logger.Debug("Event is empty")
+		return nil
+	}
 
-		jsonObject, err := event.JsonObject()
-		if err != nil {
-			d.logger.Error(err)
-			return
-		}
+	if event.Data == nil {
+		d.logger.Error("Event data is empty")
+		return nil
+	}
 
-		a, err := d.name.RenderObject(jsonObject)
-		if err != nil {
-			d.logger.Error(err)
-			return
-		}
+	jsonObject, err := event.JsonObject()
+	if err != nil {
+		d.logger.Error(err)
+		return nil
+	}
 
-		name := strings.TrimSpace(string(a))
-		if utils.IsEmpty(a) {
-			d.logger.Debug("DataDog name is empty")
-			return
-		}
+	a, err := d.name.RenderObject(jsonObject)
+	if err != nil {
+		d.logger.Error(err)
+		return nil
+	}
 
-		b, err := d.message.RenderObject(jsonObject)
-		if err != nil {
-			d.logger.Error(err)
-			return
-		}
+	name := strings.TrimSpace(string(a))
+	if utils.IsEmpty(a) {
+		d.logger.Debug("DataDog name is empty")
+		return nil
+	}
 
-		message := strings.TrimSpace(string(b))
-		if utils.IsEmpty(message) {
-			d.logger.Debug("DataDog message is empty")
-			return
-		}
+	b, err := d.message.RenderObject(jsonObject)
+	if err != nil {
+		d.logger.Error(err)
+		return nil
+	}
 
-		labels := make(map[string]string)
-		labels["event_channel"] = event.Channel
-		labels["event_type"] = event.Type
-		labels["output"] = d.Name()
+	message := strings.TrimSpace(string(b))
+	if utils.IsEmpty(message) {
+		d.logger.Debug("DataDog message is empty")
+		return nil
+	}
 
-		requests := d.meter.Counter("datadog", "requests", "Count of all datadog requests", labels, "output")
-		requests.Inc()
-		d.logger.Debug("DataDog name => %s", name)
-		d.logger.Debug("DataDog message => %s", message)
+	labels := make(map[string]string)
+	labels["event_channel"] = event.Channel
+	labels["event_type"] = event.Type
+	labels["output"] = d.Name()
 
-		attributes, err := d.getAttributes(jsonObject)
-		if err != nil {
-			d.logger.Error(err)
-		}
-		d.logger.Debug("Name: %s, Message: %s, Attributes: %s, Time: %s", name, message, strings.Join(utils.MapToArray(attributes), ","), event.Time.Format(time.RFC822))
+	requests := d.meter.Counter("datadog", "requests", "Count of all datadog requests", labels, "output")
+	requests.Inc()
+	d.logger.Debug("DataDog name => %s", name)
+	d.logger.Debug("DataDog message => %s", message)
 
-		err = d.datadogEventer.At(name, message, attributes, event.Time)
-		if err != nil {
-			errors := d.meter.Counter("datadog", "errors", "Count of all datadog errors", labels, "output")
-			errors.Inc()
-		}
-	}()
+	attributes, err := d.getAttributes(jsonObject)
+	if err != nil {
+		d.logger.Error(err)
+	}
+	if !utils.IsEmpty(event.ID) {
+		attributes["event_id"] = event.ID
+	}
+	d.logger.Debug("Name: %s, Message: %s, Attributes: %s, Time: %s", name, message, strings.Join(utils.MapToArray(attributes), ","), event.Time.Format(time.RFC822))
+
+	err = d.datadogEventer.At(name, message, attributes, event.Time)
+	if err != nil {
+		errors := d.meter.Counter("datadog", "errors", "Count of all datadog errors", labels, "output")
+		errors.Inc()
+		return err
+	}
+	return nil
+}
+
+func (d *DataDogOutput) Stop(_ context.Context) error {
+	return nil
 }
 
 func NewDataDogOutput(wg *sync.WaitGroup,
@@ -155,10 +171,10 @@ func NewDataDogOutput(wg *sync.WaitGroup,
 
 	nameOpts := toolsRender.TemplateOptions{
 		Name:       "datadog-name",
-		Content:    common.Content(options.Name),
+		Content:    options.Name,
 		TimeFormat: templateOptions.TimeFormat,
 	}
-	name, err := toolsRender.NewTextTemplate(nameOpts, observability)
+	name, err := common.NewTemplate(nameOpts, observability)
 	if err != nil {
 		logger.Error(err)
 		return nil
@@ -166,10 +182,10 @@ func NewDataDogOutput(wg *sync.WaitGroup,
 
 	messageOpts := toolsRender.TemplateOptions{
 		Name:       "datadog-message",
-		Content:    common.Content(options.Message),
+		Content:    options.Message,
 		TimeFormat: templateOptions.TimeFormat,
 	}
-	message, err := toolsRender.NewTextTemplate(messageOpts, observability)
+	message, err := common.NewTemplate(messageOpts, observability)
 	if err != nil {
 		logger.Error(err)
 		return nil
@@ -177,10 +193,10 @@ func NewDataDogOutput(wg *sync.WaitGroup,
 
 	attributesOpts := toolsRender.TemplateOptions{
 		Name:       "datadog-attributes",
-		Content:    common.Content(options.AttributesSelector),
+		Content:    options.AttributesSelector,
 		TimeFormat: templateOptions.TimeFormat,
 	}
-	attributes, err := toolsRender.NewTextTemplate(attributesOpts, observability)
+	attributes, err := common.NewTemplate(attributesOpts, observability)
 	if err != nil {
 		logger.Error(err)
 	}
@@ -1,7 +1,10 @@
 package output
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"strings"
 	"sync"
 
@@ -13,6 +16,7 @@ import (
 )
 
 type GitlabOutputOptions struct {
+	Instance  string
 	BaseURL   string
 	Token     string
 	Variables string
@@ -22,15 +26,15 @@ type GitlabOutputOptions struct {
 type GitlabOutput struct {
 	wg        *sync.WaitGroup
 	client    *gitlab.Client
-	projects  *toolsRender.TextTemplate
-	variables *toolsRender.TextTemplate
+	projects  *common.Template
+	variables *common.Template
 	options   GitlabOutputOptions
 	logger    sreCommon.Logger
 	meter     sreCommon.Meter
 }
 
 func (g *GitlabOutput) Name() string {
-	return "Gitlab"
+	return common.OutputName(g.options.Instance, "Gitlab")
 }
 
 func (g *GitlabOutput) getVariables(o interface{}) (map[string]string, error) {
@@ -87,97 +91,115 @@ func (g *GitlabOutput) Send(event *common.Event) {
 	g.wg.Add(1)
 	go func() {
 		defer g.wg.Done()
+		g.Deliver(event)
+	}()
+}
 
-		if event == nil {
-			g.logger.Debug("Event is empty")
-			return
-		}
-
-		if event.Data == nil {
-			g.logger.Error("Event data is empty")
-			return
-		}
+func (g *GitlabOutput) Deliver(event *common.Event) error {
 
-		jsonObject, err := event.JsonObject()
-		if err != nil {
-			g.logger.Error(err)
-			return
-		}
+	if event == nil {
+		g.logger.Debug("Event is empty")
+		return nil
+	}
 
-		projects := ""
-		if g.projects != nil {
-			b, err := g.projects.RenderObject(jsonObject)
-			if err != nil {
-				g.logger.Debug(err)
-			} else {
-				projects = string(b)
-			}
-		}
+	if event.Data == nil {
+		g.logger.Error("Event data is empty")
+		return nil
+	}
 
-		if utils.IsEmpty(projects) {
-			g.logger.Debug("Gitlab projects are not found")
-			return
-		}
+	jsonObject, err := event.JsonObject()
+	if err != nil {
+		g.logger.Error(err)
+		return nil
+	}
 
-		variables, err := g.getVariables(jsonObject)
+	projects := ""
+	if g.projects != nil {
+		b, err := g.projects.RenderObject(jsonObject)
 		if err != nil {
-			g.logger.Error(err)
+			g.logger.Debug(err)
+		} else {
+			projects = string(b)
 		}
+	}
+
+	if utils.IsEmpty(projects) {
+		g.logger.Debug("Gitlab projects are not found")
+		return nil
+	}
 
-		arr := strings.Split(projects, "\n")
-		for _, project := range arr {
+	variables, err := g.getVariables(jsonObject)
+	if err != nil {
+		g.logger.Error(err)
+	}
 
-			project = strings.TrimSpace(project)
-			if utils.IsEmpty(project) {
-				continue
-			}
-			pair := strings.SplitN(project, "=", 2)
-			token := g.options.Token
+	var errs []error
+	arr := strings.Split(projects, "\n")
+	for _, project := range arr {
 
-			if len(pair) == 2 && !utils.IsEmpty(pair[0]) {
-				token = pair[0]
-				project = pair[1]
-			}
+		project = strings.TrimSpace(project)
+		// project got event by a previous attempt
+		if utils.IsEmpty(project) || event.Delivered(g.Name(), project) {
+			continue
+		}
+		channel := project
+		pair := strings.SplitN(project, "=", 2)
+		token := g.options.Token
 
-			pair = strings.SplitN(project, "@", 2)
-			if len(pair) < 2 {
-				continue
-			}
+		if len(pair) == 2 && !utils.IsEmpty(pair[0]) {
+			token = pair[0]
+			project = pair[1]
+		}
 
-			id := pair[0]
-			ref := pair[1]
-			if utils.IsEmpty(ref) {
-				ref = "main"
-			}
+		pair = strings.SplitN(project, "@", 2)
+		if len(pair) < 2 {
+			continue
+		}
 
-			labels := make(map[string]string)
-			labels["event_channel"] = event.Channel
-			labels["event_type"] = event.Type
-			labels["gitlab_project_id"] = project
-			labels["gitlab_ref"] = ref
-			labels["output"] = g.Name()
+		id := pair[0]
+		ref := pair[1]
+		if utils.IsEmpty(ref) {
+			ref = "main"
+		}
 
-			requests := g.meter.Counter("gitlab", "requests", "Count of all gitlab requests", labels, "output")
-			requests.Inc()
+		labels := make(map[string]string)
+		labels["event_channel"] = event.Channel
+		labels["event_type"] = event.Type
+		labels["gitlab_project_id"] = project
+		labels["gitlab_ref"] = ref
+		labels["output"] = g.Name()
 
-			opt := &gitlab.RunPipelineTriggerOptions{Ref: &ref, Token: &token, Variables: variables}
-			pipeline, response, err := g.client.PipelineTriggers.RunPipelineTrigger(id, opt)
+		requests := g.meter.Counter("gitlab", "requests", "Count of all gitlab requests", labels, "output")
+		requests.Inc()
 
-			errors := g.meter.Counter("gitlab", "errors", "Count of all gitlab errors", labels, "output")
-			if err != nil {
-				errors.Inc()
-				g.logger.Error(err)
-				continue
-			}
+		opt := &gitlab.RunPipelineTriggerOptions{Ref: &ref, Token: &token, Variables: variables}
+		pipeline, response, err := g.client.PipelineTriggers.RunPipelineTrigger(id, opt)
 
-			if response.StatusCode < 200 || response.StatusCode >= 300 {
-				errors.Inc()
-				g.logger.Error("Gitlab response: %s", response.Status)
-				continue
+		errors := g.meter.Counter("gitlab", "errors", "Count of all gitlab errors", labels, "output")
+		if err != nil {
+			errors.Inc()
+			g.logger.Error(err)
+			if response != nil {
+				err = common.NewStatusError(response.StatusCode, err)
 			}
-			g.logger.Debug("Gitlab pipeline => %s", pipeline.WebURL)
+			errs = append(errs, err)
+			continue
 		}
-	}()
+
+		if response.StatusCode < 200 || response.StatusCode >= 300 {
+			errors.Inc()
+			g.logger.Error("Gitlab response: %s", response.Status)
+			errs = append(errs, common.NewStatusError(response.StatusCode, fmt.Errorf("gitlab response: %s", response.Status)))
+			continue
+		}
+		g.logger.Debug("Gitlab pipeline => %s", pipeline.WebURL)
+		event.SetDelivered(g.Name(), channel)
+	}
+	return errors.Join(errs...)
+}
+
+func (g *GitlabOutput) Stop(_ context.Context) error {
+	return nil
 }
 
 func NewGitlabOutput(wg *sync.WaitGroup,
@@ -199,10 +221,10 @@ func NewGitlabOutput(wg *sync.WaitGroup,
 
 	projectsOpts := toolsRender.TemplateOptions{
 		Name:       "gitlab-projects",
-		Content:    common.Content(options.Projects),
+		Content:    options.Projects,
 		TimeFormat: templateOptions.TimeFormat,
 	}
-	projects, err := toolsRender.NewTextTemplate(projectsOpts, observability)
+	projects, err := common.NewTemplate(projectsOpts, observability)
 	if err != nil {
 		logger.Error(err)
 		return nil
@@ -210,10 +232,10 @@ func NewGitlabOutput(wg *sync.WaitGroup,
 
 	variablesOpts := toolsRender.TemplateOptions{
 		Name:       "gitlab-variables",
-		Content:    common.Content(options.Variables),
+		Content:    options.Variables,
 		TimeFormat: templateOptions.TimeFormat,
 	}
-	variables, err := toolsRender.NewTextTemplate(variablesOpts, observability)
+	variables, err := common.NewTemplate(variablesOpts, observability)
 	if err != nil {
 		logger.Error(err)
 	}
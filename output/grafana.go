@@ -1,6 +1,7 @@
 package output
 
 import (
+	"context"
 	"encoding/json"
 	"strings"
 	"sync"
@@ -16,6 +17,7 @@ import (
 )
 
 type GrafanaOutputOptions struct {
+	Instance           string
 	Name               string
 	Message            string
 	AttributesSelector string
@@ -23,8 +25,8 @@ type GrafanaOutputOptions struct {
 
 type GrafanaOutput struct {
 	wg             *sync.WaitGroup
-	message        *toolsRender.TextTemplate
-	attributes     *toolsRender.TextTemplate
+	message        *common.Template
+	attributes     *common.Template
 	options        GrafanaOutputOptions
 	logger         sreCommon.Logger
 	meter          sreCommon.Meter
@@ -33,7 +35,7 @@ type GrafanaOutput struct {
 }
 
 func (g *GrafanaOutput) Name() string {
-	return "Grafana"
+	return common.OutputName(g.options.Instance, "Grafana")
 }
 
 func (g *GrafanaOutput) getAttributes(o interface{}) (map[string]string, error) {
@@ -76,63 +78,76 @@ func (g *GrafanaOutput) Send(event *common.Event) {
 	g.wg.Add(1)
 	go func() {
 		defer g.wg.Done()
+		g.Deliver(event)
+	}()
+}
 
-		if event == nil {
-			g.logger.Debug("Event is empty")
-			return
-		}
-		if event.Data == nil {
-			g.logger.Error("Event data is empty")
-			return
-		}
+func (g *GrafanaOutput) Deliver(event *common.Event) error {
 
-		jsonObject, err := event.JsonObject()
-		if err != nil {
-			g.logger.Error(err)
-			return
-		}
+	if event == nil {
+		g.logger.Debug("Event is empty")
+		return nil
+	}
+	if event.Data == nil {
+		g.logger.Error("Event data is empty")
+		return nil
+	}
 
-		b, err := g.message.RenderObject(jsonObject)
-		if err != nil {
-			g.logger.Error(err)
-			return
-		}
-		message := strings.TrimSpace(string(b))
-		if utils.IsEmpty(message) {
-			g.logger.Debug("Grafana message is empty")
-			return
-		}
-		g.logger.Debug("Grafana message => %s", message)
+	jsonObject, err := event.JsonObject()
+	if err != nil {
+		g.logger.Error(err)
+		return nil
+	}
 
-		attributes, err := g.getAttributes(jsonObject)
-		if err != nil {
-			g.logger.Error(err)
-		}
-		g.logger.Debug("Grafana attributes => %s", attributes)
+	b, err := g.message.RenderObject(jsonObject)
+	if err != nil {
+		g.logger.Error(err)
+		return nil
+	}
+	message := strings.TrimSpace(string(b))
+	if utils.IsEmpty(message) {
+		g.logger.Debug("Grafana message is empty")
+		return nil
+	}
+	g.logger.Debug("Grafana message => %s", message)
 
-		labels := make(map[string]string)
-		labels["event_channel"] = event.Channel
-		labels["event_type"] = event.Type
-		labels["output"] = g.Name()
+	attributes, err := g.getAttributes(jsonObject)
+	if err != nil {
+		g.logger.Error(err)
+	}
+	if !utils.IsEmpty(event.ID) {
+		attributes["event_id"] = event.ID
+	}
+	g.logger.Debug("Grafana attributes => %s", attributes)
 
-		rateLimiterIn := g.meter.Counter("grafana", "rl_in", "Count of all grafana requests before waiting", labels, "output", "rate_limiter")
-		rateLimiterIn.Inc()
-		r := g.rateLimiter.Reserve()
-		// TODO increment another counter events_grafana_output_ratelimiter_wait_time_total by r.Delay*time.Millisecond
-		time.Sleep(r.Delay())
+	labels := make(map[string]string)
+	labels["event_channel"] = event.Channel
+	labels["event_type"] = event.Type
+	labels["output"] = g.Name()
 
-		rateLimiterOut := g.meter.Counter("grafana", "rl_out", "Count of all grafana requests after waiting", labels, "output", "rate_limiter")
-		rateLimiterOut.Inc()
+	rateLimiterIn := g.meter.Counter("grafana", "rl_in", "Count of all grafana requests before waiting", labels, "output", "rate_limiter")
+	rateLimiterIn.Inc()
+	r := g.rateLimiter.Reserve()
+	// TODO increment another counter events_grafana_output_ratelimiter_wait_time_total by r.Delay*time.Millisecond
+	time.Sleep(r.Delay())
 
-		requests := g.meter.Counter("grafana", "requests", "Count of all grafana requests", labels, "output")
-		requests.Inc()
+	rateLimiterOut := g.meter.Counter("grafana", "rl_out", "Count of all grafana requests after waiting", labels, "output", "rate_limiter")
+	rateLimiterOut.Inc()
 
-		err = g.grafanaEventer.Interval(message, message, attributes, event.Time, event.Time)
-		if err != nil {
-			errors := g.meter.Counter("grafana", "errors", "Count of all grafana errors", labels, "output")
-			errors.Inc()
-		}
-	}()
+	requests := g.meter.Counter("grafana", "requests", "Count of all grafana requests", labels, "output")
+	requests.Inc()
+
+	err = g.grafanaEventer.Interval(message, message, attributes, event.Time, event.Time)
+	if err != nil {
+		errors := g.meter.Counter("grafana", "errors", "Count of all grafana errors", labels, "output")
+		errors.Inc()
+		return err
+	}
+	return nil
+}
+
+func (g *GrafanaOutput) Stop(_ context.Context) error {
+	return nil
 }
 
 func NewGrafanaOutput(wg *sync.WaitGroup,
@@ -149,10 +164,10 @@ func NewGrafanaOutput(wg *sync.WaitGroup,
 
 	messageOpts := toolsRender.TemplateOptions{
 		Name:       "grafana-message",
-		Content:    common.Content(options.Message),
+		Content:    options.Message,
 		TimeFormat: templateOptions.TimeFormat,
 	}
-	message, err := toolsRender.NewTextTemplate(messageOpts, observability)
+	message, err := common.NewTemplate(messageOpts, observability)
 	if err != nil {
 		logger.Error(err)
 		return nil
@@ -160,10 +175,10 @@ func NewGrafanaOutput(wg *sync.WaitGroup,
 
 	selectorOpts := toolsRender.TemplateOptions{
 		Name:       "grafana-attributes",
-		Content:    common.Content(options.AttributesSelector),
+		Content:    options.AttributesSelector,
 		TimeFormat: templateOptions.TimeFormat,
 	}
-	attributes, err := toolsRender.NewTextTemplate(selectorOpts, observability)
+	attributes, err := common.NewTemplate(selectorOpts, observability)
 	if err != nil {
 		logger.Error(err)
 	}
@@ -1,6 +1,10 @@
 package output
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -15,6 +19,7 @@ import (
 )
 
 type KafkaOutputOptions struct {
+	Instance           string
 	ClientID           string
 	Message            string
 	Brokers            string
@@ -25,19 +30,24 @@ type KafkaOutputOptions struct {
 	NetDialTimeout     int
 	NetReadTimeout     int
 	NetWriteTimeout    int
+	CloudEvents        string
 }
 
 type KafkaOutput struct {
 	wg       *sync.WaitGroup
-	producer *sarama.AsyncProducer
-	message  *toolsRender.TextTemplate
+	producer *sarama.SyncProducer
+	message  *common.Template
 	options  KafkaOutputOptions
 	logger   sreCommon.Logger
 	meter    sreCommon.Meter
+	mutex    sync.RWMutex
+	stopped  bool
 }
 
+var errKafkaStopped = errors.New("kafka producer is stopped")
+
 func (k *KafkaOutput) Name() string {
-	return "Kafka"
+	return common.OutputName(k.options.Instance, "Kafka")
 }
 
 func (k *KafkaOutput) Send(event *common.Event) {
@@ -45,49 +55,127 @@ func (k *KafkaOutput) Send(event *common.Event) {
 	k.wg.Add(1)
 	go func() {
 		defer k.wg.Done()
+		k.Deliver(event)
+	}()
+}
 
-		if event == nil {
-			k.logger.Debug("Event is empty")
-			return
-		}
+func (k *KafkaOutput) Deliver(event *common.Event) error {
 
-		b, err := k.message.RenderObject(event)
-		if err != nil {
-			k.logger.Error(err)
-			return
-		}
+	if event == nil {
+		k.logger.Debug("Event is empty")
+		return nil
+	}
 
-		message := strings.TrimSpace(string(b))
-		if utils.IsEmpty(message) {
-			k.logger.Debug("Kafka message is empty")
-			return
-		}
+	b, err := k.message.RenderObject(event)
+	if err != nil {
+		k.logger.Error(err)
+		return nil
+	}
 
-		labels := make(map[string]string)
-		labels["event_channel"] = event.Channel
-		labels["event_type"] = event.Type
-		labels["kafka_client_id"] = k.options.ClientID
-		labels["kafka_brokers"] = k.options.Brokers
-		labels["kafka_topic"] = k.options.Topic
-		labels["output"] = k.Name()
-
-		requests := k.meter.Counter("kafka", "requests", "Count of all kafka requests", labels, "output")
-		requests.Inc()
-		k.logger.Debug("Kafka  message => %s", message)
-
-		(*k.producer).Input() <- &sarama.ProducerMessage{
-			Topic: k.options.Topic,
-			Value: sarama.ByteEncoder(b),
-		}
+	message := strings.TrimSpace(string(b))
+	if utils.IsEmpty(message) {
+		k.logger.Debug("Kafka message is empty")
+		return nil
+	}
+
+	labels := make(map[string]string)
+	labels["event_channel"] = event.Channel
+	labels["event_type"] = event.Type
+	labels["kafka_client_id"] = k.options.ClientID
+	labels["kafka_brokers"] = k.options.Brokers
+	labels["kafka_topic"] = k.options.Topic
+	labels["output"] = k.Name()
+
+	requests := k.meter.Counter("kafka", "requests", "Count of all kafka requests", labels, "output")
+	requests.Inc()
+	k.logger.Debug("Kafka  message => %s", message)
+
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+
+	if k.stopped {
+		return errKafkaStopped
+	}
+
+	m, err := k.producerMessage(event, b)
+	if err != nil {
+		k.logger.Error(err)
+		return nil
+	}
+
+	start := time.Now()
+	partition, offset, err := (*k.producer).SendMessage(m)
+	common.ObserveAPIDuration(k.meter, "kafka", k.Name(), "produce", start)
+	if err != nil {
+		errors := k.meter.Counter("kafka", "errors", "Count of all kafka errors", labels, "output")
+		errors.Inc()
+		k.logger.Error(err)
+		return kafkaError(err)
+	}
+	event.SetMessageID(fmt.Sprintf("%s/%d/%d", k.options.Topic, partition, offset))
+	return nil
+}
 
-		for err = range (*k.producer).Errors() {
-			errors := k.meter.Counter("kafka", "errors", "Count of all kafka errors", labels, "output")
-			errors.Inc()
+// producerMessage wraps rendered message into CloudEvents envelope if it's enabled
+func (k *KafkaOutput) producerMessage(event *common.Event, b []byte) (*sarama.ProducerMessage, error) {
+
+	m := &sarama.ProducerMessage{
+		Topic: k.options.Topic,
+		Value: sarama.ByteEncoder(b),
+	}
+	if utils.IsEmpty(k.options.CloudEvents) {
+		if !utils.IsEmpty(event.ID) {
+			m.Headers = append(m.Headers, sarama.RecordHeader{Key: []byte("event_id"), Value: []byte(event.ID)})
 		}
-	}()
+		return m, nil
+	}
+
+	ce, err := event.CloudEvent(b)
+	if err != nil {
+		return nil, err
+	}
+	value, headers, err := common.CloudEventEncode(ce, k.options.CloudEvents, "ce_")
+	if err != nil {
+		return nil, err
+	}
+
+	m.Value = sarama.ByteEncoder(value)
+	for h, v := range headers {
+		m.Headers = append(m.Headers, sarama.RecordHeader{Key: []byte(h), Value: []byte(v)})
+	}
+	return m, nil
+}
+
+// Stop waits for buffered messages to be flushed and closes the producer
+func (k *KafkaOutput) Stop(_ context.Context) error {
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.producer == nil || k.stopped {
+		return nil
+	}
+	k.stopped = true
+	k.logger.Info("Stop %s for %s...", k.options.ClientID, k.options.Topic)
+	return (*k.producer).Close()
+}
+
+// kafkaError marks errors which can't be fixed by sending message again as permanent
+func kafkaError(err error) error {
+
+	switch {
+	case errors.Is(err, sarama.ErrMessageSizeTooLarge):
+		return common.NewStatusError(http.StatusRequestEntityTooLarge, err)
+	case errors.Is(err, sarama.ErrInvalidMessage), errors.Is(err, sarama.ErrInvalidTopic), errors.Is(err, sarama.ErrInvalidRecord):
+		return common.NewStatusError(http.StatusBadRequest, err)
+	case errors.Is(err, sarama.ErrTopicAuthorizationFailed), errors.Is(err, sarama.ErrClusterAuthorizationFailed):
+		return common.NewStatusError(http.StatusForbidden, err)
+	default:
+		return err
+	}
 }
 
-func makeKafkaProducer(wg *sync.WaitGroup, brokers string, topic string, config *sarama.Config, logger sreCommon.Logger) *sarama.AsyncProducer {
+func makeKafkaProducer(wg *sync.WaitGroup, brokers string, topic string, config *sarama.Config, logger sreCommon.Logger) *sarama.SyncProducer {
 
 	brks := strings.Split(brokers, ",")
 	if len(brks) == 0 || utils.IsEmpty(brokers) {
@@ -103,7 +191,7 @@ func makeKafkaProducer(wg *sync.WaitGroup, brokers string, topic string, config
 
 	logger.Info("Start %s for %s...", config.ClientID, topic)
 
-	producer, err := sarama.NewAsyncProducer(brks, config)
+	producer, err := sarama.NewSyncProducer(brks, config)
 	if err != nil {
 		logger.Error(err)
 		return nil
@@ -122,8 +210,11 @@ func NewKafkaOutput(wg *sync.WaitGroup, options KafkaOutputOptions, templateOpti
 
 	config.Producer.Return.Successes = true
 	config.Producer.Return.Errors = true
-	config.Producer.Flush.Frequency = time.Second * time.Duration(options.FlushFrequency)
-	config.Producer.Flush.MaxMessages = options.FlushMaxMessages
+	// sync producer waits for every message, flush batching would delay each of them till the next flush,
+	// so flush options are deprecated and messages are sent right away
+	if options.FlushFrequency > 0 || options.FlushMaxMessages > 0 {
+		observability.Logs().Warn("Kafka flush frequency and max messages are deprecated and ignored, messages are sent without batching")
+	}
 
 	config.Net.MaxOpenRequests = options.NetMaxOpenRequests
 	config.Net.DialTimeout = time.Second * time.Duration(options.NetDialTimeout)
@@ -139,10 +230,10 @@ func NewKafkaOutput(wg *sync.WaitGroup, options KafkaOutputOptions, templateOpti
 
 	messageOpts := toolsRender.TemplateOptions{
 		Name:       "kafka-message",
-		Content:    common.Content(options.Message),
+		Content:    options.Message,
 		TimeFormat: templateOptions.TimeFormat,
 	}
-	message, err := toolsRender.NewTextTemplate(messageOpts, observability)
+	message, err := common.NewTemplate(messageOpts, observability)
 	if err != nil {
 		logger.Error(err)
 		return nil
@@ -1,6 +1,7 @@
 package output
 
 import (
+	"context"
 	"encoding/json"
 	"strings"
 	"sync"
@@ -13,6 +14,7 @@ import (
 )
 
 type NewRelicOutputOptions struct {
+	Instance           string
 	Name               string
 	Message            string
 	AttributesSelector string
@@ -20,9 +22,9 @@ type NewRelicOutputOptions struct {
 
 type NewRelicOutput struct {
 	wg              *sync.WaitGroup
-	name            *toolsRender.TextTemplate
-	message         *toolsRender.TextTemplate
-	attributes      *toolsRender.TextTemplate
+	name            *common.Template
+	message         *common.Template
+	attributes      *common.Template
 	options         NewRelicOutputOptions
 	logger          sreCommon.Logger
 	meter           sreCommon.Meter
@@ -31,7 +33,7 @@ type NewRelicOutput struct {
 }
 
 func (n *NewRelicOutput) Name() string {
-	return "NewRelic"
+	return common.OutputName(n.options.Instance, "NewRelic")
 }
 
 func (n *NewRelicOutput) getAttributes(o interface{}) (map[string]string, error) {
@@ -73,57 +75,70 @@ func (r *NewRelicOutput) Send(event *common.Event) {
 	r.wg.Add(1)
 	go func() {
 		defer r.wg.Done()
+		r.Deliver(event)
+	}()
+}
 
-		if event == nil {
-			r.logger.Debug("Event is empty")
-			return
-		}
+func (r *NewRelicOutput) Deliver(event *common.Event) error {
 
-		if event.Data == nil {
-			r.logger.Error("Event data is empty")
-			return
-		}
+	if event == nil {
+		r.logger.Debug("Event is empty")
+		return nil
+	}
 
-		jsonObject, err := event.JsonObject()
-		if err != nil {
-			r.logger.Error(err)
-			return
-		}
+	if event.Data == nil {
+		r.logger.Error("Event data is empty")
+		return nil
+	}
 
-		b, err := r.message.RenderObject(jsonObject)
-		if err != nil {
-			r.logger.Error(err)
-			return
-		}
+	jsonObject, err := event.JsonObject()
+	if err != nil {
+		r.logger.Error(err)
+		return nil
+	}
 
-		message := strings.TrimSpace(string(b))
-		if utils.IsEmpty(message) {
-			r.logger.Debug("NewRelic message is empty")
-			return
-		}
+	b, err := r.message.RenderObject(jsonObject)
+	if err != nil {
+		r.logger.Error(err)
+		return nil
+	}
 
-		labels := make(map[string]string)
-		labels["event_channel"] = event.Channel
-		labels["event_type"] = event.Type
-		labels["newrelic_environment"] = r.newrelicOptions.Environment
-		labels["newrelic_service_name"] = r.newrelicOptions.ServiceName
-		labels["output"] = r.Name()
+	message := strings.TrimSpace(string(b))
+	if utils.IsEmpty(message) {
+		r.logger.Debug("NewRelic message is empty")
+		return nil
+	}
 
-		requests := r.meter.Counter("newrelic", "requests", "Count of all newrelic requests", labels, "output")
-		requests.Inc()
-		r.logger.Debug("NewRelic message => %s", message)
+	labels := make(map[string]string)
+	labels["event_channel"] = event.Channel
+	labels["event_type"] = event.Type
+	labels["newrelic_environment"] = r.newrelicOptions.Environment
+	labels["newrelic_service_name"] = r.newrelicOptions.ServiceName
+	labels["output"] = r.Name()
 
-		attributes, err := r.getAttributes(jsonObject)
-		if err != nil {
-			r.logger.Error(err)
-		}
+	requests := r.meter.Counter("newrelic", "requests", "Count of all newrelic requests", labels, "output")
+	requests.Inc()
+	r.logger.Debug("NewRelic message => %s", message)
 
-		err = r.newrelicEventer.At(message, "", attributes, event.Time)
-		if err != nil {
-			errors := r.meter.Counter("newrelic", "errors", "Count of all newrelic errors", labels, "output")
-			errors.Inc()
-		}
-	}()
+	attributes, err := r.getAttributes(jsonObject)
+	if err != nil {
+		r.logger.Error(err)
+	}
+	if !utils.IsEmpty(event.ID) {
+		attributes["event_id"] = event.ID
+	}
+
+	err = r.newrelicEventer.At(message, "", attributes, event.Time)
+	if err != nil {
+		errors := r.meter.Counter("newrelic", "errors", "Count of all newrelic errors", labels, "output")
+		errors.Inc()
+		return err
+	}
+	return nil
+}
+
+func (n *NewRelicOutput) Stop(_ context.Context) error {
+	return nil
 }
 
 func NewNewRelicOutput(wg *sync.WaitGroup,
@@ -140,10 +155,10 @@ func NewNewRelicOutput(wg *sync.WaitGroup,
 
 	messageOpts := toolsRender.TemplateOptions{
 		Name:       "newrelic-message",
-		Content:    common.Content(options.Message),
+		Content:    options.Message,
 		TimeFormat: templateOptions.TimeFormat,
 	}
-	message, err := toolsRender.NewTextTemplate(messageOpts, observability)
+	message, err := common.NewTemplate(messageOpts, observability)
 	if err != nil {
 		logger.Error(err)
 		return nil
@@ -151,10 +166,10 @@ func NewNewRelicOutput(wg *sync.WaitGroup,
 
 	attributesOpts := toolsRender.TemplateOptions{
 		Name:       "newrelic-attributes",
-		Content:    common.Content(options.AttributesSelector),
+		Content:    options.AttributesSelector,
 		TimeFormat: templateOptions.TimeFormat,
 	}
-	attributes, err := toolsRender.NewTextTemplate(attributesOpts, observability)
+	attributes, err := common.NewTemplate(attributesOpts, observability)
 	if err != nil {
 		logger.Error(err)
 	}
@@ -2,9 +2,12 @@ package output
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"cloud.google.com/go/pubsub"
 	"github.com/devopsext/events/common"
@@ -12,28 +15,47 @@ import (
 	toolsRender "github.com/devopsext/tools/render"
 	"github.com/devopsext/utils"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type PubSubOutputOptions struct {
+	Instance      string
 	Credentials   string
 	ProjectID     string
 	Message       string
 	TopicSelector string
+	CloudEvents   string
 }
 
 type PubSubOutput struct {
 	wg       *sync.WaitGroup
 	client   *pubsub.Client
 	ctx      context.Context
-	message  *toolsRender.TextTemplate
-	selector *toolsRender.TextTemplate
+	message  *common.Template
+	selector *common.Template
 	options  PubSubOutputOptions
 	meter    sreCommon.Meter
 	logger   sreCommon.Logger
+	topics   map[string]*pubsub.Topic
+	mutex    sync.Mutex
 }
 
 func (ps *PubSubOutput) Name() string {
-	return "PubSub"
+	return common.OutputName(ps.options.Instance, "PubSub")
+}
+
+func (ps *PubSubOutput) topic(name string) *pubsub.Topic {
+
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	t, ok := ps.topics[name]
+	if !ok {
+		t = ps.client.Topic(name)
+		ps.topics[name] = t
+	}
+	return t
 }
 
 func (ps *PubSubOutput) Send(event *common.Event) {
@@ -41,80 +63,149 @@ func (ps *PubSubOutput) Send(event *common.Event) {
 	ps.wg.Add(1)
 	go func() {
 		defer ps.wg.Done()
+		ps.Deliver(event)
+	}()
+}
 
-		if event == nil {
-			ps.logger.Debug("Event is empty")
-			return
-		}
+func (ps *PubSubOutput) Deliver(event *common.Event) error {
 
-		if event.Data == nil {
-			ps.logger.Error("Event data is empty")
-			return
-		}
+	if event == nil {
+		ps.logger.Debug("Event is empty")
+		return nil
+	}
+
+	if event.Data == nil {
+		ps.logger.Error("Event data is empty")
+		return nil
+	}
 
-		jsonObject, err := event.JsonObject()
+	jsonObject, err := event.JsonObject()
+	if err != nil {
+		ps.logger.Error(err)
+		return nil
+	}
+
+	topics := ""
+	if ps.selector != nil {
+		b, err := ps.selector.RenderObject(jsonObject)
 		if err != nil {
-			ps.logger.Error(err)
-			return
+			ps.logger.Debug(err)
+		} else {
+			topics = string(b)
 		}
+	}
 
-		topics := ""
-		if ps.selector != nil {
-			b, err := ps.selector.RenderObject(jsonObject)
-			if err != nil {
-				ps.logger.Debug(err)
-			} else {
-				topics = string(b)
-			}
-		}
+	if utils.IsEmpty(topics) {
+		ps.logger.Error("PubSub topics are not found")
+		return nil
+	}
+
+	b, err := ps.message.RenderObject(jsonObject)
+	if err != nil {
+		ps.logger.Error(err)
+		return nil
+	}
 
-		if utils.IsEmpty(topics) {
-			ps.logger.Error("PubSub topics are not found")
-			return
+	message := strings.TrimSpace(string(b))
+	if utils.IsEmpty(message) {
+		ps.logger.Debug("PubSub message is empty")
+		return nil
+	}
+
+	ps.logger.Debug("PubSub message => %s", message)
+
+	data, attributes, err := ps.pubsubMessage(event, b)
+	if err != nil {
+		ps.logger.Error(err)
+		return nil
+	}
+
+	var errs []error
+	arr := strings.Split(topics, "\n")
+	for _, topic := range arr {
+		topic = strings.TrimSpace(topic)
+		// topic got event by a previous attempt
+		if utils.IsEmpty(topic) || event.Delivered(ps.Name(), topic) {
+			continue
 		}
 
-		b, err := ps.message.RenderObject(jsonObject)
+		labels := make(map[string]string)
+		labels["event_channel"] = event.Channel
+		labels["event_type"] = event.Type
+		labels["pubsub_project_id"] = ps.options.ProjectID
+		labels["pubsub_topic"] = ps.options.TopicSelector
+		labels["output"] = ps.Name()
+
+		requests := ps.meter.Counter("pubsub", "requests", "Count of all pubsub requests", labels, "output")
+		requests.Inc()
+
+		t := ps.topic(topic)
+		start := time.Now()
+		serverID, err := t.Publish(ps.ctx, &pubsub.Message{Data: data, Attributes: attributes}).Get(ps.ctx)
+		common.ObserveAPIDuration(ps.meter, "pubsub", ps.Name(), "publish", start)
 		if err != nil {
+			errors := ps.meter.Counter("pubsub", "errors", "Count of all pubsub errors", labels, "output")
+			errors.Inc()
 			ps.logger.Error(err)
-			return
+			errs = append(errs, pubsubError(err))
+			continue
 		}
+		ps.logger.Debug("PubSub server ID => %s", serverID)
+		event.SetDelivered(ps.Name(), topic)
+		event.SetMessageID(serverID)
+	}
+	return errors.Join(errs...)
+}
 
-		message := strings.TrimSpace(string(b))
-		if utils.IsEmpty(message) {
-			ps.logger.Debug("PubSub message is empty")
-			return
-		}
+// pubsubError marks errors of gRPC status which can't be fixed by publishing again as permanent
+func pubsubError(err error) error {
 
-		ps.logger.Debug("PubSub message => %s", message)
-
-		arr := strings.Split(topics, "\n")
-		for _, topic := range arr {
-			topic = strings.TrimSpace(topic)
-			if utils.IsEmpty(topic) {
-				continue
-			}
-
-			labels := make(map[string]string)
-			labels["event_channel"] = event.Channel
-			labels["event_type"] = event.Type
-			labels["pubsub_project_id"] = ps.options.ProjectID
-			labels["pubsub_topic"] = ps.options.TopicSelector
-			labels["output"] = ps.Name()
-
-			requests := ps.meter.Counter("pubsub", "requests", "Count of all pubsub requests", labels, "output")
-			requests.Inc()
-
-			t := ps.client.Topic(topic)
-			serverID, err := t.Publish(ps.ctx, &pubsub.Message{Data: []byte(message)}).Get(ps.ctx)
-			if err != nil {
-				errors := ps.meter.Counter("pubsub", "errors", "Count of all pubsub errors", labels, "output")
-				errors.Inc()
-				ps.logger.Error(err)
-				continue
-			}
-			ps.logger.Debug("PubSub server ID => %s", serverID)
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return common.NewStatusError(http.StatusBadRequest, err)
+	case codes.NotFound:
+		return common.NewStatusError(http.StatusNotFound, err)
+	case codes.PermissionDenied:
+		return common.NewStatusError(http.StatusForbidden, err)
+	case codes.Unauthenticated:
+		return common.NewStatusError(http.StatusUnauthorized, err)
+	case codes.ResourceExhausted:
+		return common.NewStatusError(http.StatusTooManyRequests, err)
+	default:
+		return err
+	}
+}
+
+// pubsubMessage wraps rendered message into CloudEvents envelope if it's enabled, attributes are prefixed with ce-
+func (ps *PubSubOutput) pubsubMessage(event *common.Event, b []byte) ([]byte, map[string]string, error) {
+
+	message := []byte(strings.TrimSpace(string(b)))
+	if utils.IsEmpty(ps.options.CloudEvents) {
+		attributes := make(map[string]string)
+		if !utils.IsEmpty(event.ID) {
+			attributes["event_id"] = event.ID
 		}
-	}()
+		return message, attributes, nil
+	}
+
+	ce, err := event.CloudEvent(message)
+	if err != nil {
+		return nil, nil, err
+	}
+	return common.CloudEventEncode(ce, ps.options.CloudEvents, "ce-")
+}
+
+// Stop publishes remaining messages of all topics and closes the client
+func (ps *PubSubOutput) Stop(_ context.Context) error {
+
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	for _, t := range ps.topics {
+		t.Stop()
+	}
+	ps.topics = make(map[string]*pubsub.Topic)
+	return ps.client.Close()
 }
 
 func NewPubSubOutput(wg *sync.WaitGroup,
@@ -143,10 +234,10 @@ func NewPubSubOutput(wg *sync.WaitGroup,
 
 	messageOpts := toolsRender.TemplateOptions{
 		Name:       "pubsub-message",
-		Content:    common.Content(options.Message),
+		Content:    options.Message,
 		TimeFormat: templateOptions.TimeFormat,
 	}
-	message, err := toolsRender.NewTextTemplate(messageOpts, observability)
+	message, err := common.NewTemplate(messageOpts, observability)
 	if err != nil {
 		logger.Error(err)
 		return nil
@@ -154,10 +245,10 @@ func NewPubSubOutput(wg *sync.WaitGroup,
 
 	selectorOpts := toolsRender.TemplateOptions{
 		Name:       "pubsub-selector",
-		Content:    common.Content(options.TopicSelector),
+		Content:    options.TopicSelector,
 		TimeFormat: templateOptions.TimeFormat,
 	}
-	selector, err := toolsRender.NewTextTemplate(selectorOpts, observability)
+	selector, err := common.NewTemplate(selectorOpts, observability)
 	if err != nil {
 		logger.Error(err)
 	}
@@ -171,5 +262,6 @@ func NewPubSubOutput(wg *sync.WaitGroup,
 		options:  options,
 		logger:   logger,
 		meter:    observability.Metrics(),
+		topics:   make(map[string]*pubsub.Topic),
 	}
 }
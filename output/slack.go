@@ -1,6 +1,7 @@
 package output
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -24,7 +25,10 @@ import (
 	"github.com/prometheus/alertmanager/template"
 )
 
+const slackAPIURL = "https://slack.com/api/"
+
 type SlackOutputOptions struct {
+	Instance        string
 	Timeout         int
 	Token           string
 	Channel         string
@@ -33,13 +37,15 @@ type SlackOutputOptions struct {
 	AlertExpression string
 	Forward         string
 	Insecure        bool
+	ResolveMode     string
 }
 
 type SlackOutput struct {
 	wg       *sync.WaitGroup
+	client   *http.Client
 	slack    *vendors.Slack
-	message  *toolsRender.TextTemplate
-	selector *toolsRender.TextTemplate
+	message  *common.Template
+	selector *common.Template
 	grafana  *render.GrafanaRender
 	options  SlackOutputOptions
 	outputs  *common.Outputs
@@ -48,7 +54,7 @@ type SlackOutput struct {
 }
 
 func (s *SlackOutput) Name() string {
-	return "Slack"
+	return common.OutputName(s.options.Instance, "Slack")
 }
 
 func waitDDImage(url string, timeout int) bool {
@@ -65,6 +71,85 @@ func waitDDImage(url string, timeout int) bool {
 	return false
 }
 
+// slackError keeps error of Slack API as status error, Slack answers 200 for most of them and they are permanent
+func slackError(method, e string) error {
+
+	err := fmt.Errorf("slack %s error: %s", method, e)
+	switch e {
+	case "ratelimited":
+		return common.NewStatusError(http.StatusTooManyRequests, err)
+	case "service_unavailable", "fatal_error", "internal_error", "request_timeout":
+		return common.NewStatusError(http.StatusServiceUnavailable, err)
+	default:
+		return common.NewStatusError(http.StatusBadRequest, err)
+	}
+}
+
+type slackAttachment struct {
+	Color string `json:"color,omitempty"`
+	Text  string `json:"text"`
+}
+
+// slackJSON keeps valid JSON of attachments or blocks as is, other values are sent as strings which Slack parses itself
+func slackJSON(v string) interface{} {
+
+	if json.Valid([]byte(v)) {
+		return json.RawMessage(v)
+	}
+	return v
+}
+
+// slackPayload makes JSON body of Slack API call, message which can't be encoded is a permanent error
+func slackPayload(m map[string]interface{}, msg vendors.SlackMessageOptions) ([]byte, error) {
+
+	if !utils.IsEmpty(msg.Thread) {
+		m["thread_ts"] = msg.Thread
+	}
+	if !utils.IsEmpty(msg.Attachments) {
+		m["attachments"] = slackJSON(msg.Attachments)
+	}
+	if !utils.IsEmpty(msg.Blocks) {
+		m["blocks"] = slackJSON(msg.Blocks)
+	}
+
+	body, err := json.Marshal(m)
+	if err != nil {
+		return nil, common.NewStatusError(http.StatusBadRequest, err)
+	}
+	return body, nil
+}
+
+// post calls Slack API method with message in JSON, errors keep status code to decide if delivery is repeated
+func (s *SlackOutput) post(method string, m map[string]interface{}, msg vendors.SlackMessageOptions) ([]byte, error) {
+
+	body, err := slackPayload(m, msg)
+	if err != nil {
+		s.logger.Error(err)
+		return nil, err
+	}
+
+	start := time.Now()
+	b, err := common.HttpPostRaw(s.client, slackAPIURL+method, "application/json; charset=utf-8", "Bearer "+s.options.Token, body)
+	common.ObserveAPIDuration(s.meter, "slack", s.Name(), method, start)
+	if err != nil {
+		s.logger.Error(err)
+		return nil, err
+	}
+
+	var r struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(b, &r); err == nil && !r.OK {
+		err := slackError(method, r.Error)
+		s.logger.Error(err)
+		return nil, err
+	}
+
+	s.logger.Debug("Response from Slack => %s", string(b))
+	return b, nil
+}
+
 func (s *SlackOutput) sendMessage(msg vendors.SlackMessageOptions) ([]byte, error) {
 
 	s.logger.Debug("%+v", msg)
@@ -76,13 +161,58 @@ func (s *SlackOutput) sendMessage(msg vendors.SlackMessageOptions) ([]byte, erro
 		return nil, err
 	}
 
-	b, err := s.slack.SendMessage(msg)
+	m := map[string]interface{}{
+		"channel": msg.Channel,
+		"text":    msg.Text,
+	}
+	return s.post("chat.postMessage", m, msg)
+}
+
+func (s *SlackOutput) updateMessage(channel, ts string, msg vendors.SlackMessageOptions) ([]byte, error) {
+
+	m := map[string]interface{}{
+		"channel": channel,
+		"ts":      ts,
+		"text":    msg.Text,
+	}
+	// thread of edited message can't be changed
+	msg.Thread = ""
+	return s.post("chat.update", m, msg)
+}
+
+// sendStateMessage replies to or edits firing message for resolved event, and remembers message of firing event
+func (s *SlackOutput) sendStateMessage(event *common.Event, msg vendors.SlackMessageOptions) ([]byte, error) {
+
+	state := s.outputs.State()
+
+	var firing *common.StateMessage
+	if s.options.ResolveMode == common.StateModeReply || s.options.ResolveMode == common.StateModeEdit {
+		firing = state.Firing(event, s.Name(), msg.Channel)
+	}
+
+	var b []byte
+	var err error
+	switch {
+	case firing != nil && s.options.ResolveMode == common.StateModeEdit:
+		b, err = s.updateMessage(firing.Target, firing.ID, msg)
+	case firing != nil && utils.IsEmpty(msg.Thread):
+		msg.Thread = firing.ID
+		b, err = s.sendMessage(msg)
+	default:
+		b, err = s.sendMessage(msg)
+	}
 	if err != nil {
-		s.logger.Error(err)
 		return nil, err
 	}
 
-	s.logger.Debug("Response from Slack => %s", string(b))
+	var r struct {
+		Channel string `json:"channel"`
+		TS      string `json:"ts"`
+	}
+	if err := json.Unmarshal(b, &r); err == nil {
+		event.SetMessageID(r.TS)
+		state.Sent(event, &common.StateMessage{Output: s.Name(), Channel: msg.Channel, Target: r.Channel, ID: r.TS})
+	}
 	return b, nil
 }
 
@@ -95,7 +225,9 @@ func (s *SlackOutput) sendErrorMessage(channel string, err error) error {
 		Content: err.Error(),
 		Type:    "text",
 	}
+	start := time.Now()
 	_, e := s.slack.SendFile(fileOpts)
+	common.ObserveAPIDuration(s.meter, "slack", s.Name(), "files.upload", start)
 	return e
 }
 
@@ -109,6 +241,8 @@ func (s *SlackOutput) sendImage(channel, message, fileName, title string, image
 		Content: string(image),
 	}
 
+	start := time.Now()
+	defer common.ObserveAPIDuration(s.meter, "slack", s.Name(), "files.upload", start)
 	return s.slack.SendFile(fileOpts)
 }
 
@@ -197,6 +331,7 @@ func (s *SlackOutput) sendGlobally(event *common.Event, bytes []byte) {
 	via[s.Name()] = obj
 
 	e := common.Event{
+		ID:      event.ID,
 		Time:    event.Time,
 		Channel: event.Channel,
 		Type:    event.Type,
@@ -209,177 +344,205 @@ func (s *SlackOutput) sendGlobally(event *common.Event, bytes []byte) {
 }
 
 func (s *SlackOutput) Send(event *common.Event) {
+
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
+		s.Deliver(event)
+	}()
+}
 
-		if event == nil {
-			s.logger.Debug("Event is empty")
-			return
-		}
+func (s *SlackOutput) Deliver(event *common.Event) error {
+
+	if event == nil {
+		s.logger.Debug("Event is empty")
+		return nil
+	}
 
-		if event.Data == nil {
-			s.logger.Error("Event data is empty")
-			return
+	if event.Data == nil {
+		s.logger.Error("Event data is empty")
+		return nil
+	}
+
+	if common.InterfaceContains(event.Via, s.Name()) {
+		s.logger.Debug("Event has been sent already")
+		return nil
+	}
+
+	jsonMap, err := event.JsonMap()
+	if err != nil {
+		s.logger.Error(err)
+		return nil
+	}
+
+	channel := s.options.Channel
+	var chans []string
+	if s.selector != nil {
+		b, err := s.selector.RenderObject(jsonMap)
+		if err != nil {
+			s.logger.Debug(err)
+		} else {
+			chans = strings.Split(string(b), "\n")
 		}
+	} else {
+		chans = append(chans, channel)
+	}
+
+	if len(chans) == 0 {
+		s.logger.Error(fmt.Sprintf("slack no channels for %s", event.Type))
+		return nil
+	}
+
+	b, err := s.message.RenderObject(jsonMap)
+	if err != nil {
+		s.logger.Error(err)
+		return nil
+	}
+
+	message := strings.TrimSpace(string(b))
+	if utils.IsEmpty(message) {
+		s.logger.Debug("Slack message is empty")
+		return nil
+	}
+
+	s.logger.Debug("Slack message => %s", message)
+
+	var errs []error
+	for _, ch := range chans {
 
-		if common.InterfaceContains(event.Via, s.Name()) {
-			s.logger.Debug("Event has been sent already")
-			return
+		if len(ch) == 0 {
+			continue
 		}
 
-		jsonMap, err := event.JsonMap()
-		if err != nil {
-			s.logger.Error(err)
-			return
+		ch = strings.TrimSpace(ch)
+		// channel got event by a previous attempt
+		if event.Delivered(s.Name(), ch) {
+			continue
 		}
 
-		channel := s.options.Channel
-		var chans []string
-		if s.selector != nil {
-			b, err := s.selector.RenderObject(jsonMap)
+		labels := make(map[string]string)
+		labels["event_channel"] = event.Channel
+		labels["event_type"] = event.Type
+		labels["slack_channel_id"] = ch
+		labels["output"] = s.Name()
+
+		requests := s.meter.Counter("slack", "requests", "Count of all slack requests", labels, "output")
+		requests.Inc()
+
+		errors := s.meter.Counter("slack", "errors", "Count of all slack errors", labels, "output")
+
+		switch event.Type {
+		case "AlertmanagerEvent":
+			msgOptions := vendors.SlackMessageOptions{
+				Channel: ch,
+				Title:   "AlertmanagerEvent",
+				Text:    message,
+			}
+			bytes, err := s.sendStateMessage(event, msgOptions)
 			if err != nil {
-				s.logger.Debug(err)
+				errors.Inc()
+				errs = append(errs, err)
 			} else {
-				chans = strings.Split(string(b), "\n")
+				event.SetDelivered(s.Name(), ch)
+				s.sendGlobally(event, bytes)
+			}
+		case "DataDogEvent":
+			var slackMsg struct {
+				Text        string `json:"text"`
+				Title       string `json:"title"`
+				ImageURL    string `json:"image_url,omitempty"`
+				Attachments string `json:"attachments,omitempty"`
+				Blocks      string `json:"blocks,omitempty"`
+				Thread      string `json:"thread,omitempty"`
 			}
-		} else {
-			chans = append(chans, channel)
-		}
 
-		if len(chans) == 0 {
-			s.logger.Error(fmt.Sprintf("slack no channels for %s", event.Type))
-			return
-		}
+			err = json.Unmarshal([]byte(message), &slackMsg)
+			if err != nil {
+				errors.Inc()
+				s.logger.Error(err)
+				return nil
+			}
 
-		b, err := s.message.RenderObject(jsonMap)
-		if err != nil {
-			s.logger.Error(err)
-			return
-		}
+			msgOptions := vendors.SlackMessageOptions{
+				Channel:     ch,
+				Title:       slackMsg.Title,
+				Text:        slackMsg.Text,
+				Thread:      slackMsg.Thread,
+				Attachments: slackMsg.Attachments,
+				Blocks:      slackMsg.Blocks,
+			}
 
-		message := strings.TrimSpace(string(b))
-		if utils.IsEmpty(message) {
-			s.logger.Debug("Slack message is empty")
-			return
-		}
+			bytes, err := s.sendStateMessage(event, msgOptions)
+			if err != nil {
+				errors.Inc()
+				errs = append(errs, err)
+			} else {
+				event.SetDelivered(s.Name(), ch)
+				s.sendGlobally(event, bytes)
+			}
+		case "ZabbixEvent":
+			jData, err := json.Marshal(event.Data)
+			if err != nil {
+				break
+			}
+			var data processor.ZabbixEvent
+			err = json.Unmarshal(jData, &data)
+			if err != nil {
+				break
+			}
 
-		s.logger.Debug("Slack message => %s", message)
+			color := "#888888"
+			switch data.Status {
+			case "RESOLVED", "OK":
+				color = "#008800"
+			case "PROBLEM", "ERROR", "CRITICAL":
+				color = "#880000"
+			}
 
-		for _, ch := range chans {
+			// Create attachment with color
+			attachment, err := json.Marshal([]slackAttachment{{Color: color, Text: message}})
+			if err != nil {
+				errors.Inc()
+				s.logger.Error(err)
+				return nil
+			}
 
-			if len(ch) == 0 {
-				continue
+			msgOptions := vendors.SlackMessageOptions{
+				Channel:     ch,
+				Title:       "Zabbix Event",
+				Text:        message,
+				Attachments: string(attachment),
 			}
 
-			ch = strings.TrimSpace(ch)
-
-			labels := make(map[string]string)
-			labels["event_channel"] = event.Channel
-			labels["event_type"] = event.Type
-			labels["slack_channel_id"] = ch
-			labels["output"] = s.Name()
-
-			requests := s.meter.Counter("slack", "requests", "Count of all slack requests", labels, "output")
-			requests.Inc()
-
-			errors := s.meter.Counter("slack", "errors", "Count of all slack errors", labels, "output")
-
-			switch event.Type {
-			case "AlertmanagerEvent":
-				msgOptions := vendors.SlackMessageOptions{
-					Channel: ch,
-					Title:   "AlertmanagerEvent",
-					Text:    message,
-				}
-				bytes, err := s.sendMessage(msgOptions)
-				if err != nil {
-					errors.Inc()
-				} else {
-					s.sendGlobally(event, bytes)
-				}
-			case "DataDogEvent":
-				var slackMsg struct {
-					Text        string `json:"text"`
-					Title       string `json:"title"`
-					ImageURL    string `json:"image_url,omitempty"`
-					Attachments string `json:"attachments,omitempty"`
-					Blocks      string `json:"blocks,omitempty"`
-					Thread      string `json:"thread,omitempty"`
-				}
-
-				err = json.Unmarshal([]byte(message), &slackMsg)
-				if err != nil {
-					errors.Inc()
-					s.logger.Error(err)
-					return
-				}
-
-				msgOptions := vendors.SlackMessageOptions{
-					Channel:     ch,
-					Title:       slackMsg.Title,
-					Text:        slackMsg.Text,
-					Thread:      slackMsg.Thread,
-					Attachments: slackMsg.Attachments,
-					Blocks:      slackMsg.Blocks,
-				}
-
-				bytes, err := s.sendMessage(msgOptions)
-				if err != nil {
-					errors.Inc()
-				} else {
-					s.sendGlobally(event, bytes)
-				}
-			case "ZabbixEvent":
-				jData, err := json.Marshal(event.Data)
-				if err != nil {
-					break
-				}
-				var data processor.ZabbixEvent
-				err = json.Unmarshal(jData, &data)
-				if err != nil {
-					break
-				}
-
-				color := "#888888"
-				switch data.Status {
-				case "RESOLVED", "OK":
-					color = "#008800"
-				case "PROBLEM", "ERROR", "CRITICAL":
-					color = "#880000"
-				}
-
-				// Create attachment with color
-				attachment := fmt.Sprintf(`[{"color": "%s", "text": "%s"}]`, color, message)
-
-				msgOptions := vendors.SlackMessageOptions{
-					Channel:     ch,
-					Title:       "Zabbix Event",
-					Text:        message,
-					Attachments: attachment,
-				}
-
-				bytes, err := s.sendMessage(msgOptions)
-				if err != nil {
-					errors.Inc()
-				} else {
-					s.sendGlobally(event, bytes)
-				}
-			default:
-				msgOptions := vendors.SlackMessageOptions{
-					Channel: ch,
-					Title:   "",
-					Text:    message,
-				}
-				bytes, err := s.sendMessage(msgOptions)
-				if err != nil {
-					errors.Inc()
-				} else {
-					s.sendGlobally(event, bytes)
-				}
+			bytes, err := s.sendStateMessage(event, msgOptions)
+			if err != nil {
+				errors.Inc()
+				errs = append(errs, err)
+			} else {
+				event.SetDelivered(s.Name(), ch)
+				s.sendGlobally(event, bytes)
+			}
+		default:
+			msgOptions := vendors.SlackMessageOptions{
+				Channel: ch,
+				Title:   "",
+				Text:    message,
+			}
+			bytes, err := s.sendStateMessage(event, msgOptions)
+			if err != nil {
+				errors.Inc()
+				errs = append(errs, err)
+			} else {
+				event.SetDelivered(s.Name(), ch)
+				s.sendGlobally(event, bytes)
 			}
 		}
-	}()
+	}
+	return errors.Join(errs...)
+}
+
+func (s *SlackOutput) Stop(_ context.Context) error {
+	return nil
 }
 
 func NewSlackOutput(wg *sync.WaitGroup,
@@ -405,10 +568,10 @@ func NewSlackOutput(wg *sync.WaitGroup,
 
 	messageOpts := toolsRender.TemplateOptions{
 		Name:       "slack-message",
-		Content:    common.Content(options.Message),
+		Content:    options.Message,
 		TimeFormat: templateOptions.TimeFormat,
 	}
-	message, err := toolsRender.NewTextTemplate(messageOpts, observability)
+	message, err := common.NewTemplate(messageOpts, observability)
 	if err != nil {
 		logger.Error(err)
 		return nil
@@ -416,16 +579,17 @@ func NewSlackOutput(wg *sync.WaitGroup,
 
 	selectorOpts := toolsRender.TemplateOptions{
 		Name:       "slack-selector",
-		Content:    common.Content(options.ChannelSelector),
+		Content:    options.ChannelSelector,
 		TimeFormat: templateOptions.TimeFormat,
 	}
-	selector, err := toolsRender.NewTextTemplate(selectorOpts, observability)
+	selector, err := common.NewTemplate(selectorOpts, observability)
 	if err != nil {
 		logger.Error(err)
 	}
 
 	return &SlackOutput{
 		wg:       wg,
+		client:   utils.NewHttpClient(options.Timeout, options.Insecure),
 		slack:    slack,
 		message:  message,
 		selector: selector,
@@ -0,0 +1,64 @@
+package output
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/devopsext/tools/vendors"
+)
+
+func TestSlackPayloadAttachments(t *testing.T) {
+
+	message := "Problem \"disk\" on C:\\\nfree space < 5%"
+	attachment, err := json.Marshal([]slackAttachment{{Color: "#880000", Text: message}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		attachments string
+		raw         bool
+	}{
+		{"zabbix attachment with quotes and newlines", string(attachment), true},
+		{"template attachment which isn't json", `[{"text": "` + message + `"}]`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			m := map[string]interface{}{"channel": "C1", "text": message}
+			body, err := slackPayload(m, vendors.SlackMessageOptions{Attachments: tt.attachments, Thread: "1.2"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var payload struct {
+				Text        string          `json:"text"`
+				Thread      string          `json:"thread_ts"`
+				Attachments json.RawMessage `json:"attachments"`
+			}
+			if err := json.Unmarshal(body, &payload); err != nil {
+				t.Fatalf("body %s: %v", body, err)
+			}
+			if payload.Text != message || payload.Thread != "1.2" {
+				t.Fatalf("payload %+v", payload)
+			}
+
+			if !tt.raw {
+				var s string
+				if err := json.Unmarshal(payload.Attachments, &s); err != nil || s != tt.attachments {
+					t.Fatalf("attachments %s, want string %s", payload.Attachments, tt.attachments)
+				}
+				return
+			}
+			var attachments []slackAttachment
+			if err := json.Unmarshal(payload.Attachments, &attachments); err != nil {
+				t.Fatal(err)
+			}
+			if len(attachments) != 1 || attachments[0].Text != message || attachments[0].Color != "#880000" {
+				t.Fatalf("attachments %+v", attachments)
+			}
+		})
+	}
+}
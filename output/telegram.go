@@ -1,10 +1,13 @@
 package output
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"mime/multipart"
+	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
@@ -23,29 +26,36 @@ import (
 )
 
 type TelegramOutputOptions struct {
+	Instance string
 	vendors.TelegramOptions
 	Message         string
 	BotSelector     string
 	AlertExpression string
 	Forward         string
 	RateLimit       int
+	ResolveMode     string
 }
 
+const telegramAPIURL = "https://api.telegram.org/bot%s/%s"
+
 type TelegramOutput struct {
 	wg          *sync.WaitGroup
+	client      *http.Client
 	telegram    *vendors.Telegram
-	message     *toolsRender.TextTemplate
-	selector    *toolsRender.TextTemplate
+	message     *common.Template
+	selector    *common.Template
 	grafana     *render.GrafanaRender
 	options     TelegramOutputOptions
 	outputs     *common.Outputs
 	logger      sreCommon.Logger
 	meter       sreCommon.Meter
 	rateLimiter *rate.Limiter
+	// gauge is kept because prometheus meter binds value to the first gauge instance
+	rateLimiterWait sreCommon.Gauge
 }
 
 func (t *TelegramOutput) Name() string {
-	return "Telegram"
+	return common.OutputName(t.options.Instance, "Telegram")
 }
 
 // assume that url is => https://api.telegram.org/botID:botToken/sendMessage?chat_id=%s
@@ -58,28 +68,56 @@ func (t *TelegramOutput) getBotID(IDToken string) string {
 	return ""
 }
 
+// wait for rate limiter, waiting time shows how much telegram slows down deliveries
+func (t *TelegramOutput) wait() error {
+
+	start := time.Now()
+	err := t.rateLimiter.Wait(context.TODO())
+	t.rateLimiterWait.Set(time.Since(start).Seconds())
+	return err
+}
+
 func (t *TelegramOutput) sendMessage(IDToken, chatID, message string) ([]byte, error) {
+	return t.postMessage(IDToken, "sendMessage", chatID, message, nil)
+}
+
+// postMessage calls method with message and extra fields, errors keep status code of response
+func (t *TelegramOutput) postMessage(IDToken, method, chatID, message string, fields map[string]string) ([]byte, error) {
 
-	if err := t.rateLimiter.Wait(context.TODO()); err != nil {
+	if err := t.wait(); err != nil {
 		return nil, err
 	}
 
-	telegramOpts := vendors.TelegramOptions{
-		IDToken:               IDToken,
-		ChatID:                chatID,
-		Timeout:               t.options.Timeout,
-		Insecure:              false,
-		ParseMode:             t.options.ParseMode,
-		DisableNotification:   t.options.DisableNotification,
-		DisableWebPagePreview: true,
+	parseMode := t.options.ParseMode
+	if utils.IsEmpty(parseMode) {
+		parseMode = "HTML"
 	}
 
-	messageOpts := vendors.TelegramMessageOptions{
-		Text: message,
-	}
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
 
-	b, err := t.telegram.CustomSendMessage(telegramOpts, messageOpts)
+	values := map[string]string{
+		"chat_id":                  chatID,
+		"text":                     message,
+		"parse_mode":               parseMode,
+		"disable_web_page_preview": "true",
+		"disable_notification":     strconv.FormatBool(t.options.DisableNotification),
+	}
+	for k, v := range fields {
+		values[k] = v
+	}
+	for k, v := range values {
+		if err := w.WriteField(k, v); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
 
+	start := time.Now()
+	b, err := common.HttpPostRaw(t.client, fmt.Sprintf(telegramAPIURL, IDToken, method), w.FormDataContentType(), "", body.Bytes())
+	common.ObserveAPIDuration(t.meter, "telegram", t.Name(), method, start)
 	if err != nil {
 		t.logger.Error(err)
 		t.logger.Debug(message)
@@ -87,7 +125,50 @@ func (t *TelegramOutput) sendMessage(IDToken, chatID, message string) ([]byte, e
 	}
 
 	t.logger.Debug("Response from Telegram => %s", string(b))
-	return b, err
+	return b, nil
+}
+
+// channel is chat of bot, it keys both alert state and delivered chats, so bots sharing a chat don't mix them
+func (t *TelegramOutput) channel(IDToken, chatID string) string {
+	return fmt.Sprintf("%s=%s", t.getBotID(IDToken), chatID)
+}
+
+// sendStateMessage replies to or edits firing message for resolved event, and remembers message of firing event
+func (t *TelegramOutput) sendStateMessage(event *common.Event, IDToken, chatID, message string) ([]byte, error) {
+
+	state := t.outputs.State()
+	channel := t.channel(IDToken, chatID)
+
+	var firing *common.StateMessage
+	if t.options.ResolveMode == common.StateModeReply || t.options.ResolveMode == common.StateModeEdit {
+		firing = state.Firing(event, t.Name(), channel)
+	}
+
+	var b []byte
+	var err error
+	switch {
+	case firing != nil && t.options.ResolveMode == common.StateModeEdit:
+		b, err = t.postMessage(IDToken, "editMessageText", chatID, message, map[string]string{"message_id": firing.ID})
+	case firing != nil:
+		b, err = t.postMessage(IDToken, "sendMessage", chatID, message, map[string]string{"reply_to_message_id": firing.ID})
+	default:
+		b, err = t.sendMessage(IDToken, chatID, message)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var r struct {
+		Result struct {
+			MessageID int64 `json:"message_id"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(b, &r); err == nil && r.Result.MessageID > 0 {
+		id := strconv.FormatInt(r.Result.MessageID, 10)
+		event.SetMessageID(id)
+		state.Sent(event, &common.StateMessage{Output: t.Name(), Channel: channel, Target: chatID, ID: id})
+	}
+	return b, nil
 }
 
 func (t *TelegramOutput) sendErrorMessage(IDToken, chatID, message string, err error) error {
@@ -114,6 +195,8 @@ func (t *TelegramOutput) sendPhoto(IDToken, chatID, message, fileName string, ph
 		Content: string(photo),
 	}
 
+	start := time.Now()
+	defer common.ObserveAPIDuration(t.meter, "telegram", t.Name(), "sendPhoto", start)
 	return t.telegram.CustomSendPhoto(telegramOpts, photoOpts)
 }
 
@@ -199,6 +282,7 @@ func (t *TelegramOutput) sendGlobally(event *common.Event, bytes []byte) {
 	via[t.Name()] = obj
 
 	e := common.Event{
+		ID:      event.ID,
 		Time:    event.Time,
 		Channel: event.Channel,
 		Type:    event.Type,
@@ -215,103 +299,124 @@ func (t *TelegramOutput) Send(event *common.Event) {
 	t.wg.Add(1)
 	go func() {
 		defer t.wg.Done()
+		t.Deliver(event)
+	}()
+}
 
-		if event == nil {
-			t.logger.Debug("Event is empty")
-			return
-		}
-
-		if event.Data == nil {
-			t.logger.Error("Event data is empty")
-			return
-		}
+func (t *TelegramOutput) Deliver(event *common.Event) error {
 
-		jsonObject, err := event.JsonObject()
-		if err != nil {
-			t.logger.Error(err)
-			return
-		}
+	if event == nil {
+		t.logger.Debug("Event is empty")
+		return nil
+	}
 
-		IDTokenChatIDs := ""
-		if !utils.IsEmpty(t.options.IDToken) && !utils.IsEmpty(t.options.ChatID) {
-			IDTokenChatIDs = fmt.Sprintf("%s=%s", t.options.IDToken, t.options.ChatID)
-		}
+	if event.Data == nil {
+		t.logger.Error("Event data is empty")
+		return nil
+	}
 
-		if t.selector != nil {
-			b, err := t.selector.RenderObject(jsonObject)
-			if err != nil {
-				t.logger.Debug(err)
-			} else {
-				IDTokenChatIDs = strings.TrimSpace(string(b))
-			}
-		}
+	jsonObject, err := event.JsonObject()
+	if err != nil {
+		t.logger.Error(err)
+		return nil
+	}
 
-		if utils.IsEmpty(IDTokenChatIDs) {
-			t.logger.Debug("Telegram ID token or chat ID are not found. Skipped")
-			return
-		}
+	IDTokenChatIDs := ""
+	if !utils.IsEmpty(t.options.IDToken) && !utils.IsEmpty(t.options.ChatID) {
+		IDTokenChatIDs = fmt.Sprintf("%s=%s", t.options.IDToken, t.options.ChatID)
+	}
 
-		b, err := t.message.RenderObject(jsonObject)
+	if t.selector != nil {
+		b, err := t.selector.RenderObject(jsonObject)
 		if err != nil {
-			t.logger.Error(err)
-			return
+			t.logger.Debug(err)
+		} else {
+			IDTokenChatIDs = strings.TrimSpace(string(b))
 		}
+	}
 
-		message := strings.TrimSpace(string(b))
-		if utils.IsEmpty(message) {
-			t.logger.Debug("Telegram message is empty")
-			return
-		}
+	if utils.IsEmpty(IDTokenChatIDs) {
+		t.logger.Debug("Telegram ID token or chat ID are not found. Skipped")
+		return nil
+	}
 
-		t.logger.Debug("Telegram message => %s", message)
+	b, err := t.message.RenderObject(jsonObject)
+	if err != nil {
+		t.logger.Error(err)
+		return nil
+	}
 
-		list := strings.Split(IDTokenChatIDs, "\n")
-		for _, IDTokenChatID := range list {
+	message := strings.TrimSpace(string(b))
+	if utils.IsEmpty(message) {
+		t.logger.Debug("Telegram message is empty")
+		return nil
+	}
 
-			arr := strings.SplitN(IDTokenChatID, "=", 2)
-			if len(arr) != 2 {
-				continue
-			}
-			if utils.IsEmpty(arr[0]) || utils.IsEmpty(arr[1]) {
-				continue
-			}
+	t.logger.Debug("Telegram message => %s", message)
 
-			IDToken := arr[0]
-			chatID := arr[1]
-			// botID := t.getBotID(IDToken)
+	var errs []error
+	list := strings.Split(IDTokenChatIDs, "\n")
+	for _, IDTokenChatID := range list {
+
+		arr := strings.SplitN(IDTokenChatID, "=", 2)
+		if len(arr) != 2 {
+			continue
+		}
+		if utils.IsEmpty(arr[0]) || utils.IsEmpty(arr[1]) {
+			continue
+		}
+
+		IDToken := arr[0]
+		chatID := arr[1]
+		channel := t.channel(IDToken, chatID)
+		// chat got event by a previous attempt
+		if event.Delivered(t.Name(), channel) {
+			continue
+		}
 
-			labels := make(map[string]string)
-			labels["event_channel"] = event.Channel
-			labels["event_type"] = event.Type
-			labels["telegram_chat_id"] = chatID
-			labels["output"] = t.Name()
+		labels := make(map[string]string)
+		labels["event_channel"] = event.Channel
+		labels["event_type"] = event.Type
+		labels["telegram_chat_id"] = chatID
+		labels["output"] = t.Name()
 
-			requests := t.meter.Counter("telegram", "requests", "Count of all telegram requests", labels, "output")
-			requests.Inc()
+		requests := t.meter.Counter("telegram", "requests", "Count of all telegram requests", labels, "output")
+		requests.Inc()
 
-			errors := t.meter.Counter("telegram", "errors", "Count of all telegram errors", labels, "output")
+		errors := t.meter.Counter("telegram", "errors", "Count of all telegram errors", labels, "output")
 
-			switch event.Type {
-			case "AlertmanagerEvent":
-				// TODO: improve sendAlertmanagerImage to be compatible with telegram output
-				//bytes, err := t.sendAlertmanagerImage(IDToken, chatID, message, event.Data.(template.Alert))
-				bytes, err := t.sendMessage(IDToken, chatID, message)
-				if err != nil {
-					errors.Inc()
+		switch event.Type {
+		case "AlertmanagerEvent":
+			// TODO: improve sendAlertmanagerImage to be compatible with telegram output
+			//bytes, err := t.sendAlertmanagerImage(IDToken, chatID, message, event.Data.(template.Alert))
+			bytes, err := t.sendStateMessage(event, IDToken, chatID, message)
+			if err != nil {
+				errors.Inc()
+				errs = append(errs, err)
+				// error message is sent once if the final attempt fails too
+				event.OnFailure(t.Name(), channel, func() {
 					t.sendErrorMessage(IDToken, chatID, message, err)
-				} else {
-					t.sendGlobally(event, bytes)
-				}
-			default:
-				bytes, err := t.sendMessage(IDToken, chatID, message)
-				if err != nil {
-					errors.Inc()
-				} else {
-					t.sendGlobally(event, bytes)
-				}
+				})
+			} else {
+				event.SetDelivered(t.Name(), channel)
+				t.sendGlobally(event, bytes)
+			}
+		default:
+			bytes, err := t.sendStateMessage(event, IDToken, chatID, message)
+			if err != nil {
+				errors.Inc()
+				errs = append(errs, err)
+			} else {
+				event.SetDelivered(t.Name(), channel)
+				t.sendGlobally(event, bytes)
 			}
 		}
-	}()
+	}
+	return errors.Join(errs...)
+}
+
+func (t *TelegramOutput) Stop(_ context.Context) error {
+	return nil
 }
 
 func NewTelegramOutput(wg *sync.WaitGroup,
@@ -331,10 +436,10 @@ func NewTelegramOutput(wg *sync.WaitGroup,
 
 	messageOpts := toolsRender.TemplateOptions{
 		Name:       "telegram-message",
-		Content:    common.Content(options.Message),
+		Content:    options.Message,
 		TimeFormat: templateOptions.TimeFormat,
 	}
-	message, err := toolsRender.NewTextTemplate(messageOpts, observability)
+	message, err := common.NewTemplate(messageOpts, observability)
 	if err != nil {
 		logger.Error(err)
 		return nil
@@ -342,24 +447,30 @@ func NewTelegramOutput(wg *sync.WaitGroup,
 
 	selectorOpts := toolsRender.TemplateOptions{
 		Name:       "telegram-selector",
-		Content:    common.Content(options.BotSelector),
+		Content:    options.BotSelector,
 		TimeFormat: templateOptions.TimeFormat,
 	}
-	selector, err := toolsRender.NewTextTemplate(selectorOpts, observability)
+	selector, err := common.NewTemplate(selectorOpts, observability)
 	if err != nil {
 		logger.Error(err)
 	}
 
+	labels := make(map[string]string)
+	labels["output"] = common.OutputName(options.Instance, "Telegram")
+	meter := observability.Metrics()
+
 	return &TelegramOutput{
-		rateLimiter: rate.NewLimiter(rate.Every(time.Minute/time.Duration(options.RateLimit)), 1),
-		wg:          wg,
-		telegram:    telegram,
-		message:     message,
-		selector:    selector,
-		grafana:     render.NewGrafanaRender(grafanaRenderOptions, observability),
-		options:     options,
-		outputs:     outputs,
-		logger:      logger,
-		meter:       observability.Metrics(),
+		rateLimiter:     rate.NewLimiter(rate.Every(time.Minute/time.Duration(options.RateLimit)), 1),
+		rateLimiterWait: meter.Gauge("telegram", "wait", "Last wait time of telegram rate limiter in seconds", labels, "output", "rate_limiter"),
+		wg:              wg,
+		client:          utils.NewHttpClient(options.Timeout, options.Insecure),
+		telegram:        telegram,
+		message:         message,
+		selector:        selector,
+		grafana:         render.NewGrafanaRender(grafanaRenderOptions, observability),
+		options:         options,
+		outputs:         outputs,
+		logger:          logger,
+		meter:           meter,
 	}
 }
@@ -2,6 +2,7 @@ package output
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,6 +15,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	sreCommon "github.com/devopsext/sre/common"
 	toolsRender "github.com/devopsext/tools/render"
@@ -26,27 +28,30 @@ import (
 )
 
 type WorkchatOutputOptions struct {
+	Instance         string
 	Message          string
 	URLSelector      string
 	URL              string
 	Timeout          int
 	AlertExpression  string
 	NotificationType string
+	ResolveMode      string
 }
 
 type WorkchatOutput struct {
 	wg       *sync.WaitGroup
 	client   *http.Client
-	message  *toolsRender.TextTemplate
-	selector *toolsRender.TextTemplate
+	message  *common.Template
+	selector *common.Template
 	grafana  *render.GrafanaRender
 	options  WorkchatOutputOptions
+	outputs  *common.Outputs
 	logger   sreCommon.Logger
 	meter    sreCommon.Meter
 }
 
 func (w *WorkchatOutput) Name() string {
-	return "Workchat"
+	return common.OutputName(w.options.Instance, "Workchat")
 }
 
 // assume that url is => https://graph.workplace.com/v9.0/me/messages?access_token=%s&recipient=%s
@@ -89,7 +94,9 @@ func (w *WorkchatOutput) post(URL, contentType string, body bytes.Buffer, messag
 
 	req.Header.Set("Content-Type", contentType)
 
+	start := time.Now()
 	resp, err := w.client.Do(req)
+	common.ObserveAPIDuration(w.meter, "workchat", w.Name(), "post", start)
 	if err != nil {
 		w.logger.Error(err)
 		return nil, err
@@ -105,6 +112,12 @@ func (w *WorkchatOutput) post(URL, contentType string, body bytes.Buffer, messag
 
 	w.logger.Debug("Response from Workchat => %s", string(b))
 
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := common.NewStatusError(resp.StatusCode, fmt.Errorf("workchat response: %s", resp.Status))
+		w.logger.Error(err)
+		return nil, err
+	}
+
 	var object interface{}
 
 	if err := json.Unmarshal(b, &object); err != nil {
@@ -116,6 +129,12 @@ func (w *WorkchatOutput) post(URL, contentType string, body bytes.Buffer, messag
 }
 
 func (w *WorkchatOutput) sendMessage(URL, message string) error {
+	_, err := w.sendReply(URL, message, "")
+	return err
+}
+
+// sendReply sends message as reply to message ID if it's defined and returns ID of sent message
+func (w *WorkchatOutput) sendReply(URL, message, replyTo string) (string, error) {
 
 	var body bytes.Buffer
 	mw := multipart.NewWriter(&body)
@@ -126,20 +145,46 @@ func (w *WorkchatOutput) sendMessage(URL, message string) error {
 	}()
 
 	m := fmt.Sprintf("{\"text\":\"%s\"}", strings.ReplaceAll(message, "\n", "\\n"))
+	if !utils.IsEmpty(replyTo) {
+		m = fmt.Sprintf("{\"text\":\"%s\",\"reply_to\":{\"mid\":\"%s\"}}", strings.ReplaceAll(message, "\n", "\\n"), replyTo)
+	}
 	if err := mw.WriteField("message", m); err != nil {
-		return err
+		return "", err
 	}
 
 	if err := mw.WriteField("notification_type", w.options.NotificationType); err != nil {
-		return err
+		return "", err
 	}
 
 	if err := mw.Close(); err != nil {
-		return err
+		return "", err
 	}
 
-	_, err := w.post(URL, mw.FormDataContentType(), body, message)
-	return err
+	response, err := w.post(URL, mw.FormDataContentType(), body, message)
+	if err != nil {
+		return "", err
+	}
+
+	if r, ok := response.(map[string]interface{}); ok {
+		if id, ok := r["message_id"].(string); ok {
+			return id, nil
+		}
+	}
+	return "", nil
+}
+
+// firing returns message to reply to for resolved event, workchat messages can't be edited, so edit mode replies as well
+func (w *WorkchatOutput) firing(event *common.Event, URL string) *common.StateMessage {
+
+	if w.options.ResolveMode != common.StateModeReply && w.options.ResolveMode != common.StateModeEdit {
+		return nil
+	}
+	return w.outputs.State().Firing(event, w.Name(), URL)
+}
+
+func (w *WorkchatOutput) sent(event *common.Event, URL, id string) {
+	event.SetMessageID(id)
+	w.outputs.State().Sent(event, &common.StateMessage{Output: w.Name(), Channel: URL, ID: id})
 }
 
 func (w *WorkchatOutput) sendErrorMessage(URL, message string, err error) error {
@@ -159,7 +204,7 @@ func (w *WorkchatOutput) createFormFile(writer *multipart.Writer, fieldname, fil
 	return writer.CreatePart(h)
 }
 
-func (w *WorkchatOutput) sendPhoto(URL, message, fileName string, photo []byte) error {
+func (w *WorkchatOutput) sendPhoto(URL, message, fileName string, photo []byte) (string, error) {
 
 	var body bytes.Buffer
 	mw := multipart.NewWriter(&body)
@@ -171,35 +216,35 @@ func (w *WorkchatOutput) sendPhoto(URL, message, fileName string, photo []byte)
 
 	m := "{\"attachment\":{\"type\":\"image\",\"payload\":{\"is_reusable\":true}}}"
 	if err := mw.WriteField("message", m); err != nil {
-		return err
+		return "", err
 	}
 
 	fw, err := w.createFormFile(mw, "filedata", fileName, "image/png")
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	if _, err := fw.Write(photo); err != nil {
-		return err
+		return "", err
 	}
 
 	if err := mw.Close(); err != nil {
-		return err
+		return "", err
 	}
 
 	_, err = w.post(URL, mw.FormDataContentType(), body, message)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return w.sendMessage(URL, message)
+	return w.sendReply(URL, message, "")
 }
 
-func (w *WorkchatOutput) sendAlertmanagerImage(URL, message string, alert template.Alert) error {
+func (w *WorkchatOutput) sendAlertmanagerImage(URL, message string, alert template.Alert) (string, error) {
 
 	u, err := url.Parse(alert.GeneratorURL)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	values := u.Query()
@@ -210,7 +255,7 @@ func (w *WorkchatOutput) sendAlertmanagerImage(URL, message string, alert templa
 	query, ok := alert.Labels[w.options.AlertExpression]
 	if !ok {
 		err := errors.New("no alert expression")
-		return err
+		return "", err
 	}
 
 	caption := alert.Labels["alertname"]
@@ -224,7 +269,7 @@ func (w *WorkchatOutput) sendAlertmanagerImage(URL, message string, alert templa
 
 	expr, err := metricsql.Parse(query)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	metric := query
@@ -244,13 +289,13 @@ func (w *WorkchatOutput) sendAlertmanagerImage(URL, message string, alert templa
 	messageQuery := fmt.Sprintf("%s\n_%s_", message, query)
 
 	if w.grafana == nil {
-		return w.sendMessage(URL, messageQuery)
+		return w.sendReply(URL, messageQuery, "")
 	}
 
 	photo, fileName, err := w.grafana.GenerateDashboard(caption, metric, operator, value, minutes, unit)
 	if err != nil {
 		w.sendErrorMessage(URL, messageQuery, err)
-		return nil
+		return "", nil
 	}
 
 	return w.sendPhoto(URL, messageQuery, fileName, photo)
@@ -261,96 +306,138 @@ func (w *WorkchatOutput) Send(event *common.Event) {
 	w.wg.Add(1)
 	go func() {
 		defer w.wg.Done()
+		w.Deliver(event)
+	}()
+}
 
-		if event == nil {
-			w.logger.Debug("Event is empty")
-			return
-		}
-
-		if event.Data == nil {
-			w.logger.Error("Event data is empty")
-			return
-		}
+func (w *WorkchatOutput) Deliver(event *common.Event) error {
 
-		jsonObject, err := event.JsonObject()
-		if err != nil {
-			w.logger.Error(err)
-			return
-		}
+	if event == nil {
+		w.logger.Debug("Event is empty")
+		return nil
+	}
 
-		URLs := w.options.URL
-		if w.selector != nil {
+	if event.Data == nil {
+		w.logger.Error("Event data is empty")
+		return nil
+	}
 
-			b, err := w.selector.RenderObject(jsonObject)
-			if err != nil {
-				w.logger.Debug(err)
-			} else {
-				URLs = string(b)
-			}
-		}
+	jsonObject, err := event.JsonObject()
+	if err != nil {
+		w.logger.Error(err)
+		return nil
+	}
 
-		if utils.IsEmpty(URLs) {
-			w.logger.Error("Workchat URLs are not found")
-			return
-		}
+	URLs := w.options.URL
+	if w.selector != nil {
 
-		b, err := w.message.RenderObject(jsonObject)
+		b, err := w.selector.RenderObject(jsonObject)
 		if err != nil {
-			w.logger.Error(err)
-			return
+			w.logger.Debug(err)
+		} else {
+			URLs = string(b)
 		}
+	}
 
-		message := strings.TrimSpace(string(b))
-		if utils.IsEmpty(message) {
-			w.logger.Debug("Workchat message is empty")
-			return
-		}
+	if utils.IsEmpty(URLs) {
+		w.logger.Error("Workchat URLs are not found")
+		return nil
+	}
 
-		w.logger.Debug("Workchat message => %s", message)
+	b, err := w.message.RenderObject(jsonObject)
+	if err != nil {
+		w.logger.Error(err)
+		return nil
+	}
 
-		arr := strings.Split(URLs, "\n")
+	message := strings.TrimSpace(string(b))
+	if utils.IsEmpty(message) {
+		w.logger.Debug("Workchat message is empty")
+		return nil
+	}
 
-		labels := make(map[string]string)
-		labels["event_channel"] = event.Channel
-		labels["event_type"] = event.Type
-		labels["output"] = w.Name()
+	w.logger.Debug("Workchat message => %s", message)
 
-		for _, URL := range arr {
+	arr := strings.Split(URLs, "\n")
 
-			URL = strings.TrimSpace(URL)
-			if utils.IsEmpty(URL) {
-				continue
-			}
+	labels := make(map[string]string)
+	labels["event_channel"] = event.Channel
+	labels["event_type"] = event.Type
+	labels["output"] = w.Name()
 
-			labels["workchat_url"] = URL
-			requests := w.meter.Counter("workchat", "requests", "Count of all workchar requests", labels, "output")
+	var errs []error
+	for _, URL := range arr {
 
-			// thread := w.getThread(URL)
-			requests.Inc()
+		URL = strings.TrimSpace(URL)
+		// url got event by a previous attempt
+		if utils.IsEmpty(URL) || event.Delivered(w.Name(), URL) {
+			continue
+		}
 
-			errors := w.meter.Counter("workchat", "errors", "Count of all workchar requests", labels, "output")
+		labels["workchat_url"] = URL
+		requests := w.meter.Counter("workchat", "requests", "Count of all workchar requests", labels, "output")
 
-			switch event.Type {
-			case "AlertmanagerEvent":
-				if err := w.sendAlertmanagerImage(URL, message, event.Data.(template.Alert)); err != nil {
-					errors.Inc()
-					w.sendErrorMessage(URL, message, err)
-				}
-			default:
-				err := w.sendMessage(URL, message)
-				if err != nil {
-					errors.Inc()
+		// thread := w.getThread(URL)
+		requests.Inc()
+
+		errors := w.meter.Counter("workchat", "errors", "Count of all workchar requests", labels, "output")
+
+		if firing := w.firing(event, URL); firing != nil {
+			id, err := w.sendReply(URL, message, firing.ID)
+			if err != nil {
+				errors.Inc()
+				errs = append(errs, err)
+				continue
+			}
+			event.SetDelivered(w.Name(), URL)
+			event.SetMessageID(id)
+			continue
+		}
+
+		switch event.Type {
+		case "AlertmanagerEvent":
+			var alert template.Alert
+			var id string
+			jData, err := json.Marshal(event.Data)
+			if err == nil {
+				err = json.Unmarshal(jData, &alert)
+			}
+			if err == nil {
+				id, err = w.sendAlertmanagerImage(URL, message, alert)
+			}
+			if err != nil {
+				errors.Inc()
+				if err := w.sendErrorMessage(URL, message, err); err != nil {
+					errs = append(errs, err)
 				}
+			} else {
+				event.SetDelivered(w.Name(), URL)
+				w.sent(event, URL, id)
+			}
+		default:
+			id, err := w.sendReply(URL, message, "")
+			if err != nil {
+				errors.Inc()
+				errs = append(errs, err)
+			} else {
+				event.SetDelivered(w.Name(), URL)
+				w.sent(event, URL, id)
 			}
 		}
-	}()
+	}
+	return errors.Join(errs...)
+}
+
+func (w *WorkchatOutput) Stop(_ context.Context) error {
+	return nil
 }
 
 func NewWorkchatOutput(wg *sync.WaitGroup,
 	options WorkchatOutputOptions,
 	templateOptions toolsRender.TemplateOptions,
 	grafanaRenderOptions render.GrafanaRenderOptions,
-	observability *common.Observability) *WorkchatOutput {
+	observability *common.Observability,
+	outputs *common.Outputs) *WorkchatOutput {
 
 	logger := observability.Logs()
 	if utils.IsEmpty(options.URL) {
@@ -360,10 +447,10 @@ func NewWorkchatOutput(wg *sync.WaitGroup,
 
 	messageOpts := toolsRender.TemplateOptions{
 		Name:       "workchat-message",
-		Content:    common.Content(options.Message),
+		Content:    options.Message,
 		TimeFormat: templateOptions.TimeFormat,
 	}
-	message, err := toolsRender.NewTextTemplate(messageOpts, observability)
+	message, err := common.NewTemplate(messageOpts, observability)
 	if err != nil {
 		logger.Error(err)
 		return nil
@@ -371,10 +458,10 @@ func NewWorkchatOutput(wg *sync.WaitGroup,
 
 	selectorOpts := toolsRender.TemplateOptions{
 		Name:       "workchat-selector",
-		Content:    common.Content(options.URLSelector),
+		Content:    options.URLSelector,
 		TimeFormat: templateOptions.TimeFormat,
 	}
-	selector, err := toolsRender.NewTextTemplate(selectorOpts, observability)
+	selector, err := common.NewTemplate(selectorOpts, observability)
 	if err != nil {
 		logger.Error(err)
 	}
@@ -386,6 +473,7 @@ func NewWorkchatOutput(wg *sync.WaitGroup,
 		selector: selector,
 		grafana:  render.NewGrafanaRender(grafanaRenderOptions, observability),
 		options:  options,
+		outputs:  outputs,
 		logger:   logger,
 		meter:    observability.Metrics(),
 	}
@@ -41,7 +41,38 @@ func (p *AlertmanagerProcessor) prepareStatus(status string) string {
 	return title.String(lower.String(status))
 }
 
-func (p *AlertmanagerProcessor) send(channel string, data *template.Data) {
+func (p *AlertmanagerProcessor) alert(alert template.Alert) *common.Alert {
+
+	title := alert.Annotations["summary"]
+	if title == "" {
+		title = alert.Labels["alertname"]
+	}
+
+	entity := alert.Labels["instance"]
+	for _, l := range []string{"pod", "service", "job"} {
+		if entity != "" {
+			break
+		}
+		entity = alert.Labels[l]
+	}
+
+	a := &common.Alert{
+		Fingerprint: alert.Fingerprint,
+		Status:      alert.Status,
+		Severity:    common.AlertSeverity(alert.Labels["severity"]),
+		Title:       title,
+		Description: alert.Annotations["description"],
+		Source:      "alertmanager",
+		Entity:      entity,
+		Labels:      alert.Labels,
+	}
+	a.AddLink("Source", alert.GeneratorURL)
+	a.AddLink("Runbook", alert.Annotations["runbook_url"])
+	a.AddLink("Dashboard", alert.Annotations["dashboard_url"])
+	return a
+}
+
+func (p *AlertmanagerProcessor) send(channel string, span sreCommon.TracerSpan, data *template.Data) {
 
 	for _, alert := range data.Alerts {
 
@@ -49,9 +80,14 @@ func (p *AlertmanagerProcessor) send(channel string, data *template.Data) {
 			Channel: channel,
 			Type:    p.EventType(),
 			Data:    alert,
+			Key:     fmt.Sprintf("%s:%s", alert.Fingerprint, alert.Status),
 		}
+		e.Alert = p.alert(alert)
+		e.State = e.Alert.EventState()
+		e.State.SetEndsAt(alert.EndsAt)
 		e.SetTime(alert.StartsAt.UTC())
 		e.SetLogger(p.logger)
+		e.SetSpan(span)
 
 		p.outputs.Send(e)
 	}
@@ -77,6 +113,7 @@ func (p *AlertmanagerProcessor) HandleEvent(e *common.Event) error {
 func (p *AlertmanagerProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
 
 	channel := strings.TrimLeft(r.URL.Path, "/")
+	span := common.SpanFromContext(r.Context())
 
 	labels := make(map[string]string)
 	labels["path"] = r.URL.Path
@@ -89,9 +126,14 @@ func (p *AlertmanagerProcessor) HandleHttpRequest(w http.ResponseWriter, r *http
 
 	var body []byte
 	if r.Body != nil {
-		if data, err := io.ReadAll(r.Body); err == nil {
-			body = data
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			errors.Inc()
+			p.logger.Error(err)
+			http.Error(w, err.Error(), common.HttpBodyErrorStatus(err))
+			return err
 		}
+		body = data
 	}
 
 	if len(body) == 0 {
@@ -114,7 +156,7 @@ func (p *AlertmanagerProcessor) HandleHttpRequest(w http.ResponseWriter, r *http
 		}
 		errorString = response.Message
 	} else {
-		p.send(channel, &data)
+		p.send(channel, span, &data)
 		response = &AlertmanagerResponse{
 			Message: "OK",
 		}
@@ -41,7 +41,7 @@ func (p *AWSProcessor) EventType() string {
 	return common.AsEventType(AWSProcessorType())
 }
 
-func (p *AWSProcessor) send(channel string, o interface{}, t *time.Time) {
+func (p *AWSProcessor) send(channel string, span sreCommon.TracerSpan, o interface{}, t *time.Time) {
 
 	e := &common.Event{
 		Channel: channel,
@@ -54,6 +54,7 @@ func (p *AWSProcessor) send(channel string, o interface{}, t *time.Time) {
 		e.SetTime(time.Now().UTC())
 	}
 	e.SetLogger(p.logger)
+	e.SetSpan(span)
 	p.outputs.Send(e)
 }
 
@@ -77,6 +78,7 @@ func (p *AWSProcessor) HandleEvent(e *common.Event) error {
 func (p *AWSProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
 
 	channel := strings.TrimLeft(r.URL.Path, "/")
+	span := common.SpanFromContext(r.Context())
 
 	labels := make(map[string]string)
 	labels["path"] = r.URL.Path
@@ -89,9 +91,14 @@ func (p *AWSProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request)
 
 	var body []byte
 	if r.Body != nil {
-		if data, err := ioutil.ReadAll(r.Body); err == nil {
-			body = data
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			errors.Inc()
+			p.logger.Error(err)
+			http.Error(w, err.Error(), common.HttpBodyErrorStatus(err))
+			return err
 		}
+		body = data
 	}
 
 	if len(body) == 0 {
@@ -114,9 +121,9 @@ func (p *AWSProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request)
 
 	if request.Time.UnixMilli() > 0 {
 		t := request.Time
-		p.send(channel, request, &t)
+		p.send(channel, span, request, &t)
 	} else {
-		p.send(channel, request, nil)
+		p.send(channel, span, request, nil)
 	}
 
 	response := &AWSResponse{
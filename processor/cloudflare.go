@@ -35,7 +35,7 @@ func (p *CloudflareProcessor) EventType() string {
 	return common.AsEventType(CloudflareProcessorType())
 }
 
-func (p *CloudflareProcessor) send(channel string, o interface{}, t *time.Time) {
+func (p *CloudflareProcessor) send(channel string, span sreCommon.TracerSpan, o interface{}, t *time.Time) {
 
 	e := &common.Event{
 		Channel: channel,
@@ -48,6 +48,7 @@ func (p *CloudflareProcessor) send(channel string, o interface{}, t *time.Time)
 		e.SetTime(time.Now().UTC())
 	}
 	e.SetLogger(p.logger)
+	e.SetSpan(span)
 	p.outputs.Send(e)
 }
 
@@ -72,6 +73,7 @@ func (p *CloudflareProcessor) HandleEvent(e *common.Event) error {
 func (p *CloudflareProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
 
 	channel := strings.TrimLeft(r.URL.Path, "/")
+	span := common.SpanFromContext(r.Context())
 
 	labels := make(map[string]string)
 	labels["path"] = r.URL.Path
@@ -84,9 +86,14 @@ func (p *CloudflareProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.R
 
 	var body []byte
 	if r.Body != nil {
-		if data, err := ioutil.ReadAll(r.Body); err == nil {
-			body = data
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			errors.Inc()
+			p.logger.Error(err)
+			http.Error(w, err.Error(), common.HttpBodyErrorStatus(err))
+			return err
 		}
+		body = data
 	}
 
 	if len(body) == 0 {
@@ -107,7 +114,7 @@ func (p *CloudflareProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.R
 		return err
 	}
 
-	p.send(channel, Cloudflare, nil)
+	p.send(channel, span, Cloudflare, nil)
 
 	response := &CloudflareResponse{
 		Message: "OK",
@@ -1,19 +1,32 @@
 package processor
 
 import (
+	"bytes"
 	"encoding/json"
 	errPkg "errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/devopsext/events/common"
 	sreCommon "github.com/devopsext/sre/common"
 	"github.com/devopsext/utils"
-	"github.com/prometheus/alertmanager/template"
 )
 
+// CustomJsonProcessorOptions are JSON paths of item like alert.startsAt or items.0.id, fingerprint may be a list of paths
+type CustomJsonProcessorOptions struct {
+	TimePath        string
+	TimeFormat      string
+	ChannelPath     string
+	FingerprintPath string
+}
+
+// CustomJsonProcessor makes event of every JSON object of body, body is an object, an array or NDJSON
 type CustomJsonProcessor struct {
+	options CustomJsonProcessorOptions
 	outputs *common.Outputs
 	logger  sreCommon.Logger
 	meter   sreCommon.Meter
@@ -31,12 +44,156 @@ func (p *CustomJsonProcessor) EventType() string {
 	return common.AsEventType(CustomJsonProcessorType())
 }
 
+// items splits body into JSON values, top level arrays are expanded
+func (p *CustomJsonProcessor) items(body []byte) ([]json.RawMessage, error) {
+
+	var items []json.RawMessage
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	for {
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(raw, []byte("[")) {
+			var list []json.RawMessage
+			if err := json.Unmarshal(raw, &list); err != nil {
+				return nil, err
+			}
+			items = append(items, list...)
+			continue
+		}
+		items = append(items, raw)
+	}
+	return items, nil
+}
+
+// parseTime parses value by format, unix seconds or milliseconds are guessed if format is empty
+func (p *CustomJsonProcessor) parseTime(value string) (time.Time, error) {
+
+	format := p.options.TimeFormat
+	switch strings.ToLower(format) {
+	case "":
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return t, nil
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("time %s is neither RFC3339 nor unix time", value)
+		}
+		// milliseconds are too big to be seconds of this age
+		if f > 1e11 {
+			return time.UnixMilli(int64(f)), nil
+		}
+		return time.Unix(0, int64(f*float64(time.Second))), nil
+	case "unix":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(0, int64(f*float64(time.Second))), nil
+	case "unix_ms":
+		ms, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.UnixMilli(ms), nil
+	default:
+		return time.Parse(format, value)
+	}
+}
+
+func (p *CustomJsonProcessor) time(item []byte) time.Time {
+
+	if utils.IsEmpty(p.options.TimePath) {
+		return time.Now().UTC()
+	}
+	value, ok := common.DataValue(item, p.options.TimePath)
+	if !ok {
+		return time.Now().UTC()
+	}
+	t, err := p.parseTime(value)
+	if err != nil {
+		p.logger.Debug("CustomJson time is not parsed: %v", err)
+		return time.Now().UTC()
+	}
+	return t.UTC()
+}
+
+// fingerprint joins values of paths, it's empty if none of them is found
+func (p *CustomJsonProcessor) fingerprint(item []byte) string {
+
+	var values []string
+	found := false
+	for _, path := range strings.Split(p.options.FingerprintPath, ",") {
+		path = strings.TrimSpace(path)
+		if utils.IsEmpty(path) {
+			continue
+		}
+		value, ok := common.DataValue(item, path)
+		found = found || ok
+		values = append(values, value)
+	}
+	if !found {
+		return ""
+	}
+	return strings.Join(values, ":")
+}
+
+func (p *CustomJsonProcessor) send(channel string, span sreCommon.TracerSpan, item json.RawMessage) error {
+
+	var data interface{}
+	decoder := json.NewDecoder(bytes.NewReader(item))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return err
+	}
+
+	if !utils.IsEmpty(p.options.ChannelPath) {
+		if value, ok := common.DataValue(item, p.options.ChannelPath); ok && !utils.IsEmpty(value) {
+			channel = value
+		}
+	}
+
+	e := &common.Event{
+		Channel: channel,
+		Type:    p.EventType(),
+		Data:    data,
+		Key:     p.fingerprint(item),
+	}
+	e.SetTime(p.time(item))
+	e.SetLogger(p.logger)
+	e.SetSpan(span)
+	p.outputs.Send(e)
+	return nil
+}
+
 func (p *CustomJsonProcessor) HandleEvent(e *common.Event) error {
+
+	if e == nil {
+		p.logger.Debug("Event is not defined")
+		return nil
+	}
+
+	labels := make(map[string]string)
+	labels["event_channel"] = e.Channel
+	labels["processor"] = p.EventType()
+
+	requests := p.meter.Counter("customjson", "requests", "Count of all customjson processor requests", labels, "processor")
+	requests.Inc()
+
+	p.outputs.Send(e)
 	return nil
 }
 
 func (p *CustomJsonProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
 
+	channel := strings.TrimLeft(r.URL.Path, "/")
+	span := common.SpanFromContext(r.Context())
+
 	labels := make(map[string]string)
 	labels["path"] = r.URL.Path
 	labels["processor"] = p.EventType()
@@ -48,12 +205,17 @@ func (p *CustomJsonProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.R
 
 	var body []byte
 	if r.Body != nil {
-		if data, err := ioutil.ReadAll(r.Body); err == nil {
-			body = data
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			errors.Inc()
+			p.logger.Error(err)
+			http.Error(w, err.Error(), common.HttpBodyErrorStatus(err))
+			return err
 		}
+		body = data
 	}
 
-	if len(body) == 0 {
+	if len(bytes.TrimSpace(body)) == 0 {
 		errors.Inc()
 		err := errPkg.New("empty body")
 		p.logger.Error(err)
@@ -63,23 +225,23 @@ func (p *CustomJsonProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.R
 
 	p.logger.Debug("Body => %s", body)
 
-	var response *CustomJsonResponse
-	errorString := ""
-	data := template.Data{}
-	if err := json.Unmarshal(body, &data); err != nil {
-		errorString = err.Error()
+	// whole body is checked before sending, so a broken batch is not sent partially
+	items, err := p.items(body)
+	if err != nil {
+		errors.Inc()
 		p.logger.Error("Can't decode body: %v", err)
-		response = &CustomJsonResponse{
-			Message: errorString,
-		}
-	} else {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
 
-		response = &CustomJsonResponse{
-			Message: "OK",
+	for _, item := range items {
+		if err := p.send(channel, span, item); err != nil {
+			errors.Inc()
+			p.logger.Error("Can't decode item: %v", err)
 		}
 	}
 
-	resp, err := json.Marshal(response)
+	resp, err := json.Marshal(&CustomJsonResponse{Message: "OK"})
 	if err != nil {
 		errors.Inc()
 		p.logger.Error("Can't encode response: %v", err)
@@ -87,26 +249,20 @@ func (p *CustomJsonProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.R
 		return err
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	if _, err := w.Write(resp); err != nil {
 		errors.Inc()
 		p.logger.Error("Can't write response: %v", err)
 		http.Error(w, fmt.Sprintf("could not write response: %v", err), http.StatusInternalServerError)
 		return err
 	}
-
-	if !utils.IsEmpty(errorString) {
-		errors.Inc()
-		err := errPkg.New(errorString)
-		p.logger.Error(errorString)
-		http.Error(w, fmt.Sprint(errorString), http.StatusInternalServerError)
-		return err
-	}
 	return nil
 }
 
-func NewCustomJsonProcessor(outputs *common.Outputs, observability *common.Observability) *CustomJsonProcessor {
+func NewCustomJsonProcessor(options CustomJsonProcessorOptions, outputs *common.Outputs, observability *common.Observability) *CustomJsonProcessor {
 
 	return &CustomJsonProcessor{
+		options: options,
 		outputs: outputs,
 		logger:  observability.Logs(),
 		meter:   observability.Metrics(),
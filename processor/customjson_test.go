@@ -0,0 +1,66 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/devopsext/events/common"
+)
+
+func TestCustomJsonProcessorFixtures(t *testing.T) {
+
+	newProcessor := func(outputs *common.Outputs) common.HttpProcessor {
+		options := CustomJsonProcessorOptions{
+			TimePath:        "finishedAt",
+			ChannelPath:     "service.team",
+			FingerprintPath: "service.name, version",
+		}
+		return NewCustomJsonProcessor(options, outputs, testObservability())
+	}
+
+	tests := []struct {
+		fixture  string
+		channels []string
+		keys     []string
+		times    []time.Time
+	}{
+		{
+			"customjson.json",
+			[]string{"payments", "storefront"},
+			[]string{"billing-api:1.42.0", "checkout-web:3.7.1"},
+			[]time.Time{time.Date(2024, 3, 18, 10, 21, 7, 0, time.UTC), time.UnixMilli(1710757391000).UTC()},
+		},
+		{
+			// every line of NDJSON is an event
+			"customjson.ndjson",
+			[]string{"payments", "payments"},
+			[]string{"billing-api:1.42.1", "billing-api:1.42.1"},
+			[]time.Time{time.Unix(1710757500, 0).UTC(), time.Unix(1710757620, 0).UTC()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+
+			code, events := handleFixture(t, newProcessor, tt.fixture)
+			if code != 200 {
+				t.Fatalf("status %d, want 200", code)
+			}
+			if len(events) != len(tt.keys) {
+				t.Fatalf("%d events, want %d", len(events), len(tt.keys))
+			}
+
+			for i, e := range events {
+				if e.Type != "CustomJsonEvent" || e.Channel != tt.channels[i] || e.Key != tt.keys[i] {
+					t.Fatalf("event %d is %s/%s with key %s, want %s with key %s", i, e.Channel, e.Type, e.Key, tt.channels[i], tt.keys[i])
+				}
+				if !e.Time.Equal(tt.times[i]) {
+					t.Fatalf("event %d time %v, want %v", i, e.Time, tt.times[i])
+				}
+				if _, ok := e.Data.(map[string]interface{}); !ok {
+					t.Fatalf("event %d data %T", i, e.Data)
+				}
+			}
+		})
+	}
+}
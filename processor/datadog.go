@@ -85,12 +85,67 @@ func (p *DataDogProcessor) EventType() string {
 	return common.AsEventType(DataDogProcessorType())
 }
 
-func (p *DataDogProcessor) send(channel string, o interface{}, t *time.Time) {
+// key is alert ID with transition, so re-notifications are duplicates and recoveries are not
+func (r *DataDogRequest) key() string {
+
+	if r.Alert != nil && r.Alert.ID != "" {
+		return fmt.Sprintf("%s:%s", r.Alert.ID, r.Alert.Transition)
+	}
+	return r.ID
+}
+
+// alert is monitor alert with its scope, recovered transition resolves it
+func (r *DataDogRequest) alert() *common.Alert {
+
+	if r.Alert == nil || r.Alert.ID == "" {
+		return nil
+	}
+
+	status := common.StateFiring
+	severity := common.AlertSeverityCritical
+	switch strings.ToLower(r.Alert.Transition) {
+	case "recovered":
+		status = common.StateResolved
+		severity = ""
+	case "warn":
+		severity = common.AlertSeverityWarning
+	}
+
+	title := r.Alert.Title
+	description := r.TextOnlyMsg
+	if r.Event != nil {
+		if title == "" {
+			title = r.Event.Title
+		}
+		if description == "" {
+			description = r.Event.Msg
+		}
+	}
+
+	a := &common.Alert{
+		Fingerprint: fmt.Sprintf("%s:%s", r.Alert.ID, r.Alert.Scope),
+		Status:      status,
+		Severity:    severity,
+		Title:       title,
+		Description: description,
+		Source:      "datadog",
+		Entity:      r.Alert.Scope,
+		Labels:      common.AlertTags(strings.Split(r.Tags, ",")),
+	}
+	a.AddLink("Source", r.Link)
+	a.AddLink("Snapshot", r.Snapshot)
+	return a
+}
+
+func (p *DataDogProcessor) send(channel string, span sreCommon.TracerSpan, o interface{}, key string, alert *common.Alert, t *time.Time) {
 
 	e := &common.Event{
 		Channel: channel,
 		Type:    p.EventType(),
 		Data:    o,
+		Key:     key,
+		Alert:   alert,
+		State:   alert.EventState(),
 	}
 	if t != nil && (*t).UnixNano() > 0 {
 		e.SetTime((*t).UTC())
@@ -98,6 +153,7 @@ func (p *DataDogProcessor) send(channel string, o interface{}, t *time.Time) {
 		e.SetTime(time.Now().UTC())
 	}
 	e.SetLogger(p.logger)
+	e.SetSpan(span)
 	p.outputs.Send(e)
 }
 
@@ -122,6 +178,7 @@ func (p *DataDogProcessor) HandleEvent(e *common.Event) error {
 func (p *DataDogProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
 
 	channel := strings.TrimLeft(r.URL.Path, "/")
+	span := common.SpanFromContext(r.Context())
 
 	labels := make(map[string]string)
 	labels["path"] = r.URL.Path
@@ -134,9 +191,14 @@ func (p *DataDogProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Requ
 
 	var body []byte
 	if r.Body != nil {
-		if data, err := io.ReadAll(r.Body); err == nil {
-			body = data
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			errors.Inc()
+			p.logger.Error(err)
+			http.Error(w, err.Error(), common.HttpBodyErrorStatus(err))
+			return err
 		}
+		body = data
 	}
 
 	if len(body) == 0 {
@@ -158,7 +220,7 @@ func (p *DataDogProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Requ
 	}
 
 	t := time.UnixMilli(datadog.LastUpdated)
-	p.send(channel, datadog, &t)
+	p.send(channel, span, datadog, datadog.key(), datadog.alert(), &t)
 
 	response := &DataDogResponse{
 		Message: "OK",
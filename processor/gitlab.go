@@ -34,7 +34,7 @@ func (p *GitlabProcessor) EventType() string {
 	return common.AsEventType(GitlabProcessorType())
 }
 
-func (p *GitlabProcessor) send(channel string, o interface{}, t *time.Time) {
+func (p *GitlabProcessor) send(channel string, span sreCommon.TracerSpan, o interface{}, t *time.Time) {
 
 	e := &common.Event{
 		Channel: channel,
@@ -47,6 +47,7 @@ func (p *GitlabProcessor) send(channel string, o interface{}, t *time.Time) {
 		e.SetTime(time.Now().UTC())
 	}
 	e.SetLogger(p.logger)
+	e.SetSpan(span)
 	p.outputs.Send(e)
 }
 
@@ -71,6 +72,7 @@ func (p *GitlabProcessor) HandleEvent(e *common.Event) error {
 func (p *GitlabProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
 
 	channel := strings.TrimLeft(r.URL.Path, "/")
+	span := common.SpanFromContext(r.Context())
 
 	labels := make(map[string]string)
 	labels["path"] = r.URL.Path
@@ -83,9 +85,14 @@ func (p *GitlabProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Reque
 
 	var body []byte
 	if r.Body != nil {
-		if data, err := io.ReadAll(r.Body); err == nil {
-			body = data
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			errors.Inc()
+			p.logger.Error(err)
+			http.Error(w, err.Error(), common.HttpBodyErrorStatus(err))
+			return err
 		}
+		body = data
 	}
 
 	if len(body) == 0 {
@@ -113,35 +120,35 @@ func (p *GitlabProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Reque
 
 	switch pl := payload.(type) {
 	case gitlab.PushEventPayload:
-		p.send(channel, payload.(gitlab.PushEventPayload), nil)
+		p.send(channel, span, payload.(gitlab.PushEventPayload), nil)
 	case gitlab.TagEventPayload:
-		p.send(channel, payload.(gitlab.TagEventPayload), nil)
+		p.send(channel, span, payload.(gitlab.TagEventPayload), nil)
 	case gitlab.IssueEventPayload:
 		event := payload.(gitlab.IssueEventPayload)
-		p.send(channel, event, &event.ObjectAttributes.CreatedAt.Time)
+		p.send(channel, span, event, &event.ObjectAttributes.CreatedAt.Time)
 	case gitlab.ConfidentialIssueEventPayload:
 		event := payload.(gitlab.ConfidentialIssueEventPayload)
-		p.send(channel, event, &event.ObjectAttributes.CreatedAt.Time)
+		p.send(channel, span, event, &event.ObjectAttributes.CreatedAt.Time)
 	case gitlab.CommentEventPayload:
 		event := payload.(gitlab.CommentEventPayload)
-		p.send(channel, event, &event.ObjectAttributes.CreatedAt.Time)
+		p.send(channel, span, event, &event.ObjectAttributes.CreatedAt.Time)
 	case gitlab.MergeRequestEventPayload:
 		event := payload.(gitlab.MergeRequestEventPayload)
-		p.send(channel, event, &event.ObjectAttributes.CreatedAt.Time)
+		p.send(channel, span, event, &event.ObjectAttributes.CreatedAt.Time)
 	case gitlab.WikiPageEventPayload:
 		event := payload.(gitlab.WikiPageEventPayload)
-		p.send(channel, event, &event.ObjectAttributes.CreatedAt.Time)
+		p.send(channel, span, event, &event.ObjectAttributes.CreatedAt.Time)
 	case gitlab.PipelineEventPayload:
 		event := payload.(gitlab.PipelineEventPayload)
-		p.send(channel, event, &event.ObjectAttributes.CreatedAt.Time)
+		p.send(channel, span, event, &event.ObjectAttributes.CreatedAt.Time)
 	case gitlab.BuildEventPayload:
 		event := payload.(gitlab.BuildEventPayload)
-		p.send(channel, event, &event.BuildStartedAt.Time)
+		p.send(channel, span, event, &event.BuildStartedAt.Time)
 	case gitlab.JobEventPayload:
 		event := payload.(gitlab.JobEventPayload)
-		p.send(channel, event, &event.BuildStartedAt.Time)
+		p.send(channel, span, event, &event.BuildStartedAt.Time)
 	case gitlab.SystemHookPayload:
-		p.send(channel, payload.(gitlab.SystemHookPayload), nil)
+		p.send(channel, span, payload.(gitlab.SystemHookPayload), nil)
 	default:
 		p.logger.Debug("Not supported %s", pl)
 	}
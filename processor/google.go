@@ -92,12 +92,61 @@ func (p *GoogleProcessor) EventType() string {
 	return common.AsEventType(GoogleProcessorType())
 }
 
-func (p *GoogleProcessor) send(channel string, o interface{}, t *time.Time) {
+// alert is incident, closed state resolves it
+func (r *GoogleRequest) alert() *common.Alert {
+
+	i := r.Incident
+	if i == nil {
+		return nil
+	}
+
+	status := common.StateFiring
+	if strings.EqualFold(i.State, "closed") {
+		status = common.StateResolved
+	}
+
+	labels := make(map[string]string)
+	if i.Resource != nil {
+		for k, v := range i.Resource.Labels {
+			labels[k] = v
+		}
+	}
+	for k, v := range i.PolicyUserLabels {
+		labels[k] = v
+	}
+
+	title := i.PolicyName
+	if title == "" {
+		title = i.ConditionName
+	}
+
+	entity := i.ResourceDisplayName
+	if entity == "" {
+		entity = i.ResourceName
+	}
+
+	a := &common.Alert{
+		Fingerprint: i.IncidentID,
+		Status:      status,
+		Severity:    common.AlertSeverity(i.PolicyUserLabels["severity"]),
+		Title:       title,
+		Description: i.Summary,
+		Source:      "google",
+		Entity:      entity,
+		Labels:      labels,
+	}
+	a.AddLink("Incident", i.URL)
+	return a
+}
+
+func (p *GoogleProcessor) send(channel string, span sreCommon.TracerSpan, o interface{}, alert *common.Alert, t *time.Time) {
 
 	e := &common.Event{
 		Channel: channel,
 		Type:    p.EventType(),
 		Data:    o,
+		Alert:   alert,
+		State:   alert.EventState(),
 	}
 	if t != nil && (*t).UnixNano() > 0 {
 		e.SetTime((*t).UTC())
@@ -105,6 +154,7 @@ func (p *GoogleProcessor) send(channel string, o interface{}, t *time.Time) {
 		e.SetTime(time.Now().UTC())
 	}
 	e.SetLogger(p.logger)
+	e.SetSpan(span)
 	p.outputs.Send(e)
 }
 
@@ -129,6 +179,7 @@ func (p *GoogleProcessor) HandleEvent(e *common.Event) error {
 func (p *GoogleProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
 
 	channel := strings.TrimLeft(r.URL.Path, "/")
+	span := common.SpanFromContext(r.Context())
 
 	labels := make(map[string]string)
 	labels["path"] = r.URL.Path
@@ -141,9 +192,14 @@ func (p *GoogleProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Reque
 
 	var body []byte
 	if r.Body != nil {
-		if data, err := ioutil.ReadAll(r.Body); err == nil {
-			body = data
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			errors.Inc()
+			p.logger.Error(err)
+			http.Error(w, err.Error(), common.HttpBodyErrorStatus(err))
+			return err
 		}
+		body = data
 	}
 
 	if len(body) == 0 {
@@ -166,9 +222,9 @@ func (p *GoogleProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Reque
 
 	if request.Incident.StartedAt > 0 {
 		t := time.UnixMilli(request.Incident.StartedAt)
-		p.send(channel, request, &t)
+		p.send(channel, span, request, request.alert(), &t)
 	} else {
-		p.send(channel, request, nil)
+		p.send(channel, span, request, request.alert(), nil)
 	}
 
 	response := &GoogleResponse{
@@ -0,0 +1,229 @@
+package processor
+
+import (
+	"encoding/json"
+	errPkg "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/devopsext/events/common"
+	sreCommon "github.com/devopsext/sre/common"
+)
+
+type GrafanaProcessor struct {
+	outputs *common.Outputs
+	logger  sreCommon.Logger
+	meter   sreCommon.Meter
+}
+
+type GrafanaResponse struct {
+	Message string
+}
+
+// GrafanaAlert is an alert of Grafana contact point webhook, receiver and org are taken from the payload
+type GrafanaAlert struct {
+	Status       string             `json:"status"`
+	Labels       map[string]string  `json:"labels"`
+	Annotations  map[string]string  `json:"annotations"`
+	StartsAt     time.Time          `json:"startsAt"`
+	EndsAt       time.Time          `json:"endsAt"`
+	GeneratorURL string             `json:"generatorURL"`
+	Fingerprint  string             `json:"fingerprint"`
+	SilenceURL   string             `json:"silenceURL,omitempty"`
+	DashboardURL string             `json:"dashboardURL,omitempty"`
+	PanelURL     string             `json:"panelURL,omitempty"`
+	ImageURL     string             `json:"imageURL,omitempty"`
+	Values       map[string]float64 `json:"values,omitempty"`
+	ValueString  string             `json:"valueString,omitempty"`
+	Receiver     string             `json:"receiver,omitempty"`
+	OrgID        int64              `json:"orgId,omitempty"`
+	ExternalURL  string             `json:"externalURL,omitempty"`
+}
+
+type GrafanaRequest struct {
+	Receiver          string            `json:"receiver"`
+	Status            string            `json:"status"`
+	OrgID             int64             `json:"orgId"`
+	Alerts            []*GrafanaAlert   `json:"alerts"`
+	GroupLabels       map[string]string `json:"groupLabels,omitempty"`
+	CommonLabels      map[string]string `json:"commonLabels,omitempty"`
+	CommonAnnotations map[string]string `json:"commonAnnotations,omitempty"`
+	ExternalURL       string            `json:"externalURL"`
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	TruncatedAlerts   int               `json:"truncatedAlerts,omitempty"`
+	Title             string            `json:"title,omitempty"`
+	State             string            `json:"state,omitempty"`
+	Message           string            `json:"message,omitempty"`
+}
+
+func GrafanaProcessorType() string {
+	return "Grafana"
+}
+
+func (p *GrafanaProcessor) EventType() string {
+	return common.AsEventType(GrafanaProcessorType())
+}
+
+func (a *GrafanaAlert) alert() *common.Alert {
+
+	title := a.Annotations["summary"]
+	if title == "" {
+		title = a.Labels["alertname"]
+	}
+
+	entity := ""
+	for _, l := range []string{"instance", "service", "job", "grafana_folder"} {
+		if entity != "" {
+			break
+		}
+		entity = a.Labels[l]
+	}
+
+	alert := &common.Alert{
+		Fingerprint: a.Fingerprint,
+		Status:      a.Status,
+		Severity:    common.AlertSeverity(a.Labels["severity"]),
+		Title:       title,
+		Description: a.Annotations["description"],
+		Source:      "grafana",
+		Entity:      entity,
+		Labels:      a.Labels,
+	}
+	alert.AddLink("Source", a.GeneratorURL)
+	alert.AddLink("Dashboard", a.DashboardURL)
+	alert.AddLink("Panel", a.PanelURL)
+	alert.AddLink("Silence", a.SilenceURL)
+	alert.AddLink("Image", a.ImageURL)
+	alert.AddLink("Runbook", a.Annotations["runbook_url"])
+	return alert
+}
+
+func (p *GrafanaProcessor) send(channel string, span sreCommon.TracerSpan, data *GrafanaRequest) {
+
+	for _, a := range data.Alerts {
+
+		if a == nil {
+			continue
+		}
+		a.Receiver = data.Receiver
+		a.OrgID = data.OrgID
+		a.ExternalURL = data.ExternalURL
+
+		e := &common.Event{
+			Channel: channel,
+			Type:    p.EventType(),
+			Data:    a,
+			Key:     fmt.Sprintf("%s:%s", a.Fingerprint, a.Status),
+		}
+		e.Alert = a.alert()
+		e.State = e.Alert.EventState()
+		e.State.SetEndsAt(a.EndsAt)
+		if a.StartsAt.UnixNano() > 0 {
+			e.SetTime(a.StartsAt.UTC())
+		} else {
+			e.SetTime(time.Now().UTC())
+		}
+		e.SetLogger(p.logger)
+		e.SetSpan(span)
+		p.outputs.Send(e)
+	}
+}
+
+func (p *GrafanaProcessor) HandleEvent(e *common.Event) error {
+
+	if e == nil {
+		p.logger.Debug("Event is not defined")
+		return nil
+	}
+
+	labels := make(map[string]string)
+	labels["event_channel"] = e.Channel
+	labels["processor"] = p.EventType()
+
+	requests := p.meter.Counter("grafana", "requests", "Count of all grafana processor requests", labels, "processor")
+	requests.Inc()
+
+	p.outputs.Send(e)
+	return nil
+}
+
+func (p *GrafanaProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
+
+	channel := strings.TrimLeft(r.URL.Path, "/")
+	span := common.SpanFromContext(r.Context())
+
+	labels := make(map[string]string)
+	labels["path"] = r.URL.Path
+	labels["processor"] = p.EventType()
+
+	requests := p.meter.Counter("grafana", "requests", "Count of all grafana processor requests", labels, "processor")
+	requests.Inc()
+
+	errors := p.meter.Counter("grafana", "errors", "Count of all grafana processor errors", labels, "processor")
+
+	var body []byte
+	if r.Body != nil {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			errors.Inc()
+			p.logger.Error(err)
+			http.Error(w, err.Error(), common.HttpBodyErrorStatus(err))
+			return err
+		}
+		body = data
+	}
+
+	if len(body) == 0 {
+		errors.Inc()
+		err := errPkg.New("empty body")
+		p.logger.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
+	p.logger.Debug("Body => %s", body)
+
+	var data GrafanaRequest
+	err := json.Unmarshal(body, &data)
+	if err == nil && len(data.Alerts) == 0 {
+		err = errPkg.New("payload has no alerts")
+	}
+	if err != nil {
+		errors.Inc()
+		p.logger.Error("Can't decode body: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
+	p.send(channel, span, &data)
+
+	resp, err := json.Marshal(&GrafanaResponse{Message: "OK"})
+	if err != nil {
+		errors.Inc()
+		p.logger.Error("Can't encode response: %v", err)
+		http.Error(w, fmt.Sprintf("could not encode response: %v", err), http.StatusInternalServerError)
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(resp); err != nil {
+		errors.Inc()
+		p.logger.Error("Can't write response: %v", err)
+		http.Error(w, fmt.Sprintf("could not write response: %v", err), http.StatusInternalServerError)
+		return err
+	}
+	return nil
+}
+
+func NewGrafanaProcessor(outputs *common.Outputs, observability *common.Observability) *GrafanaProcessor {
+
+	return &GrafanaProcessor{
+		outputs: outputs,
+		logger:  observability.Logs(),
+		meter:   observability.Metrics(),
+	}
+}
@@ -0,0 +1,53 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/devopsext/events/common"
+)
+
+func TestGrafanaProcessorFixture(t *testing.T) {
+
+	newProcessor := func(outputs *common.Outputs) common.HttpProcessor {
+		return NewGrafanaProcessor(outputs, testObservability())
+	}
+
+	code, events := handleFixture(t, newProcessor, "grafana.json")
+	if code != 200 {
+		t.Fatalf("status %d, want 200", code)
+	}
+
+	tests := []struct {
+		fingerprint string
+		status      string
+		startsAt    time.Time
+		endsAt      time.Time
+	}{
+		{"6f2c1d0e9b8a7f65", common.StateFiring, time.Date(2024, 3, 18, 10, 20, 0, 0, time.UTC), time.Time{}},
+		{"1a2b3c4d5e6f7a8b", common.StateResolved, time.Date(2024, 3, 18, 9, 55, 0, 0, time.UTC), time.Date(2024, 3, 18, 10, 19, 0, 0, time.UTC)},
+	}
+	if len(events) != len(tests) {
+		t.Fatalf("%d events, want %d", len(events), len(tests))
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.fingerprint, func(t *testing.T) {
+
+			e := events[i]
+			if e.Type != "GrafanaEvent" || e.Key != tt.fingerprint+":"+tt.status || !e.Time.Equal(tt.startsAt) {
+				t.Fatalf("event %s with key %s at %v", e.Type, e.Key, e.Time)
+			}
+			if e.Alert == nil || e.Alert.Fingerprint != tt.fingerprint || e.Alert.Status != tt.status {
+				t.Fatalf("alert %+v, want %s %s", e.Alert, tt.fingerprint, tt.status)
+			}
+			// ends at of firing alert is zero in payload, resolved one keeps resolve time of grafana
+			if e.State == nil || e.State.Status != tt.status || !e.State.EndsAt.Equal(tt.endsAt) {
+				t.Fatalf("state %+v, want %s ending at %v", e.State, tt.status, tt.endsAt)
+			}
+			if a, ok := e.Data.(*GrafanaAlert); !ok || a.Receiver == "" {
+				t.Fatalf("data %+v without receiver of payload", e.Data)
+			}
+		})
+	}
+}
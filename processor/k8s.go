@@ -66,7 +66,7 @@ func (p *K8sProcessor) prepareOperation(operation admv1beta1.Operation) string {
 	return strings.Title(strings.ToLower(string(operation)))
 }
 
-func (p *K8sProcessor) send(channel string, ar *admv1beta1.AdmissionRequest, location string, o interface{}, patch interface{}) {
+func (p *K8sProcessor) send(channel string, span sreCommon.TracerSpan, ar *admv1beta1.AdmissionRequest, location string, o interface{}, patch interface{}) {
 
 	user := &K8sUser{Name: ar.UserInfo.Username, ID: ar.UserInfo.UID}
 	operation := p.prepareOperation(ar.Operation)
@@ -86,10 +86,11 @@ func (p *K8sProcessor) send(channel string, ar *admv1beta1.AdmissionRequest, loc
 	}
 	e.SetTime(time.Now().UTC())
 	e.SetLogger(p.logger)
+	e.SetSpan(span)
 	p.outputs.Send(e)
 }
 
-func (p *K8sProcessor) processNamespace(channel string, ar *admv1beta1.AdmissionRequest) {
+func (p *K8sProcessor) processNamespace(channel string, span sreCommon.TracerSpan, ar *admv1beta1.AdmissionRequest) {
 
 	var old *corev1.Namespace
 	var new *corev1.Namespace
@@ -117,10 +118,10 @@ func (p *K8sProcessor) processNamespace(channel string, ar *admv1beta1.Admission
 	if len(res) > 0 {
 		name = new.Name
 	}
-	p.send(channel, ar, name, res, patch)
+	p.send(channel, span, ar, name, res, patch)
 }
 
-func (p *K8sProcessor) processNode(channel string, ar *admv1beta1.AdmissionRequest) {
+func (p *K8sProcessor) processNode(channel string, span sreCommon.TracerSpan, ar *admv1beta1.AdmissionRequest) {
 
 	var old *corev1.Node
 	var new *corev1.Node
@@ -148,10 +149,10 @@ func (p *K8sProcessor) processNode(channel string, ar *admv1beta1.AdmissionReque
 	if len(res) > 0 {
 		name = new.Name
 	}
-	p.send(channel, ar, name, res, patch)
+	p.send(channel, span, ar, name, res, patch)
 }
 
-func (p *K8sProcessor) processReplicaSet(channel string, ar *admv1beta1.AdmissionRequest) {
+func (p *K8sProcessor) processReplicaSet(channel string, span sreCommon.TracerSpan, ar *admv1beta1.AdmissionRequest) {
 
 	var old *appsv1.ReplicaSet
 	var new *appsv1.ReplicaSet
@@ -181,10 +182,10 @@ func (p *K8sProcessor) processReplicaSet(channel string, ar *admv1beta1.Admissio
 		name = new.Name
 		namespace = new.Namespace
 	}
-	p.send(channel, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
+	p.send(channel, span, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
 }
 
-func (p *K8sProcessor) processStatefulSet(channel string, ar *admv1beta1.AdmissionRequest) {
+func (p *K8sProcessor) processStatefulSet(channel string, span sreCommon.TracerSpan, ar *admv1beta1.AdmissionRequest) {
 
 	var old *appsv1.StatefulSet
 	var new *appsv1.StatefulSet
@@ -214,10 +215,10 @@ func (p *K8sProcessor) processStatefulSet(channel string, ar *admv1beta1.Admissi
 		name = new.Name
 		namespace = new.Namespace
 	}
-	p.send(channel, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
+	p.send(channel, span, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
 }
 
-func (p *K8sProcessor) processDaemonSet(channel string, ar *admv1beta1.AdmissionRequest) {
+func (p *K8sProcessor) processDaemonSet(channel string, span sreCommon.TracerSpan, ar *admv1beta1.AdmissionRequest) {
 
 	var old *appsv1.DaemonSet
 	var new *appsv1.DaemonSet
@@ -247,10 +248,10 @@ func (p *K8sProcessor) processDaemonSet(channel string, ar *admv1beta1.Admission
 		name = new.Name
 		namespace = new.Namespace
 	}
-	p.send(channel, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
+	p.send(channel, span, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
 }
 
-func (p *K8sProcessor) processSecret(channel string, ar *admv1beta1.AdmissionRequest) {
+func (p *K8sProcessor) processSecret(channel string, span sreCommon.TracerSpan, ar *admv1beta1.AdmissionRequest) {
 
 	var old *corev1.Secret
 	var new *corev1.Secret
@@ -280,10 +281,10 @@ func (p *K8sProcessor) processSecret(channel string, ar *admv1beta1.AdmissionReq
 		name = new.Name
 		namespace = new.Namespace
 	}
-	p.send(channel, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
+	p.send(channel, span, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
 }
 
-func (p *K8sProcessor) processIngress(channel string, ar *admv1beta1.AdmissionRequest) {
+func (p *K8sProcessor) processIngress(channel string, span sreCommon.TracerSpan, ar *admv1beta1.AdmissionRequest) {
 
 	var old *netv1.Ingress
 	var new *netv1.Ingress
@@ -313,10 +314,10 @@ func (p *K8sProcessor) processIngress(channel string, ar *admv1beta1.AdmissionRe
 		name = new.Name
 		namespace = new.Namespace
 	}
-	p.send(channel, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
+	p.send(channel, span, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
 }
 
-func (p *K8sProcessor) processJob(channel string, ar *admv1beta1.AdmissionRequest) {
+func (p *K8sProcessor) processJob(channel string, span sreCommon.TracerSpan, ar *admv1beta1.AdmissionRequest) {
 
 	var old *batchv1.Job
 	var new *batchv1.Job
@@ -346,10 +347,10 @@ func (p *K8sProcessor) processJob(channel string, ar *admv1beta1.AdmissionReques
 		name = new.Name
 		namespace = new.Namespace
 	}
-	p.send(channel, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
+	p.send(channel, span, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
 }
 
-func (p *K8sProcessor) processCronJob(channel string, ar *admv1beta1.AdmissionRequest) {
+func (p *K8sProcessor) processCronJob(channel string, span sreCommon.TracerSpan, ar *admv1beta1.AdmissionRequest) {
 
 	var old *batchv1beta.CronJob
 	var new *batchv1beta.CronJob
@@ -379,10 +380,10 @@ func (p *K8sProcessor) processCronJob(channel string, ar *admv1beta1.AdmissionRe
 		name = new.Name
 		namespace = new.Namespace
 	}
-	p.send(channel, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
+	p.send(channel, span, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
 }
 
-func (p *K8sProcessor) processConfigMap(channel string, ar *admv1beta1.AdmissionRequest) {
+func (p *K8sProcessor) processConfigMap(channel string, span sreCommon.TracerSpan, ar *admv1beta1.AdmissionRequest) {
 
 	var old *corev1.ConfigMap
 	var new *corev1.ConfigMap
@@ -412,10 +413,10 @@ func (p *K8sProcessor) processConfigMap(channel string, ar *admv1beta1.Admission
 		name = new.Name
 		namespace = new.Namespace
 	}
-	p.send(channel, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
+	p.send(channel, span, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
 }
 
-func (p *K8sProcessor) processRole(channel string, ar *admv1beta1.AdmissionRequest) {
+func (p *K8sProcessor) processRole(channel string, span sreCommon.TracerSpan, ar *admv1beta1.AdmissionRequest) {
 
 	var old *rbacv1.Role
 	var new *rbacv1.Role
@@ -445,10 +446,10 @@ func (p *K8sProcessor) processRole(channel string, ar *admv1beta1.AdmissionReque
 		name = new.Name
 		namespace = new.Namespace
 	}
-	p.send(channel, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
+	p.send(channel, span, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
 }
 
-func (p *K8sProcessor) processDeployment(channel string, ar *admv1beta1.AdmissionRequest) {
+func (p *K8sProcessor) processDeployment(channel string, span sreCommon.TracerSpan, ar *admv1beta1.AdmissionRequest) {
 
 	var old *appsv1.Deployment
 	var new *appsv1.Deployment
@@ -478,10 +479,10 @@ func (p *K8sProcessor) processDeployment(channel string, ar *admv1beta1.Admissio
 		name = new.Name
 		namespace = new.Namespace
 	}
-	p.send(channel, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
+	p.send(channel, span, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
 }
 
-func (p *K8sProcessor) processService(channel string, ar *admv1beta1.AdmissionRequest) {
+func (p *K8sProcessor) processService(channel string, span sreCommon.TracerSpan, ar *admv1beta1.AdmissionRequest) {
 
 	var old *corev1.Service
 	var new *corev1.Service
@@ -511,10 +512,10 @@ func (p *K8sProcessor) processService(channel string, ar *admv1beta1.AdmissionRe
 		name = new.Name
 		namespace = new.Namespace
 	}
-	p.send(channel, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
+	p.send(channel, span, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
 }
 
-func (p *K8sProcessor) processPod(channel string, ar *admv1beta1.AdmissionRequest) {
+func (p *K8sProcessor) processPod(channel string, span sreCommon.TracerSpan, ar *admv1beta1.AdmissionRequest) {
 
 	var old *corev1.Pod
 	var new *corev1.Pod
@@ -544,10 +545,10 @@ func (p *K8sProcessor) processPod(channel string, ar *admv1beta1.AdmissionReques
 		name = new.Name
 		namespace = new.Namespace
 	}
-	p.send(channel, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
+	p.send(channel, span, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
 }
 
-func (p *K8sProcessor) processArgoApplication(channel string, ar *admv1beta1.AdmissionRequest) {
+func (p *K8sProcessor) processArgoApplication(channel string, span sreCommon.TracerSpan, ar *admv1beta1.AdmissionRequest) {
 	var old *argov1a.Application
 	var new *argov1a.Application
 	res := make(map[string]*argov1a.Application)
@@ -572,7 +573,7 @@ func (p *K8sProcessor) processArgoApplication(channel string, ar *admv1beta1.Adm
 	patch, _ := jsondiff.CompareJSON(ar.OldObject.Raw, ar.Object.Raw)
 	name := new.Name
 	namespace := new.Spec.Destination.Namespace
-	p.send(channel, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
+	p.send(channel, span, ar, fmt.Sprintf("%s.%s", namespace, name), res, patch)
 }
 
 func (p *K8sProcessor) HandleEvent(e *common.Event) error {
@@ -609,6 +610,7 @@ func (p *K8sProcessor) HandleEvent(e *common.Event) error {
 func (p *K8sProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
 
 	channel := strings.TrimLeft(r.URL.Path, "/")
+	span := common.SpanFromContext(r.Context())
 
 	labels := make(map[string]string)
 	labels["path"] = r.URL.Path
@@ -621,9 +623,14 @@ func (p *K8sProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request)
 
 	var body []byte
 	if r.Body != nil {
-		if data, err := ioutil.ReadAll(r.Body); err == nil {
-			body = data
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			errors.Inc()
+			p.logger.Error(err)
+			http.Error(w, err.Error(), common.HttpBodyErrorStatus(err))
+			return err
 		}
+		body = data
 	}
 
 	if len(body) == 0 {
@@ -655,35 +662,35 @@ func (p *K8sProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request)
 		if !*req.DryRun {
 			switch req.Kind.Kind {
 			case "Namespace":
-				p.processNamespace(channel, req)
+				p.processNamespace(channel, span, req)
 			case "Node":
-				p.processNode(channel, req)
+				p.processNode(channel, span, req)
 			case "ReplicaSet":
-				p.processReplicaSet(channel, req)
+				p.processReplicaSet(channel, span, req)
 			case "StatefulSet":
-				p.processStatefulSet(channel, req)
+				p.processStatefulSet(channel, span, req)
 			case "DaemonSet":
-				p.processDaemonSet(channel, req)
+				p.processDaemonSet(channel, span, req)
 			case "Secret":
-				p.processSecret(channel, req)
+				p.processSecret(channel, span, req)
 			case "Ingress":
-				p.processIngress(channel, req)
+				p.processIngress(channel, span, req)
 			case "Job":
-				p.processJob(channel, req)
+				p.processJob(channel, span, req)
 			case "CronJob":
-				p.processCronJob(channel, req)
+				p.processCronJob(channel, span, req)
 			case "ConfigMap":
-				p.processConfigMap(channel, req)
+				p.processConfigMap(channel, span, req)
 			case "Role":
-				p.processRole(channel, req)
+				p.processRole(channel, span, req)
 			case "Deployment":
-				p.processDeployment(channel, req)
+				p.processDeployment(channel, span, req)
 			case "Service":
-				p.processService(channel, req)
+				p.processService(channel, span, req)
 			case "Pod":
-				p.processPod(channel, req)
+				p.processPod(channel, span, req)
 			case "Application":
-				p.processArgoApplication(channel, req)
+				p.processArgoApplication(channel, span, req)
 			}
 		}
 
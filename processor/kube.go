@@ -41,7 +41,7 @@ type EnhancedEvent struct {
 	InvolvedObject EnhancedObjectReference `json:"involvedObject"`
 }
 
-func (p *KubeProcessor) send(channel string, e *EnhancedEvent) error {
+func (p *KubeProcessor) send(channel string, span sreCommon.TracerSpan, e *EnhancedEvent) error {
 	ce := &common.Event{
 		Channel: channel,
 		Type:    p.EventType(),
@@ -56,6 +56,7 @@ func (p *KubeProcessor) send(channel string, e *EnhancedEvent) error {
 	}
 	ce.SetTime(time.Now().UTC())
 	ce.SetLogger(p.logger)
+	ce.SetSpan(span)
 	p.outputs.Send(ce)
 	return nil
 }
@@ -63,6 +64,7 @@ func (p *KubeProcessor) send(channel string, e *EnhancedEvent) error {
 func (p *KubeProcessor) processEvent(
 	w http.ResponseWriter,
 	channel string,
+	span sreCommon.TracerSpan,
 	e *EnhancedEvent,
 ) error {
 
@@ -72,7 +74,7 @@ func (p *KubeProcessor) processEvent(
 
 	errors := p.meter.Counter("kube", "errors", "Count of all kube processor requests", labels, "processor")
 
-	if err := p.send(channel, e); err != nil {
+	if err := p.send(channel, span, e); err != nil {
 		errors.Inc()
 		p.logger.Error("Can't send event: %v", err)
 		http.Error(w, fmt.Sprintf("couldn't send event: %v", err), http.StatusInternalServerError)
@@ -89,6 +91,7 @@ func (p *KubeProcessor) processEvent(
 
 func (p *KubeProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
 	channel := strings.TrimLeft(r.URL.Path, "/")
+	span := common.SpanFromContext(r.Context())
 
 	labels := make(map[string]string)
 	labels["path"] = r.URL.Path
@@ -101,9 +104,14 @@ func (p *KubeProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request
 
 	var body []byte
 	if r.Body != nil {
-		if data, err := io.ReadAll(r.Body); err == nil {
-			body = data
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			errors.Inc()
+			p.logger.Error(err)
+			http.Error(w, err.Error(), common.HttpBodyErrorStatus(err))
+			return err
 		}
+		body = data
 	}
 
 	if len(body) == 0 {
@@ -118,7 +126,7 @@ func (p *KubeProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request
 
 	var e *EnhancedEvent
 	if err := json.Unmarshal(body, &e); err == nil {
-		return p.processEvent(w, channel, e)
+		return p.processEvent(w, channel, span, e)
 	}
 	errorString := fmt.Sprintf("Could not parse body as EnhancedEvent: %s", body)
 	errors.Inc()
@@ -1 +1,311 @@
 package processor
+
+import (
+	"encoding/json"
+	errPkg "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/devopsext/events/common"
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+)
+
+type NewRelicProcessor struct {
+	outputs *common.Outputs
+	logger  sreCommon.Logger
+	meter   sreCommon.Meter
+}
+
+type NewRelicResponse struct {
+	Message string
+}
+
+// NewRelicIssue is a Workflows webhook payload of default template, issueId and issuePageUrl are accepted as well
+type NewRelicIssue struct {
+	ID                  string   `json:"id"`
+	IssueID             string   `json:"issueId,omitempty"`
+	IssueURL            string   `json:"issueUrl,omitempty"`
+	IssuePageURL        string   `json:"issuePageUrl,omitempty"`
+	Title               string   `json:"title"`
+	Priority            string   `json:"priority"`
+	State               string   `json:"state"`
+	Trigger             string   `json:"trigger,omitempty"`
+	IsCorrelated        bool     `json:"isCorrelated,omitempty"`
+	TotalIncidents      int      `json:"totalIncidents,omitempty"`
+	CreatedAt           int64    `json:"createdAt,omitempty"`
+	UpdatedAt           int64    `json:"updatedAt,omitempty"`
+	ImpactedEntities    []string `json:"impactedEntities,omitempty"`
+	Sources             []string `json:"sources,omitempty"`
+	AlertPolicyNames    []string `json:"alertPolicyNames,omitempty"`
+	AlertConditionNames []string `json:"alertConditionNames,omitempty"`
+	WorkflowName        string   `json:"workflowName,omitempty"`
+	ViolationChartURL   string   `json:"violationChartUrl,omitempty"`
+	RunbookURL          string   `json:"runbookUrl,omitempty"`
+}
+
+type NewRelicTarget struct {
+	ID      string            `json:"id"`
+	Name    string            `json:"name"`
+	Link    string            `json:"link,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Product string            `json:"product,omitempty"`
+	Type    string            `json:"type,omitempty"`
+}
+
+// NewRelicIncident is a legacy Alerts notification channel webhook payload
+type NewRelicIncident struct {
+	AccountID              int64             `json:"account_id"`
+	AccountName            string            `json:"account_name"`
+	ConditionID            int64             `json:"condition_id"`
+	ConditionName          string            `json:"condition_name"`
+	CurrentState           string            `json:"current_state"`
+	Details                string            `json:"details"`
+	EventType              string            `json:"event_type"`
+	IncidentID             int64             `json:"incident_id"`
+	IncidentURL            string            `json:"incident_url"`
+	IncidentAcknowledgeURL string            `json:"incident_acknowledge_url,omitempty"`
+	Owner                  string            `json:"owner,omitempty"`
+	PolicyName             string            `json:"policy_name"`
+	PolicyURL              string            `json:"policy_url,omitempty"`
+	RunbookURL             string            `json:"runbook_url,omitempty"`
+	Severity               string            `json:"severity"`
+	Targets                []*NewRelicTarget `json:"targets,omitempty"`
+	Timestamp              int64             `json:"timestamp"`
+	ViolationCallbackURL   string            `json:"violation_callback_url,omitempty"`
+	ViolationChartURL      string            `json:"violation_chart_url,omitempty"`
+}
+
+func NewRelicProcessorType() string {
+	return "NewRelic"
+}
+
+func (p *NewRelicProcessor) EventType() string {
+	return common.AsEventType(NewRelicProcessorType())
+}
+
+// newrelicStatus is firing until issue or incident is closed, acknowledged ones are still firing
+func newrelicStatus(state string) string {
+
+	if strings.EqualFold(state, "closed") {
+		return common.StateResolved
+	}
+	return common.StateFiring
+}
+
+func newrelicTime(ms int64) time.Time {
+
+	if ms > 0 {
+		return time.UnixMilli(ms).UTC()
+	}
+	return time.Now().UTC()
+}
+
+func (i *NewRelicIssue) normalize() {
+
+	if utils.IsEmpty(i.ID) {
+		i.ID = i.IssueID
+	}
+	if utils.IsEmpty(i.IssueURL) {
+		i.IssueURL = i.IssuePageURL
+	}
+}
+
+func (i *NewRelicIssue) alert() *common.Alert {
+
+	status := newrelicStatus(i.State)
+	severity := ""
+	if status == common.StateFiring {
+		severity = common.AlertSeverity(i.Priority)
+	}
+
+	a := &common.Alert{
+		Fingerprint: i.ID,
+		Status:      status,
+		Severity:    severity,
+		Title:       i.Title,
+		Description: strings.Join(i.AlertConditionNames, ", "),
+		Source:      "newrelic",
+		Entity:      strings.Join(i.ImpactedEntities, ", "),
+	}
+	if len(i.AlertPolicyNames) > 0 {
+		a.Labels = map[string]string{"policy": strings.Join(i.AlertPolicyNames, ", ")}
+	}
+	a.AddLink("Issue", i.IssueURL)
+	a.AddLink("Chart", i.ViolationChartURL)
+	a.AddLink("Runbook", i.RunbookURL)
+	return a
+}
+
+func (i *NewRelicIncident) alert() *common.Alert {
+
+	status := newrelicStatus(i.CurrentState)
+	severity := ""
+	if status == common.StateFiring {
+		severity = common.AlertSeverity(i.Severity)
+	}
+
+	var entities []string
+	labels := make(map[string]string)
+	for _, t := range i.Targets {
+		if t == nil {
+			continue
+		}
+		entities = append(entities, t.Name)
+		for k, v := range t.Labels {
+			labels[k] = v
+		}
+	}
+	labels["policy"] = i.PolicyName
+
+	a := &common.Alert{
+		Fingerprint: fmt.Sprintf("%d", i.IncidentID),
+		Status:      status,
+		Severity:    severity,
+		Title:       i.ConditionName,
+		Description: i.Details,
+		Source:      "newrelic",
+		Entity:      strings.Join(entities, ", "),
+		Labels:      labels,
+	}
+	a.AddLink("Incident", i.IncidentURL)
+	a.AddLink("Chart", i.ViolationChartURL)
+	a.AddLink("Runbook", i.RunbookURL)
+	return a
+}
+
+func (p *NewRelicProcessor) send(channel string, span sreCommon.TracerSpan, o interface{}, key string, alert *common.Alert, t time.Time) {
+
+	e := &common.Event{
+		Channel: channel,
+		Type:    p.EventType(),
+		Data:    o,
+		Key:     key,
+		Alert:   alert,
+		State:   alert.EventState(),
+	}
+	e.SetTime(t)
+	e.SetLogger(p.logger)
+	e.SetSpan(span)
+	p.outputs.Send(e)
+}
+
+// decode tells legacy incidents from workflow issues by incident ID, payload without any ID is invalid
+func (p *NewRelicProcessor) decode(channel string, span sreCommon.TracerSpan, body []byte) error {
+
+	var incident NewRelicIncident
+	if err := json.Unmarshal(body, &incident); err == nil && incident.IncidentID > 0 {
+		alert := incident.alert()
+		key := fmt.Sprintf("%d:%s", incident.IncidentID, strings.ToLower(incident.CurrentState))
+		p.send(channel, span, &incident, key, alert, newrelicTime(incident.Timestamp))
+		return nil
+	}
+
+	var issue NewRelicIssue
+	if err := json.Unmarshal(body, &issue); err != nil {
+		return err
+	}
+	issue.normalize()
+	if utils.IsEmpty(issue.ID) {
+		return errPkg.New("payload has neither issue nor incident ID")
+	}
+
+	t := issue.UpdatedAt
+	if t <= 0 {
+		t = issue.CreatedAt
+	}
+	key := fmt.Sprintf("%s:%s", issue.ID, strings.ToLower(issue.State))
+	p.send(channel, span, &issue, key, issue.alert(), newrelicTime(t))
+	return nil
+}
+
+func (p *NewRelicProcessor) HandleEvent(e *common.Event) error {
+
+	if e == nil {
+		p.logger.Debug("Event is not defined")
+		return nil
+	}
+
+	labels := make(map[string]string)
+	labels["event_channel"] = e.Channel
+	labels["processor"] = p.EventType()
+
+	requests := p.meter.Counter("newrelic", "requests", "Count of all newrelic processor requests", labels, "processor")
+	requests.Inc()
+
+	p.outputs.Send(e)
+	return nil
+}
+
+func (p *NewRelicProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
+
+	channel := strings.TrimLeft(r.URL.Path, "/")
+	span := common.SpanFromContext(r.Context())
+
+	labels := make(map[string]string)
+	labels["path"] = r.URL.Path
+	labels["processor"] = p.EventType()
+
+	requests := p.meter.Counter("newrelic", "requests", "Count of all newrelic processor requests", labels, "processor")
+	requests.Inc()
+
+	errors := p.meter.Counter("newrelic", "errors", "Count of all newrelic processor errors", labels, "processor")
+
+	var body []byte
+	if r.Body != nil {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			errors.Inc()
+			p.logger.Error(err)
+			http.Error(w, err.Error(), common.HttpBodyErrorStatus(err))
+			return err
+		}
+		body = data
+	}
+
+	if len(body) == 0 {
+		errors.Inc()
+		err := errPkg.New("empty body")
+		p.logger.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
+	p.logger.Debug("Body => %s", body)
+
+	if err := p.decode(channel, span, body); err != nil {
+		errors.Inc()
+		p.logger.Error("Can't decode body: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
+	resp, err := json.Marshal(&NewRelicResponse{Message: "OK"})
+	if err != nil {
+		errors.Inc()
+		p.logger.Error("Can't encode response: %v", err)
+		http.Error(w, fmt.Sprintf("could not encode response: %v", err), http.StatusInternalServerError)
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(resp); err != nil {
+		errors.Inc()
+		p.logger.Error("Can't write response: %v", err)
+		http.Error(w, fmt.Sprintf("could not write response: %v", err), http.StatusInternalServerError)
+		return err
+	}
+	return nil
+}
+
+func NewNewRelicProcessor(outputs *common.Outputs, observability *common.Observability) *NewRelicProcessor {
+
+	return &NewRelicProcessor{
+		outputs: outputs,
+		logger:  observability.Logs(),
+		meter:   observability.Metrics(),
+	}
+}
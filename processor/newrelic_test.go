@@ -0,0 +1,54 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/devopsext/events/common"
+)
+
+func TestNewRelicProcessorFixtures(t *testing.T) {
+
+	newProcessor := func(outputs *common.Outputs) common.HttpProcessor {
+		return NewNewRelicProcessor(outputs, testObservability())
+	}
+
+	tests := []struct {
+		fixture     string
+		key         string
+		fingerprint string
+		status      string
+		severity    string
+	}{
+		{"newrelic-incident.json", "98765:open", "98765", common.StateFiring, common.AlertSeverityCritical},
+		{"newrelic-issue-activated.json", "b3a9e5a4-5c1d-4f4e-9d1c-7a2f0c3e8d21:activated", "b3a9e5a4-5c1d-4f4e-9d1c-7a2f0c3e8d21", common.StateFiring, common.AlertSeverityCritical},
+		{"newrelic-issue-closed.json", "b3a9e5a4-5c1d-4f4e-9d1c-7a2f0c3e8d21:closed", "b3a9e5a4-5c1d-4f4e-9d1c-7a2f0c3e8d21", common.StateResolved, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+
+			code, events := handleFixture(t, newProcessor, tt.fixture)
+			if code != 200 {
+				t.Fatalf("status %d, want 200", code)
+			}
+			if len(events) != 1 {
+				t.Fatalf("%d events, want 1", len(events))
+			}
+
+			e := events[0]
+			if e.Type != "NewRelicEvent" || e.Key != tt.key {
+				t.Fatalf("event %s with key %s, want key %s", e.Type, e.Key, tt.key)
+			}
+			if e.Alert == nil || e.Alert.Fingerprint != tt.fingerprint || e.Alert.Status != tt.status {
+				t.Fatalf("alert %+v, want %s %s", e.Alert, tt.fingerprint, tt.status)
+			}
+			// resolved alert has no severity
+			if e.Alert.Severity != tt.severity {
+				t.Fatalf("alert severity %q, want %q", e.Alert.Severity, tt.severity)
+			}
+			if e.State == nil || e.State.Fingerprint != tt.fingerprint || e.State.Status != tt.status {
+				t.Fatalf("state %+v, want %s %s", e.State, tt.fingerprint, tt.status)
+			}
+		})
+	}
+}
@@ -41,13 +41,14 @@ func (p *NomadProcessor) EventType() string {
 	return common.AsEventType(NomadProcessorType())
 }
 
-func (p *NomadProcessor) ProcessEvent(ne nomad.Event) error {
+func (p *NomadProcessor) ProcessEvent(ne nomad.Event, span sreCommon.TracerSpan) error {
 	ce := &common.Event{
 		Channel: channel,
 		Type:    p.EventType(),
 		Data:    ne,
 	}
 	ce.SetTime(time.Now().UTC())
+	ce.SetSpan(span)
 	err := p.HandleEvent(ce)
 	if err != nil {
 		return err
@@ -41,12 +41,46 @@ func (p *ObserviumEventProcessor) EventType() string {
 	return common.AsEventType(ObserviumEventProcessorType())
 }
 
-func (p *ObserviumEventProcessor) send(channel string, o interface{}, t *time.Time) {
+// alert is alert entry of device, recover state resolves it
+func (r *ObserviumRequest) alert() *common.Alert {
+
+	fingerprint := r.AlertURL
+	if fingerprint == "" {
+		fingerprint = fmt.Sprintf("%s:%s", r.DeviceHostname, r.Title)
+	}
+
+	status := common.StateFiring
+	severity := common.AlertSeverityCritical
+	switch strings.ToUpper(r.AlertState) {
+	case "RECOVER", "RECOVERY", "OK":
+		status = common.StateResolved
+		severity = ""
+	case "SYSLOG":
+		severity = common.AlertSeverityInfo
+	}
+
+	a := &common.Alert{
+		Fingerprint: fingerprint,
+		Status:      status,
+		Severity:    severity,
+		Title:       r.Title,
+		Description: r.Metrics,
+		Source:      "observium",
+		Entity:      r.DeviceHostname,
+		Labels:      map[string]string{"location": r.DeviceLocation},
+	}
+	a.AddLink("Alert", r.AlertURL)
+	return a
+}
+
+func (p *ObserviumEventProcessor) send(channel string, span sreCommon.TracerSpan, o interface{}, alert *common.Alert, t *time.Time) {
 
 	e := &common.Event{
 		Channel: channel,
 		Type:    p.EventType(),
 		Data:    o,
+		Alert:   alert,
+		State:   alert.EventState(),
 	}
 	if t != nil && (*t).UnixNano() > 0 {
 		e.SetTime((*t).UTC())
@@ -54,6 +88,7 @@ func (p *ObserviumEventProcessor) send(channel string, o interface{}, t *time.Ti
 		e.SetTime(time.Now().UTC())
 	}
 	e.SetLogger(p.logger)
+	e.SetSpan(span)
 	p.outputs.Send(e)
 }
 
@@ -78,6 +113,7 @@ func (p *ObserviumEventProcessor) HandleEvent(e *common.Event) error {
 func (p *ObserviumEventProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
 
 	channel := strings.TrimLeft(r.URL.Path, "/")
+	span := common.SpanFromContext(r.Context())
 
 	labels := make(map[string]string)
 	labels["path"] = r.URL.Path
@@ -90,9 +126,14 @@ func (p *ObserviumEventProcessor) HandleHttpRequest(w http.ResponseWriter, r *ht
 
 	var body []byte
 	if r.Body != nil {
-		if data, err := ioutil.ReadAll(r.Body); err == nil {
-			body = data
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			errors.Inc()
+			p.logger.Error(err)
+			http.Error(w, err.Error(), common.HttpBodyErrorStatus(err))
+			return err
 		}
+		body = data
 	}
 
 	if len(body) == 0 {
@@ -116,7 +157,7 @@ func (p *ObserviumEventProcessor) HandleHttpRequest(w http.ResponseWriter, r *ht
 
 	t := time.Unix(observiumEvent.AlertUnixTime, 0)
 
-	p.send(channel, observiumEvent, &t)
+	p.send(channel, span, observiumEvent, observiumEvent.alert(), &t)
 
 	response := &ObserviumResponse{
 		Message: "OK",
@@ -144,5 +185,6 @@ func NewObserviumEventProcessor(outputs *common.Outputs, observability *common.O
 	return &ObserviumEventProcessor{
 		outputs: outputs,
 		logger:  observability.Logs(),
+		meter:   observability.Metrics(),
 	}
 }
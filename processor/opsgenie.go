@@ -0,0 +1,343 @@
+package processor
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	errPkg "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/devopsext/events/common"
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+)
+
+// OpsgenieProcessorOptions, Opsgenie doesn't sign webhooks, so secret is a custom header of webhook integration, it's required
+type OpsgenieProcessorOptions struct {
+	Header string
+	Secret string
+}
+
+type OpsgenieProcessor struct {
+	options OpsgenieProcessorOptions
+	outputs *common.Outputs
+	logger  sreCommon.Logger
+	meter   sreCommon.Meter
+}
+
+type OpsgenieResponse struct {
+	Message string
+}
+
+type OpsgenieResponder struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// OpsgenieAlertData is an alert of webhook, createdAt is in milliseconds and updatedAt is in nanoseconds
+type OpsgenieAlertData struct {
+	AlertID     string               `json:"alertId"`
+	TinyID      string               `json:"tinyId"`
+	Alias       string               `json:"alias"`
+	Message     string               `json:"message"`
+	Description string               `json:"description,omitempty"`
+	Entity      string               `json:"entity,omitempty"`
+	Source      string               `json:"source,omitempty"`
+	Priority    string               `json:"priority"`
+	Tags        []string             `json:"tags,omitempty"`
+	Teams       []string             `json:"teams,omitempty"`
+	Responders  []*OpsgenieResponder `json:"responders,omitempty"`
+	Details     map[string]string    `json:"details,omitempty"`
+	Owner       string               `json:"owner,omitempty"`
+	Username    string               `json:"username,omitempty"`
+	UserID      string               `json:"userId,omitempty"`
+	Note        string               `json:"note,omitempty"`
+	CreatedAt   int64                `json:"createdAt"`
+	UpdatedAt   int64                `json:"updatedAt"`
+}
+
+type OpsgenieSource struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type OpsgenieRequest struct {
+	Action          string             `json:"action"`
+	Alert           *OpsgenieAlertData `json:"alert"`
+	Source          *OpsgenieSource    `json:"source,omitempty"`
+	IntegrationName string             `json:"integrationName,omitempty"`
+	IntegrationID   string             `json:"integrationId,omitempty"`
+	IntegrationType string             `json:"integrationType,omitempty"`
+}
+
+// OpsgenieAlert is an alert lifecycle event, urgency is high for P1 and P2 priorities
+type OpsgenieAlert struct {
+	Action      string            `json:"action"`
+	ID          string            `json:"id"`
+	TinyID      string            `json:"tinyId,omitempty"`
+	Alias       string            `json:"alias,omitempty"`
+	Message     string            `json:"message"`
+	Description string            `json:"description,omitempty"`
+	Entity      string            `json:"entity,omitempty"`
+	Service     string            `json:"service,omitempty"`
+	Priority    string            `json:"priority,omitempty"`
+	Urgency     string            `json:"urgency,omitempty"`
+	Assignee    string            `json:"assignee,omitempty"`
+	User        string            `json:"user,omitempty"`
+	Teams       []string          `json:"teams,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Note        string            `json:"note,omitempty"`
+	Integration string            `json:"integration,omitempty"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+}
+
+func OpsgenieProcessorType() string {
+	return "Opsgenie"
+}
+
+func (p *OpsgenieProcessor) EventType() string {
+	return common.AsEventType(OpsgenieProcessorType())
+}
+
+func (p *OpsgenieProcessor) verify(r *http.Request) error {
+
+	// requests are rejected if secret is not defined, so webhook is not open by mistake
+	if utils.IsEmpty(p.options.Secret) {
+		return errPkg.New("secret is not defined")
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(p.options.Header)), []byte(p.options.Secret)) != 1 {
+		return fmt.Errorf("header %s is invalid", p.options.Header)
+	}
+	return nil
+}
+
+// opsgenieTime guesses unit of timestamp, seconds, milliseconds and nanoseconds are used by webhooks
+func opsgenieTime(v int64) time.Time {
+
+	switch {
+	case v <= 0:
+		return time.Time{}
+	case v > 1e17:
+		return time.Unix(0, v).UTC()
+	case v > 1e11:
+		return time.UnixMilli(v).UTC()
+	default:
+		return time.Unix(v, 0).UTC()
+	}
+}
+
+func (r *OpsgenieRequest) alert() *OpsgenieAlert {
+
+	d := r.Alert
+	a := &OpsgenieAlert{
+		Action:      r.Action,
+		ID:          d.AlertID,
+		TinyID:      d.TinyID,
+		Alias:       d.Alias,
+		Message:     d.Message,
+		Description: d.Description,
+		Entity:      d.Entity,
+		Service:     d.Source,
+		Priority:    d.Priority,
+		Assignee:    d.Owner,
+		User:        d.Username,
+		Tags:        d.Tags,
+		Details:     d.Details,
+		Note:        d.Note,
+		Integration: r.IntegrationName,
+		CreatedAt:   opsgenieTime(d.CreatedAt),
+		UpdatedAt:   opsgenieTime(d.UpdatedAt),
+	}
+	if utils.IsEmpty(a.Assignee) && strings.EqualFold(r.Action, "Acknowledge") {
+		a.Assignee = d.Username
+	}
+	// teams of alert are IDs, names are known by responders only
+	for _, rs := range d.Responders {
+		if rs != nil && rs.Type == "team" {
+			a.Teams = append(a.Teams, rs.Name)
+		}
+	}
+	if len(a.Teams) == 0 {
+		a.Teams = d.Teams
+	}
+	switch strings.ToUpper(d.Priority) {
+	case "P1", "P2":
+		a.Urgency = "high"
+	case "":
+	default:
+		a.Urgency = "low"
+	}
+	return a
+}
+
+// state is made of lifecycle actions only, notes don't change alert state
+func (a *OpsgenieAlert) state() *common.Alert {
+
+	status := ""
+	switch strings.ToLower(a.Action) {
+	case "create", "acknowledge", "unacknowledge", "escalate":
+		status = common.StateFiring
+	case "close":
+		status = common.StateResolved
+	default:
+		return nil
+	}
+
+	severity := common.AlertSeverity(a.Priority)
+	if status == common.StateResolved {
+		severity = ""
+	}
+
+	alert := &common.Alert{
+		Fingerprint: a.ID,
+		Status:      status,
+		Severity:    severity,
+		Title:       a.Message,
+		Description: a.Description,
+		Source:      "opsgenie",
+		Entity:      a.Entity,
+		Labels:      common.AlertTags(a.Tags),
+	}
+	if alert.Entity == "" {
+		alert.Entity = a.Service
+	}
+	return alert
+}
+
+func (p *OpsgenieProcessor) send(channel string, span sreCommon.TracerSpan, a *OpsgenieAlert) {
+
+	t := a.UpdatedAt
+	if t.IsZero() {
+		t = a.CreatedAt
+	}
+
+	e := &common.Event{
+		Channel: channel,
+		Type:    p.EventType(),
+		Data:    a,
+		Key:     fmt.Sprintf("%s:%s:%d", a.ID, a.Action, t.UnixNano()),
+	}
+	e.Alert = a.state()
+	e.State = e.Alert.EventState()
+	if !t.IsZero() {
+		e.SetTime(t)
+	} else {
+		e.SetTime(time.Now().UTC())
+	}
+	e.SetLogger(p.logger)
+	e.SetSpan(span)
+	p.outputs.Send(e)
+}
+
+func (p *OpsgenieProcessor) HandleEvent(e *common.Event) error {
+
+	if e == nil {
+		p.logger.Debug("Event is not defined")
+		return nil
+	}
+
+	labels := make(map[string]string)
+	labels["event_channel"] = e.Channel
+	labels["processor"] = p.EventType()
+
+	requests := p.meter.Counter("opsgenie", "requests", "Count of all opsgenie processor requests", labels, "processor")
+	requests.Inc()
+
+	p.outputs.Send(e)
+	return nil
+}
+
+func (p *OpsgenieProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
+
+	channel := strings.TrimLeft(r.URL.Path, "/")
+	span := common.SpanFromContext(r.Context())
+
+	labels := make(map[string]string)
+	labels["path"] = r.URL.Path
+	labels["processor"] = p.EventType()
+
+	requests := p.meter.Counter("opsgenie", "requests", "Count of all opsgenie processor requests", labels, "processor")
+	requests.Inc()
+
+	errors := p.meter.Counter("opsgenie", "errors", "Count of all opsgenie processor errors", labels, "processor")
+
+	if err := p.verify(r); err != nil {
+		unauthorized := p.meter.Counter("opsgenie", "unauthorized", "Count of all opsgenie processor requests with invalid secret", labels, "processor")
+		unauthorized.Inc()
+		p.logger.Debug("Opsgenie request to %s is unauthorized: %v", r.URL.Path, err)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return err
+	}
+
+	var body []byte
+	if r.Body != nil {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			errors.Inc()
+			p.logger.Error(err)
+			http.Error(w, err.Error(), common.HttpBodyErrorStatus(err))
+			return err
+		}
+		body = data
+	}
+
+	if len(body) == 0 {
+		errors.Inc()
+		err := errPkg.New("empty body")
+		p.logger.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
+	p.logger.Debug("Body => %s", body)
+
+	var data OpsgenieRequest
+	err := json.Unmarshal(body, &data)
+	if err == nil && (data.Alert == nil || utils.IsEmpty(data.Action)) {
+		err = errPkg.New("payload has no action or alert")
+	}
+	if err != nil {
+		errors.Inc()
+		p.logger.Error("Can't decode body: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
+	p.send(channel, span, data.alert())
+
+	resp, err := json.Marshal(&OpsgenieResponse{Message: "OK"})
+	if err != nil {
+		errors.Inc()
+		p.logger.Error("Can't encode response: %v", err)
+		http.Error(w, fmt.Sprintf("could not encode response: %v", err), http.StatusInternalServerError)
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(resp); err != nil {
+		errors.Inc()
+		p.logger.Error("Can't write response: %v", err)
+		http.Error(w, fmt.Sprintf("could not write response: %v", err), http.StatusInternalServerError)
+		return err
+	}
+	return nil
+}
+
+func NewOpsgenieProcessor(options OpsgenieProcessorOptions, outputs *common.Outputs, observability *common.Observability) *OpsgenieProcessor {
+
+	if utils.IsEmpty(options.Header) {
+		options.Header = "X-Opsgenie-Secret"
+	}
+	return &OpsgenieProcessor{
+		options: options,
+		outputs: outputs,
+		logger:  observability.Logs(),
+		meter:   observability.Metrics(),
+	}
+}
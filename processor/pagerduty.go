@@ -0,0 +1,340 @@
+package processor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	errPkg "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/devopsext/events/common"
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+)
+
+const pagerDutySignatureHeader = "X-PagerDuty-Signature"
+
+// PagerDutyProcessorOptions secret is a signing secret of webhook subscription, it's required
+type PagerDutyProcessorOptions struct {
+	Secret string
+}
+
+type PagerDutyProcessor struct {
+	options PagerDutyProcessorOptions
+	outputs *common.Outputs
+	logger  sreCommon.Logger
+	meter   sreCommon.Meter
+}
+
+type PagerDutyResponse struct {
+	Message string
+}
+
+type PagerDutyReference struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Summary string `json:"summary"`
+	Self    string `json:"self,omitempty"`
+	HtmlURL string `json:"html_url,omitempty"`
+}
+
+// PagerDutyData is an incident, or a note which refers to incident for incident.annotated
+type PagerDutyData struct {
+	ID               string                `json:"id"`
+	Type             string                `json:"type"`
+	HtmlURL          string                `json:"html_url,omitempty"`
+	Number           int64                 `json:"number,omitempty"`
+	Status           string                `json:"status,omitempty"`
+	IncidentKey      string                `json:"incident_key,omitempty"`
+	CreatedAt        time.Time             `json:"created_at,omitempty"`
+	Title            string                `json:"title,omitempty"`
+	Urgency          string                `json:"urgency,omitempty"`
+	Service          *PagerDutyReference   `json:"service,omitempty"`
+	Assignees        []*PagerDutyReference `json:"assignees,omitempty"`
+	EscalationPolicy *PagerDutyReference   `json:"escalation_policy,omitempty"`
+	Teams            []*PagerDutyReference `json:"teams,omitempty"`
+	Priority         *PagerDutyReference   `json:"priority,omitempty"`
+	Incident         *PagerDutyReference   `json:"incident,omitempty"`
+	Content          string                `json:"content,omitempty"`
+}
+
+type PagerDutyEvent struct {
+	ID           string              `json:"id"`
+	EventType    string              `json:"event_type"`
+	ResourceType string              `json:"resource_type"`
+	OccurredAt   time.Time           `json:"occurred_at"`
+	Agent        *PagerDutyReference `json:"agent,omitempty"`
+	Data         *PagerDutyData      `json:"data"`
+}
+
+type PagerDutyRequest struct {
+	Event *PagerDutyEvent `json:"event"`
+}
+
+// PagerDutyIncident is an incident lifecycle event, note is set for annotated ones
+type PagerDutyIncident struct {
+	EventID    string    `json:"eventId"`
+	EventType  string    `json:"eventType"`
+	OccurredAt time.Time `json:"occurredAt"`
+	ID         string    `json:"id"`
+	Number     int64     `json:"number,omitempty"`
+	Title      string    `json:"title,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	Urgency    string    `json:"urgency,omitempty"`
+	Priority   string    `json:"priority,omitempty"`
+	Service    string    `json:"service,omitempty"`
+	ServiceID  string    `json:"serviceId,omitempty"`
+	Assignees  []string  `json:"assignees,omitempty"`
+	Teams      []string  `json:"teams,omitempty"`
+	Agent      string    `json:"agent,omitempty"`
+	Note       string    `json:"note,omitempty"`
+	URL        string    `json:"url,omitempty"`
+	CreatedAt  time.Time `json:"createdAt,omitempty"`
+}
+
+func PagerDutyProcessorType() string {
+	return "PagerDuty"
+}
+
+func (p *PagerDutyProcessor) EventType() string {
+	return common.AsEventType(PagerDutyProcessorType())
+}
+
+// verify checks v1 signatures of header, there are several of them while secret is rotated
+func (p *PagerDutyProcessor) verify(r *http.Request, body []byte) error {
+
+	// requests are rejected if secret is not defined, so webhook is not open by mistake
+	if utils.IsEmpty(p.options.Secret) {
+		return errPkg.New("secret is not defined")
+	}
+
+	mac := hmac.New(sha256.New, []byte(p.options.Secret))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+
+	for _, s := range strings.Split(r.Header.Get(pagerDutySignatureHeader), ",") {
+		s = strings.TrimSpace(s)
+		if !strings.HasPrefix(s, "v1=") {
+			continue
+		}
+		sig, err := hex.DecodeString(strings.TrimPrefix(s, "v1="))
+		if err == nil && hmac.Equal(sig, expected) {
+			return nil
+		}
+	}
+	return errPkg.New("signature is invalid")
+}
+
+func pagerDutySummaries(refs []*PagerDutyReference) []string {
+
+	var r []string
+	for _, ref := range refs {
+		if ref != nil {
+			r = append(r, ref.Summary)
+		}
+	}
+	return r
+}
+
+func (e *PagerDutyEvent) incident() *PagerDutyIncident {
+
+	d := e.Data
+	i := &PagerDutyIncident{
+		EventID:    e.ID,
+		EventType:  e.EventType,
+		OccurredAt: e.OccurredAt,
+		ID:         d.ID,
+		Number:     d.Number,
+		Title:      d.Title,
+		Status:     d.Status,
+		Urgency:    d.Urgency,
+		Assignees:  pagerDutySummaries(d.Assignees),
+		Teams:      pagerDutySummaries(d.Teams),
+		URL:        d.HtmlURL,
+		CreatedAt:  d.CreatedAt,
+	}
+	if d.Incident != nil {
+		i.ID = d.Incident.ID
+		i.Title = d.Incident.Summary
+		i.URL = d.Incident.HtmlURL
+		i.Note = d.Content
+	}
+	if d.Service != nil {
+		i.Service = d.Service.Summary
+		i.ServiceID = d.Service.ID
+	}
+	if d.Priority != nil {
+		i.Priority = d.Priority.Summary
+	}
+	if e.Agent != nil {
+		i.Agent = e.Agent.Summary
+	}
+	return i
+}
+
+// alert is made of status changes only, notes don't change alert state
+func (i *PagerDutyIncident) alert() *common.Alert {
+
+	status := ""
+	switch i.EventType {
+	case "incident.triggered", "incident.acknowledged", "incident.unacknowledged", "incident.reopened":
+		status = common.StateFiring
+	case "incident.resolved":
+		status = common.StateResolved
+	default:
+		return nil
+	}
+
+	severity := common.AlertSeverity(i.Priority)
+	if severity == "" {
+		severity = common.AlertSeverity(i.Urgency)
+	}
+	if status == common.StateResolved {
+		severity = ""
+	}
+
+	a := &common.Alert{
+		Fingerprint: i.ID,
+		Status:      status,
+		Severity:    severity,
+		Title:       i.Title,
+		Source:      "pagerduty",
+		Entity:      i.Service,
+	}
+	a.AddLink("Incident", i.URL)
+	return a
+}
+
+func (p *PagerDutyProcessor) send(channel string, span sreCommon.TracerSpan, i *PagerDutyIncident) {
+
+	e := &common.Event{
+		Channel: channel,
+		Type:    p.EventType(),
+		Data:    i,
+		Key:     i.EventID,
+	}
+	e.Alert = i.alert()
+	e.State = e.Alert.EventState()
+	if i.OccurredAt.UnixNano() > 0 {
+		e.SetTime(i.OccurredAt.UTC())
+	} else {
+		e.SetTime(time.Now().UTC())
+	}
+	e.SetLogger(p.logger)
+	e.SetSpan(span)
+	p.outputs.Send(e)
+}
+
+func (p *PagerDutyProcessor) HandleEvent(e *common.Event) error {
+
+	if e == nil {
+		p.logger.Debug("Event is not defined")
+		return nil
+	}
+
+	labels := make(map[string]string)
+	labels["event_channel"] = e.Channel
+	labels["processor"] = p.EventType()
+
+	requests := p.meter.Counter("pagerduty", "requests", "Count of all pagerduty processor requests", labels, "processor")
+	requests.Inc()
+
+	p.outputs.Send(e)
+	return nil
+}
+
+func (p *PagerDutyProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
+
+	channel := strings.TrimLeft(r.URL.Path, "/")
+	span := common.SpanFromContext(r.Context())
+
+	labels := make(map[string]string)
+	labels["path"] = r.URL.Path
+	labels["processor"] = p.EventType()
+
+	requests := p.meter.Counter("pagerduty", "requests", "Count of all pagerduty processor requests", labels, "processor")
+	requests.Inc()
+
+	errors := p.meter.Counter("pagerduty", "errors", "Count of all pagerduty processor errors", labels, "processor")
+
+	var body []byte
+	if r.Body != nil {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			errors.Inc()
+			p.logger.Error(err)
+			http.Error(w, err.Error(), common.HttpBodyErrorStatus(err))
+			return err
+		}
+		body = data
+	}
+
+	if len(body) == 0 {
+		errors.Inc()
+		err := errPkg.New("empty body")
+		p.logger.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
+	if err := p.verify(r, body); err != nil {
+		unauthorized := p.meter.Counter("pagerduty", "unauthorized", "Count of all pagerduty processor requests with invalid signature", labels, "processor")
+		unauthorized.Inc()
+		p.logger.Debug("PagerDuty request to %s is unauthorized: %v", r.URL.Path, err)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return err
+	}
+
+	p.logger.Debug("Body => %s", body)
+
+	var data PagerDutyRequest
+	err := json.Unmarshal(body, &data)
+	if err == nil && (data.Event == nil || data.Event.Data == nil) {
+		err = errPkg.New("payload has no event data")
+	}
+	if err != nil {
+		errors.Inc()
+		p.logger.Error("Can't decode body: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
+	// pings and events of other resources are answered, otherwise they are sent again
+	if strings.HasPrefix(data.Event.EventType, "incident.") {
+		p.send(channel, span, data.Event.incident())
+	} else {
+		p.logger.Debug("PagerDuty event %s is skipped", data.Event.EventType)
+	}
+
+	resp, err := json.Marshal(&PagerDutyResponse{Message: "OK"})
+	if err != nil {
+		errors.Inc()
+		p.logger.Error("Can't encode response: %v", err)
+		http.Error(w, fmt.Sprintf("could not encode response: %v", err), http.StatusInternalServerError)
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(resp); err != nil {
+		errors.Inc()
+		p.logger.Error("Can't write response: %v", err)
+		http.Error(w, fmt.Sprintf("could not write response: %v", err), http.StatusInternalServerError)
+		return err
+	}
+	return nil
+}
+
+func NewPagerDutyProcessor(options PagerDutyProcessorOptions, outputs *common.Outputs, observability *common.Observability) *PagerDutyProcessor {
+
+	return &PagerDutyProcessor{
+		options: options,
+		outputs: outputs,
+		logger:  observability.Logs(),
+		meter:   observability.Metrics(),
+	}
+}
@@ -1,10 +1,20 @@
 package processor
 
 import (
+	"bytes"
+	"encoding/json"
+	errPkg "errors"
+	"fmt"
+	"io"
 	"net/http"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/devopsext/events/common"
 	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+	"github.com/prometheus/alertmanager/template"
 )
 
 type RancherProcessor struct {
@@ -13,6 +23,55 @@ type RancherProcessor struct {
 	meter   sreCommon.Meter
 }
 
+type RancherResponse struct {
+	Message string
+}
+
+// RancherContext is cluster and project where event happened
+type RancherContext struct {
+	ClusterID   string `json:"clusterId,omitempty"`
+	ClusterName string `json:"clusterName,omitempty"`
+	ProjectID   string `json:"projectId,omitempty"`
+	ProjectName string `json:"projectName,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+}
+
+// RancherAlert is an alert of Rancher v2 alerting or notifier webhook, they are Alertmanager compatible
+type RancherAlert struct {
+	template.Alert
+	Receiver string         `json:"receiver,omitempty"`
+	Rancher  RancherContext `json:"rancher"`
+}
+
+type RancherAuditUser struct {
+	Name  string              `json:"name"`
+	Group []string            `json:"group,omitempty"`
+	Extra map[string][]string `json:"extra,omitempty"`
+}
+
+// RancherAudit is an entry of Rancher API audit log
+type RancherAudit struct {
+	AuditID        string           `json:"auditID"`
+	RequestURI     string           `json:"requestURI"`
+	SourceIPs      []string         `json:"sourceIPs,omitempty"`
+	User           RancherAuditUser `json:"user"`
+	Method         string           `json:"method"`
+	Verb           string           `json:"verb,omitempty"`
+	Stage          string           `json:"stage,omitempty"`
+	StageTimestamp string           `json:"stageTimestamp,omitempty"`
+	RequestBody    interface{}      `json:"requestBody,omitempty"`
+	ResponseBody   interface{}      `json:"responseBody,omitempty"`
+	ResponseStatus interface{}      `json:"responseStatus,omitempty"`
+	Rancher        RancherContext   `json:"rancher"`
+}
+
+// cluster and project IDs are like c-m-4kqf8nqs and p-x7kzm, project ID is often prefixed by cluster ID
+var rancherClusterRegex = regexp.MustCompile(`\b(c-(?:m-)?[a-z0-9]+)(?::(p-[a-z0-9]+))?\b`)
+var rancherProjectRegex = regexp.MustCompile(`\b(p-[a-z0-9]+)\b`)
+var rancherNamespaceRegex = regexp.MustCompile(`/namespaces/([^/?]+)`)
+
+var rancherAuditTimeFormats = []string{time.RFC3339Nano, "2006-01-02 15:04:05 -0700", "2006-01-02 15:04:05.999999999 -0700 MST"}
+
 func RancherProcessorType() string {
 	return "Rancher"
 }
@@ -21,11 +80,251 @@ func (p *RancherProcessor) EventType() string {
 	return common.AsEventType(RancherProcessorType())
 }
 
+// rancherIDs finds cluster and project IDs in group or rule IDs of alert, or in request URI of audit
+func rancherIDs(ctx *RancherContext, values ...string) {
+
+	for _, v := range values {
+		if utils.IsEmpty(ctx.ClusterID) {
+			if m := rancherClusterRegex.FindStringSubmatch(v); len(m) == 3 {
+				ctx.ClusterID = m[1]
+				if utils.IsEmpty(ctx.ProjectID) {
+					ctx.ProjectID = m[2]
+				}
+			}
+		}
+		if utils.IsEmpty(ctx.ProjectID) {
+			if m := rancherProjectRegex.FindStringSubmatch(v); len(m) == 2 {
+				ctx.ProjectID = m[1]
+			}
+		}
+	}
+}
+
+func (p *RancherProcessor) alertContext(alert template.Alert) RancherContext {
+
+	ctx := RancherContext{
+		ClusterID:   alert.Labels["cluster_id"],
+		ClusterName: alert.Labels["cluster_name"],
+		ProjectID:   alert.Labels["project_id"],
+		ProjectName: alert.Labels["project_name"],
+		Namespace:   alert.Labels["namespace"],
+	}
+	if utils.IsEmpty(ctx.ClusterName) {
+		ctx.ClusterName = alert.Labels["cluster"]
+	}
+	rancherIDs(&ctx, alert.Labels["group_id"], alert.Labels["rule_id"])
+	return ctx
+}
+
+func (p *RancherProcessor) alert(ra *RancherAlert) *common.Alert {
+
+	alert := ra.Alert
+	title := alert.Annotations["summary"]
+	for _, l := range []string{"alert_name", "alertname"} {
+		if title != "" {
+			break
+		}
+		title = alert.Labels[l]
+	}
+
+	entity := ""
+	for _, l := range []string{"workload_name", "pod_name", "node_name", "instance", "service"} {
+		if entity != "" {
+			break
+		}
+		entity = alert.Labels[l]
+	}
+	if entity == "" {
+		entity = ra.Rancher.ClusterName
+	}
+
+	a := &common.Alert{
+		Fingerprint: alert.Fingerprint,
+		Status:      alert.Status,
+		Severity:    common.AlertSeverity(alert.Labels["severity"]),
+		Title:       title,
+		Description: alert.Annotations["description"],
+		Source:      "rancher",
+		Entity:      entity,
+		Labels:      alert.Labels,
+	}
+	a.AddLink("Source", alert.GeneratorURL)
+	a.AddLink("Runbook", alert.Annotations["runbook_url"])
+	return a
+}
+
+func (p *RancherProcessor) sendAlerts(channel string, span sreCommon.TracerSpan, data *template.Data) {
+
+	for _, alert := range data.Alerts {
+
+		ra := &RancherAlert{
+			Alert:    alert,
+			Receiver: data.Receiver,
+			Rancher:  p.alertContext(alert),
+		}
+
+		e := &common.Event{
+			Channel: channel,
+			Type:    p.EventType(),
+			Data:    ra,
+			Key:     fmt.Sprintf("%s:%s", alert.Fingerprint, alert.Status),
+		}
+		e.Alert = p.alert(ra)
+		e.State = e.Alert.EventState()
+		if alert.StartsAt.UnixNano() > 0 {
+			e.SetTime(alert.StartsAt.UTC())
+		} else {
+			e.SetTime(time.Now().UTC())
+		}
+		e.SetLogger(p.logger)
+		e.SetSpan(span)
+		p.outputs.Send(e)
+	}
+}
+
+func (p *RancherProcessor) auditTime(audit *RancherAudit) time.Time {
+
+	for _, f := range rancherAuditTimeFormats {
+		if t, err := time.Parse(f, audit.StageTimestamp); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Now().UTC()
+}
+
+func (p *RancherProcessor) sendAudits(channel string, span sreCommon.TracerSpan, audits []*RancherAudit) {
+
+	for _, audit := range audits {
+
+		rancherIDs(&audit.Rancher, audit.RequestURI)
+		if m := rancherNamespaceRegex.FindStringSubmatch(audit.RequestURI); len(m) == 2 {
+			audit.Rancher.Namespace = m[1]
+		}
+
+		e := &common.Event{
+			Channel: channel,
+			Type:    p.EventType(),
+			Data:    audit,
+			Key:     fmt.Sprintf("%s:%s", audit.AuditID, audit.Stage),
+		}
+		e.SetTime(p.auditTime(audit))
+		e.SetLogger(p.logger)
+		e.SetSpan(span)
+		p.outputs.Send(e)
+	}
+}
+
+// audits decodes audit log entry or a batch of them, nil is returned if body is not an audit log
+func (p *RancherProcessor) audits(body []byte) ([]*RancherAudit, error) {
+
+	var audits []*RancherAudit
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		if err := json.Unmarshal(body, &audits); err != nil {
+			return nil, err
+		}
+	} else {
+		var audit RancherAudit
+		if err := json.Unmarshal(body, &audit); err != nil {
+			return nil, err
+		}
+		audits = append(audits, &audit)
+	}
+
+	for _, a := range audits {
+		if a == nil || utils.IsEmpty(a.AuditID) {
+			return nil, nil
+		}
+	}
+	return audits, nil
+}
+
 func (p *RancherProcessor) HandleEvent(e *common.Event) error {
+
+	if e == nil {
+		p.logger.Debug("Event is not defined")
+		return nil
+	}
+
+	labels := make(map[string]string)
+	labels["event_channel"] = e.Channel
+	labels["processor"] = p.EventType()
+
+	requests := p.meter.Counter("rancher", "requests", "Count of all rancher processor requests", labels, "processor")
+	requests.Inc()
+
+	p.outputs.Send(e)
 	return nil
 }
 
 func (p *RancherProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
+
+	channel := strings.TrimLeft(r.URL.Path, "/")
+	span := common.SpanFromContext(r.Context())
+
+	labels := make(map[string]string)
+	labels["path"] = r.URL.Path
+	labels["processor"] = p.EventType()
+
+	requests := p.meter.Counter("rancher", "requests", "Count of all rancher processor requests", labels, "processor")
+	requests.Inc()
+
+	errors := p.meter.Counter("rancher", "errors", "Count of all rancher processor errors", labels, "processor")
+
+	var body []byte
+	if r.Body != nil {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			errors.Inc()
+			p.logger.Error(err)
+			http.Error(w, err.Error(), common.HttpBodyErrorStatus(err))
+			return err
+		}
+		body = data
+	}
+
+	if len(body) == 0 {
+		errors.Inc()
+		err := errPkg.New("empty body")
+		p.logger.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
+	p.logger.Debug("Body => %s", body)
+
+	// alerts are Alertmanager webhook payloads, anything else should be an audit log
+	data := template.Data{}
+	if err := json.Unmarshal(body, &data); err == nil && len(data.Alerts) > 0 {
+		p.sendAlerts(channel, span, &data)
+	} else {
+		audits, err := p.audits(body)
+		if err == nil && len(audits) == 0 {
+			err = errPkg.New("payload is neither alert nor audit log")
+		}
+		if err != nil {
+			errors.Inc()
+			p.logger.Error("Can't decode body: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return err
+		}
+		p.sendAudits(channel, span, audits)
+	}
+
+	resp, err := json.Marshal(&RancherResponse{Message: "OK"})
+	if err != nil {
+		errors.Inc()
+		p.logger.Error("Can't encode response: %v", err)
+		http.Error(w, fmt.Sprintf("could not encode response: %v", err), http.StatusInternalServerError)
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(resp); err != nil {
+		errors.Inc()
+		p.logger.Error("Can't write response: %v", err)
+		http.Error(w, fmt.Sprintf("could not write response: %v", err), http.StatusInternalServerError)
+		return err
+	}
 	return nil
 }
 
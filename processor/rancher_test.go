@@ -0,0 +1,57 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/devopsext/events/common"
+)
+
+func TestRancherProcessorFixtures(t *testing.T) {
+
+	newProcessor := func(outputs *common.Outputs) common.HttpProcessor {
+		return NewRancherProcessor(outputs, testObservability())
+	}
+
+	tests := []struct {
+		fixture string
+		count   int
+		keys    []string
+		status  []string
+		cluster string
+	}{
+		{"rancher-alert.json", 2, []string{"b2e8a1c4f5d6e7a8:firing", "0c9d8e7f6a5b4c3d:resolved"}, []string{common.StateFiring, common.StateResolved}, "c-m-4kqf8nqs"},
+		{"rancher-audit.json", 2, []string{"4a3b9c1e-2f7d-4e8a-9b6c-1d2e3f4a5b6c:ResponseComplete"}, nil, "c-m-4kqf8nqs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+
+			code, events := handleFixture(t, newProcessor, tt.fixture)
+			if code != 200 {
+				t.Fatalf("status %d, want 200", code)
+			}
+			if len(events) != tt.count {
+				t.Fatalf("%d events, want %d", len(events), tt.count)
+			}
+
+			for i, key := range tt.keys {
+				e := events[i]
+				if e.Type != "RancherEvent" || e.Channel != "test" || e.Key != key {
+					t.Fatalf("event %d is %s/%s with key %s, want key %s", i, e.Channel, e.Type, e.Key, key)
+				}
+				switch data := e.Data.(type) {
+				case *RancherAlert:
+					if e.State == nil || e.State.Status != tt.status[i] || data.Rancher.ClusterID != tt.cluster {
+						t.Fatalf("alert %d state %+v, rancher %+v", i, e.State, data.Rancher)
+					}
+				case *RancherAudit:
+					if e.State != nil || data.Rancher.ClusterID != tt.cluster {
+						t.Fatalf("audit %d state %+v, rancher %+v", i, e.State, data.Rancher)
+					}
+				default:
+					t.Fatalf("event %d data %T", i, e.Data)
+				}
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -50,12 +51,54 @@ func (p *Site24x7Processor) EventType() string {
 	return common.AsEventType(Site24x7ProcessorType())
 }
 
-func (p *Site24x7Processor) send(channel string, o interface{}, t *time.Time) {
+// key is monitor with status, so re-notifications are duplicates and status changes are not
+func (r *Site24x7Request) key() string {
+	return fmt.Sprintf("%d:%s", r.MonitorID, r.Status)
+}
+
+// alert is monitor, up status resolves it
+func (r *Site24x7Request) alert() *common.Alert {
+
+	status := common.StateFiring
+	severity := common.AlertSeverityCritical
+	switch strings.ToUpper(r.Status) {
+	case "UP":
+		status = common.StateResolved
+		severity = ""
+	case "TROUBLE":
+		severity = common.AlertSeverityWarning
+	}
+
+	labels := common.AlertTags(append(append([]string{}, r.GroupTags...), r.Tags...))
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels["monitor_type"] = r.MonitorType
+	labels["monitor_group"] = r.MonitorGroupName
+
+	a := &common.Alert{
+		Fingerprint: strconv.FormatInt(r.MonitorID, 10),
+		Status:      status,
+		Severity:    severity,
+		Title:       r.MonitorName,
+		Description: r.IncidentReason,
+		Source:      "site24x7",
+		Entity:      r.MonitorURL,
+		Labels:      labels,
+	}
+	a.AddLink("Dashboard", r.MonitorDashboardLink)
+	return a
+}
+
+func (p *Site24x7Processor) send(channel string, span sreCommon.TracerSpan, o interface{}, key string, alert *common.Alert, t *time.Time) {
 
 	e := &common.Event{
 		Channel: channel,
 		Type:    p.EventType(),
 		Data:    o,
+		Key:     key,
+		Alert:   alert,
+		State:   alert.EventState(),
 	}
 	if t != nil && (*t).UnixNano() > 0 {
 		e.SetTime((*t).UTC())
@@ -63,6 +106,7 @@ func (p *Site24x7Processor) send(channel string, o interface{}, t *time.Time) {
 		e.SetTime(time.Now().UTC())
 	}
 	e.SetLogger(p.logger)
+	e.SetSpan(span)
 	p.outputs.Send(e)
 }
 
@@ -87,6 +131,7 @@ func (p *Site24x7Processor) HandleEvent(e *common.Event) error {
 func (p *Site24x7Processor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
 
 	channel := strings.TrimLeft(r.URL.Path, "/")
+	span := common.SpanFromContext(r.Context())
 
 	labels := make(map[string]string)
 	labels["path"] = r.URL.Path
@@ -99,9 +144,14 @@ func (p *Site24x7Processor) HandleHttpRequest(w http.ResponseWriter, r *http.Req
 
 	var body []byte
 	if r.Body != nil {
-		if data, err := ioutil.ReadAll(r.Body); err == nil {
-			body = data
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			errors.Inc()
+			p.logger.Error(err)
+			http.Error(w, err.Error(), common.HttpBodyErrorStatus(err))
+			return err
 		}
+		body = data
 	}
 
 	if len(body) == 0 {
@@ -130,7 +180,7 @@ func (p *Site24x7Processor) HandleHttpRequest(w http.ResponseWriter, r *http.Req
 		http.Error(w, "Error incident time ISO format", http.StatusInternalServerError)
 		return err
 	}
-	p.send(channel, site24x7, &t)
+	p.send(channel, span, site24x7, site24x7.key(), site24x7.alert(), &t)
 
 	response := &Site24x7Response{
 		Message: "OK",
@@ -62,6 +62,7 @@ func (p TeamcityProcessor) HandleEvent(e *common.Event) error {
 
 func (p TeamcityProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
 	channel := strings.TrimLeft(r.URL.Path, "/")
+	span := common.SpanFromContext(r.Context())
 
 	labels := make(map[string]string)
 	labels["path"] = r.URL.Path
@@ -74,9 +75,14 @@ func (p TeamcityProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Requ
 
 	var body []byte
 	if r.Body != nil {
-		if data, err := io.ReadAll(r.Body); err == nil {
-			body = data
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			errors.Inc()
+			p.logger.Error(err)
+			http.Error(w, err.Error(), common.HttpBodyErrorStatus(err))
+			return err
 		}
+		body = data
 	}
 
 	if len(body) == 0 {
@@ -97,7 +103,7 @@ func (p TeamcityProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Requ
 		return err
 	}
 
-	p.send(channel, tc, &tc.Timestamp)
+	p.send(channel, span, tc, &tc.Timestamp)
 
 	response := &TeamcityResponse{
 		Message: "OK",
@@ -120,7 +126,7 @@ func (p TeamcityProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Requ
 	return nil
 }
 
-func (p TeamcityProcessor) send(channel string, tc TeamcityEvent, t *time.Time) {
+func (p TeamcityProcessor) send(channel string, span sreCommon.TracerSpan, tc TeamcityEvent, t *time.Time) {
 	e := &common.Event{
 		Channel: channel,
 		Type:    p.EventType(),
@@ -132,6 +138,7 @@ func (p TeamcityProcessor) send(channel string, tc TeamcityEvent, t *time.Time)
 		e.SetTime(time.Now().UTC())
 	}
 	e.SetLogger(p.logger)
+	e.SetSpan(span)
 	p.outputs.Send(e)
 }
 
@@ -1,6 +1,7 @@
 package processor
 
 import (
+	"encoding/json"
 	errPkg "errors"
 	"fmt"
 	"strconv"
@@ -415,6 +416,24 @@ func (vce *vcenterEvent) parse(jsonByte []byte) error {
 	return fmt.Errorf("%s %s %w", vce.Subject, jsonByte, err)
 }
 
+// cloudEventString returns CloudEvents envelope of event, old inputs put it into data as a string
+func (p *VCenterProcessor) cloudEventString(e *common.Event) (string, error) {
+
+	if s, ok := e.Data.(string); ok {
+		return s, nil
+	}
+
+	ce, err := e.CloudEvent(nil)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(ce)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
 func (p *VCenterProcessor) HandleEvent(e *common.Event) error {
 
 	labels := make(map[string]string)
@@ -432,7 +451,12 @@ func (p *VCenterProcessor) HandleEvent(e *common.Event) error {
 		return nil
 	}
 
-	jsonString := e.Data.(string)
+	jsonString, err := p.cloudEventString(e)
+	if err != nil {
+		errors.Inc()
+		return err
+	}
+
 	subject, err := jsonparser.GetString([]byte(jsonString), "subject")
 	if err != nil {
 		errors.Inc()
@@ -473,6 +497,7 @@ func (p *VCenterProcessor) HandleEvent(e *common.Event) error {
 
 		curevent.SetTime(eventTime)
 		curevent.SetLogger(p.logger)
+		curevent.SetSpan(e.Span())
 		p.outputs.Send(curevent)
 	}
 
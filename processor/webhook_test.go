@@ -0,0 +1,180 @@
+package processor
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/devopsext/events/common"
+	sre "github.com/devopsext/sre/common"
+)
+
+func testObservability() *common.Observability {
+	return common.NewObservability(sre.NewLogs(), sre.NewTraces(), sre.NewMetrics(), sre.NewEvents())
+}
+
+// recordOutput keeps events which are sent by processor
+type recordOutput struct {
+	events []*common.Event
+}
+
+func (o *recordOutput) Name() string                  { return "record" }
+func (o *recordOutput) Send(e *common.Event)          { o.events = append(o.events, e) }
+func (o *recordOutput) Deliver(e *common.Event) error { o.Send(e); return nil }
+func (o *recordOutput) Stop(_ context.Context) error  { return nil }
+
+// handleFixture posts fixture of test dir to processor and returns response status and events which are sent
+func handleFixture(t *testing.T, newProcessor func(*common.Outputs) common.HttpProcessor, fixture string) (int, []*common.Event) {
+
+	t.Helper()
+	body, err := os.ReadFile("../test/" + fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs := common.NewOutputs(testObservability())
+	record := &recordOutput{}
+	outputs.Add(record)
+	p := newProcessor(&outputs)
+
+	w := httptest.NewRecorder()
+	p.HandleHttpRequest(w, httptest.NewRequest("POST", "/test", strings.NewReader(string(body))))
+	return w.Code, record.events
+}
+
+func pagerDutySignature(secret string, body []byte) string {
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestPagerDutyProcessorSignature(t *testing.T) {
+
+	body, err := os.ReadFile("../test/pagerduty-triggered.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		status    int
+	}{
+		{"valid", "secret", pagerDutySignature("secret", body), 200},
+		{"rotated", "secret", pagerDutySignature("old", body) + ", " + pagerDutySignature("secret", body), 200},
+		{"invalid", "secret", pagerDutySignature("other", body), 401},
+		{"missing", "secret", "", 401},
+		{"no secret", "", pagerDutySignature("", body), 401},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			outputs := common.NewOutputs(testObservability())
+			p := NewPagerDutyProcessor(PagerDutyProcessorOptions{Secret: tt.secret}, &outputs, testObservability())
+
+			r := httptest.NewRequest("POST", "/pagerduty", strings.NewReader(string(body)))
+			if tt.signature != "" {
+				r.Header.Set(pagerDutySignatureHeader, tt.signature)
+			}
+			w := httptest.NewRecorder()
+			p.HandleHttpRequest(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestOpsgenieProcessorSecret(t *testing.T) {
+
+	body, err := os.ReadFile("../test/opsgenie-create.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		status int
+	}{
+		{"valid", "secret", "secret", 200},
+		{"invalid", "secret", "other", 401},
+		{"missing", "secret", "", 401},
+		{"no secret", "", "", 401},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			outputs := common.NewOutputs(testObservability())
+			p := NewOpsgenieProcessor(OpsgenieProcessorOptions{Secret: tt.secret}, &outputs, testObservability())
+
+			r := httptest.NewRequest("POST", "/opsgenie", strings.NewReader(string(body)))
+			if tt.header != "" {
+				r.Header.Set("X-Opsgenie-Secret", tt.header)
+			}
+			w := httptest.NewRecorder()
+			p.HandleHttpRequest(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestProcessorBodyTooLarge(t *testing.T) {
+
+	obs := testObservability()
+	outputs := common.NewOutputs(obs)
+	body := `{"alerts": [{"status": "firing", "labels": {"alertname": "large"}}]}`
+
+	processors := []common.HttpProcessor{
+		NewAlertmanagerProcessor(&outputs, obs),
+		NewAWSProcessor(&outputs, obs),
+		NewCloudflareProcessor(&outputs, obs),
+		NewCustomJsonProcessor(CustomJsonProcessorOptions{}, &outputs, obs),
+		NewDataDogProcessor(&outputs, obs),
+		NewGitlabProcessor(&outputs, obs),
+		NewGoogleProcessor(&outputs, obs),
+		NewGrafanaProcessor(&outputs, obs),
+		NewK8sProcessor(&outputs, obs),
+		NewKubeProcessor(&outputs, obs),
+		NewNewRelicProcessor(&outputs, obs),
+		NewObserviumEventProcessor(&outputs, obs),
+		NewOpsgenieProcessor(OpsgenieProcessorOptions{Secret: "secret"}, &outputs, obs),
+		NewPagerDutyProcessor(PagerDutyProcessorOptions{Secret: "secret"}, &outputs, obs),
+		NewRancherProcessor(&outputs, obs),
+		NewSite24x7Processor(&outputs, obs),
+		NewTeamcityProcessor(&outputs, obs),
+		NewWinEventProcessor(&outputs, obs),
+		NewZabbixProcessor(&outputs, obs),
+	}
+
+	for _, p := range processors {
+		t.Run(p.EventType(), func(t *testing.T) {
+
+			// body over max size of http input fails on read
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/large", strings.NewReader(body))
+			r.Header.Set("X-Opsgenie-Secret", "secret")
+			r.Body = http.MaxBytesReader(w, r.Body, 16)
+
+			if err := p.HandleHttpRequest(w, r); err == nil {
+				t.Fatal("HandleHttpRequest() = nil, want error")
+			}
+			if w.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("status %d, want 413", w.Code)
+			}
+		})
+	}
+}
@@ -88,6 +88,7 @@ func (p *WinEventProcessor) HandleEvent(e *common.Event) error {
 			}
 			t := time.UnixMilli(event.Timestamp * 1000)
 			newEvent.SetTime(t.UTC())
+			newEvent.SetSpan(e.Span())
 			requests.Inc()
 			p.outputs.Send(newEvent)
 		}
@@ -98,6 +99,7 @@ func (p *WinEventProcessor) HandleEvent(e *common.Event) error {
 func (p *WinEventProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
 
 	channel := strings.TrimLeft(r.URL.Path, "/")
+	span := common.SpanFromContext(r.Context())
 
 	labels := make(map[string]string)
 	labels["path"] = r.URL.Path
@@ -110,9 +112,14 @@ func (p *WinEventProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Req
 
 	var body []byte
 	if r.Body != nil {
-		if data, err := ioutil.ReadAll(r.Body); err == nil {
-			body = data
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			errors.Inc()
+			p.logger.Error(err)
+			http.Error(w, err.Error(), common.HttpBodyErrorStatus(err))
+			return err
 		}
+		body = data
 	}
 
 	if len(body) == 0 {
@@ -133,7 +140,7 @@ func (p *WinEventProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Req
 	}
 	for _, event := range WinEvents.Events {
 		t := time.UnixMilli(event.Timestamp)
-		p.send(channel, event, &t)
+		p.send(channel, span, event, &t)
 	}
 
 	response := &WinEventResponse{
@@ -157,7 +164,7 @@ func (p *WinEventProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Req
 	return nil
 }
 
-func (p *WinEventProcessor) send(channel string, event interface{}, t *time.Time) {
+func (p *WinEventProcessor) send(channel string, span sreCommon.TracerSpan, event interface{}, t *time.Time) {
 	e := &common.Event{
 		Channel: channel,
 		Type:    p.EventType(),
@@ -169,6 +176,7 @@ func (p *WinEventProcessor) send(channel string, event interface{}, t *time.Time
 		e.SetTime(time.Now().UTC())
 	}
 	e.SetLogger(p.logger)
+	e.SetSpan(span)
 	p.outputs.Send(e)
 }
 
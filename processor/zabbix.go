@@ -3,6 +3,7 @@ package processor
 import (
 	"encoding/json"
 	errPkg "errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
@@ -46,12 +47,66 @@ func (p *ZabbixProcessor) EventType() string {
 	return common.AsEventType(ZabbixProcessorType())
 }
 
-func (p *ZabbixProcessor) send(channel string, o interface{}, t *time.Time) {
+// alert is problem event, resolved or ok status resolves it
+func (z *ZabbixEvent) alert() *common.Alert {
+
+	fingerprint := z.EventID
+	if fingerprint == "" && z.TriggerName != "" {
+		fingerprint = fmt.Sprintf("%s:%s", z.HostName, z.TriggerName)
+	}
+
+	status := common.StateFiring
+	switch strings.ToUpper(z.Status) {
+	case "RESOLVED", "OK":
+		status = common.StateResolved
+	}
+
+	// not classified, information, warning, average, high, disaster
+	severity := ""
+	switch z.EventNSeverity {
+	case "1":
+		severity = common.AlertSeverityInfo
+	case "2", "3":
+		severity = common.AlertSeverityWarning
+	case "4", "5":
+		severity = common.AlertSeverityCritical
+	}
+
+	title := z.EventName
+	if title == "" {
+		title = z.TriggerName
+	}
+
+	labels := common.AlertTags(strings.Split(z.EventTags, ","))
+	if z.Environment != "" {
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels["environment"] = z.Environment
+	}
+
+	a := &common.Alert{
+		Fingerprint: fingerprint,
+		Status:      status,
+		Severity:    severity,
+		Title:       title,
+		Description: z.TriggerDescription,
+		Source:      "zabbix",
+		Entity:      z.HostName,
+		Labels:      labels,
+	}
+	a.AddLink("Alert", z.AlertURL)
+	return a
+}
+
+func (p *ZabbixProcessor) send(channel string, span sreCommon.TracerSpan, o interface{}, alert *common.Alert, t *time.Time) {
 
 	e := &common.Event{
 		Channel: channel,
 		Type:    p.EventType(),
 		Data:    o,
+		Alert:   alert,
+		State:   alert.EventState(),
 	}
 	if t != nil && (*t).UnixNano() > 0 {
 		e.SetTime((*t).UTC())
@@ -59,6 +114,7 @@ func (p *ZabbixProcessor) send(channel string, o interface{}, t *time.Time) {
 		e.SetTime(time.Now().UTC())
 	}
 	e.SetLogger(p.logger)
+	e.SetSpan(span)
 	p.outputs.Send(e)
 }
 
@@ -83,6 +139,7 @@ func (p *ZabbixProcessor) HandleEvent(e *common.Event) error {
 func (p *ZabbixProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
 
 	channel := strings.TrimLeft(r.URL.Path, "/")
+	span := common.SpanFromContext(r.Context())
 
 	labels := make(map[string]string)
 	labels["path"] = r.URL.Path
@@ -95,9 +152,14 @@ func (p *ZabbixProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Reque
 
 	var body []byte
 	if r.Body != nil {
-		if data, err := io.ReadAll(r.Body); err == nil {
-			body = data
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			errors.Inc()
+			p.logger.Error(err)
+			http.Error(w, err.Error(), common.HttpBodyErrorStatus(err))
+			return err
 		}
+		body = data
 	}
 
 	if len(body) == 0 {
@@ -122,10 +184,10 @@ func (p *ZabbixProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Reque
 
 	EventDateTime, err := time.Parse(time.RFC3339Nano, strings.ReplaceAll(request.EventDate, ".", "-")+"T"+request.EventTime+"Z")
 	if err != nil {
-		p.send(channel, request, nil)
+		p.send(channel, span, request, request.alert(), nil)
 		return nil
 	}
-	p.send(channel, request, &EventDateTime)
+	p.send(channel, span, request, request.alert(), &EventDateTime)
 	return nil
 }
 
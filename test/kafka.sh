@@ -0,0 +1,15 @@
+#!/bin/bash
+
+# Kafka input against a local broker:
+#   docker run -d --name kafka -p 9092:9092 apache/kafka:3.7.0
+#   EVENTS_KAFKA_IN_BROKERS=localhost:9092 EVENTS_KAFKA_IN_TOPICS=events EVENTS_KAFKA_IN_OFFSET=oldest EVENTS_STDOUT_LEVEL=debug go run ../events.go
+# events are sent to outputs by their type, alerts are handled by Alertmanager processor with
+#   EVENTS_KAFKA_IN_TOPICS=alertmanager EVENTS_KAFKA_IN_PROCESSOR=Alertmanager
+
+BROKER=${BROKER:-localhost:9092}
+
+docker exec -i kafka /opt/kafka/bin/kafka-topics.sh --bootstrap-server "$BROKER" --create --if-not-exists --topic events
+docker exec -i kafka /opt/kafka/bin/kafka-topics.sh --bootstrap-server "$BROKER" --create --if-not-exists --topic alertmanager
+
+jq -c . kafka-event.json | docker exec -i kafka /opt/kafka/bin/kafka-console-producer.sh --bootstrap-server "$BROKER" --topic events
+jq -c . alertmanager.json | docker exec -i kafka /opt/kafka/bin/kafka-console-producer.sh --bootstrap-server "$BROKER" --topic alertmanager
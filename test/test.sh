@@ -6,11 +6,33 @@
 curl -sk -X POST -H "Content-type: application/json" -d @k8s.json "http://localhost:8081/k8s"
 
 #curl -sk -X POST -H "Content-type: application/json" -d @alertmanager.json "http://localhost:80/alertmanager"
+#curl -sk -X POST -H "Content-type: application/json" -d @rancher-alert.json "http://localhost:80/rancher"
+#curl -sk -X POST -H "Content-type: application/json" -d @rancher-audit.json "http://localhost:80/rancher"
 #curl -sk -X POST -H "Content-type: application/json" -d @zabbix.json "http://localhost:80/zabbix"
 
+#curl -sk -X POST -H "Content-type: application/json" -d @customjson.json "http://localhost:8081/deploys"
+#curl -sk -X POST -H "Content-type: application/x-ndjson" --data-binary @customjson.ndjson "http://localhost:8081/deploys"
+
 #curl -sk -X POST -H "Content-type: application/json" -d @datadog-triggered.json "http://localhost:8081/datadog"
 #curl -sk -X POST -H "Content-type: application/json" -d @datadog-recovered.json "http://localhost:8081/datadog"
 
+#curl -sk -X POST -H "Content-type: application/json" -d @newrelic-issue-activated.json "http://localhost:8081/newrelic"
+#curl -sk -X POST -H "Content-type: application/json" -d @newrelic-issue-closed.json "http://localhost:8081/newrelic"
+#curl -sk -X POST -H "Content-type: application/json" -d @newrelic-incident.json "http://localhost:8081/newrelic"
+
+#curl -sk -X POST -H "Content-type: application/json" -d @grafana.json "http://localhost:8081/grafana"
+
+#PAGERDUTY_SIGNATURE="v1=$(openssl dgst -sha256 -hmac "pagerduty-signing-secret" -hex < pagerduty-triggered.json | sed 's/^.* //')"
+#curl -sk -X POST -H "Content-type: application/json" -H "X-PagerDuty-Signature: $PAGERDUTY_SIGNATURE" --data-binary @pagerduty-triggered.json "http://localhost:8081/pagerduty/payments"
+#PAGERDUTY_SIGNATURE="v1=$(openssl dgst -sha256 -hmac "$EVENTS_PAGERDUTY_SECRET" -hex < pagerduty-annotated.json | sed 's/^.* //')"
+#curl -sk -X POST -H "Content-type: application/json" -H "X-PagerDuty-Signature: $PAGERDUTY_SIGNATURE" --data-binary @pagerduty-annotated.json "http://localhost:8081/pagerduty"
+#PAGERDUTY_SIGNATURE="v1=$(openssl dgst -sha256 -hmac "$EVENTS_PAGERDUTY_SECRET" -hex < pagerduty-resolved.json | sed 's/^.* //')"
+#curl -sk -X POST -H "Content-type: application/json" -H "X-PagerDuty-Signature: $PAGERDUTY_SIGNATURE" --data-binary @pagerduty-resolved.json "http://localhost:8081/pagerduty"
+
+#curl -sk -X POST -H "Content-type: application/json" -H "X-Opsgenie-Secret: opsgenie-secret" -d @opsgenie-create.json "http://localhost:8081/opsgenie"
+#curl -sk -X POST -H "Content-type: application/json" -H "X-Opsgenie-Secret: opsgenie-secret" -d @opsgenie-addnote.json "http://localhost:8081/opsgenie"
+#curl -sk -X POST -H "Content-type: application/json" -H "X-Opsgenie-Secret: opsgenie-secret" -d @opsgenie-close.json "http://localhost:8081/opsgenie"
+
 #curl -sk -X POST -H "Content-type: application/json" -d @site24x7.json "http://localhost:80/site24x7"
 
 #curl -sk -X POST -H "Content-type: application/json" -d @cloudflare.json "http://localhost:80/cloudflare"